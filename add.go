@@ -29,7 +29,7 @@ func (d *doccurator) AddMultiple(filePaths []string, allowForDuplicateMovedAndOb
 	}()
 
 	for _, filePath := range filePaths {
-		newId, idErr := ExtractIdFromStandardizedFilename(filePath)
+		newId, idErr := d.extractIdFromFilename(filePath)
 		if idErr != nil {
 			if !generateMissingIds {
 				if abortOnError {
@@ -39,7 +39,22 @@ func (d *doccurator) AddMultiple(filePaths []string, allowForDuplicateMovedAndOb
 				d.Print(out.Normal, "Skipping bad path (%s): %s\n", filePath, idErr)
 				continue
 			}
-			newId = d.GetFreeId()
+			if newId, idErr = d.GetFreeIdFor(filePath); idErr != nil {
+				if abortOnError {
+					err = fmt.Errorf(`no ID for %s: %w`, filePath, idErr)
+					return
+				}
+				d.Print(out.Normal, "Skipping (%s): %s\n", filePath, idErr)
+				continue
+			}
+		} else if namespace, foreign := d.foreignIdNamespace(newId); foreign && !allowForDuplicateMovedAndObsolete {
+			foreignErr := fmt.Errorf("ID %s from filename belongs to %s (override required to add anyway)", newId, d.describeIdNamespace(namespace))
+			if abortOnError {
+				err = fmt.Errorf(`bad path %s: (%w)`, filePath, foreignErr)
+				return
+			}
+			d.Print(out.Normal, "Skipping bad path (%s): %s\n", filePath, foreignErr)
+			continue
 		}
 		_, addErr := d.addSingle(newId, filePath, allowForDuplicateMovedAndObsolete, allowEmpty)
 		if addErr != nil {
@@ -91,9 +106,9 @@ func (d *doccurator) addSingle(id document.Id, filePath string, allowForDuplicat
 	absoluteFilePath := mustAbsFilepath(filePath)
 	if !allowForDuplicateMovedAndObsolete {
 		switch check := d.appLib.CheckFilePath(absoluteFilePath, false); check.Status() { //check on add must be accurate hence no performance optimization
-		case library.Moved:
+		case library.Moved, library.MovedModified:
 			return library.Document{}, fmt.Errorf("document creation prevented: use update to accept move (%s)", filePath)
-		case library.Duplicate, library.Obsolete:
+		case library.Duplicate, library.Linked, library.Obsolete:
 			return library.Document{}, fmt.Errorf("document creation prevented: override required to add duplicate/obsolete file (%s)", filePath)
 		}
 	}
@@ -115,6 +130,12 @@ func (d *doccurator) addSingle(id document.Id, filePath string, allowForDuplicat
 		d.appLib.ForgetDocument(doc)
 		return library.Document{}, fmt.Errorf("document creation prevented: file to record is empty (%s): %w", filePath, recordEmptyContentError)
 	}
+	if mode := d.appLib.GetIdAttributeMode(); mode != library.NoIdAttribute {
+		//failing to write the attribute does not prevent the record, status reveals the missing link if the file is moved
+		if attributeErr := d.appLib.WriteIdAttribute(doc, mode == library.IdAttributeWithHash); attributeErr != nil {
+			d.Print(out.Normal, "ID attribute not written (%s): %s\n", filePath, attributeErr)
+		}
+	}
 	d.Print(out.Normal, "Added %s: %s\n", id, d.displayablePath(absoluteFilePath, true, false))
 	d.Print(out.Verbose, "%s\n", out.Indent(2, doc.String()))
 	return doc, nil
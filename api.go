@@ -1,6 +1,11 @@
 package doccurator
 
-import "github.com/n2code/doccurator/internal/document"
+import (
+	"crypto/sha256"
+	"github.com/n2code/doccurator/internal/document"
+	"github.com/n2code/doccurator/internal/library"
+	"time"
+)
 
 // Doccurator lets you interface with a doccurator database whose handle was retrieved using New or Open.
 type Doccurator interface {
@@ -11,7 +16,7 @@ type Doccurator interface {
 
 	// AddMultiple creates multiple documents from a set of paths, attempting to use IDs from the filenames.
 	// A flag can be set to allow automatic ID generation where no extraction is possible.
-	// Attempting to add moved files or files with either duplicate or new content produce errors unless instructed otherwise.
+	// Attempting to add moved files, files with either duplicate or new content, or files whose ID belongs to a foreign ID namespace produce errors unless instructed otherwise.
 	// Error handling can be toggled to either fail immediately (the library remains clean in that case, i.e. has the same state as before) or ignore issues.
 	// Changes need to be committed with PersistChanges.
 	AddMultiple(paths []string, allowForDuplicateMovedAndObsolete bool, allowEmpty bool, generateMissingIds bool, abortOnError bool) (added []document.Id, err error)
@@ -43,26 +48,215 @@ type Doccurator interface {
 	// Changes need to be committed with PersistChanges.
 	ForgetAllObsolete()
 
-	// StandardizeLocation renames the file of the given document to conform to the standard format:
+	// StandardizeLocation renames the file of the given document to conform to the name template of the library, by default:
 	//   file.ext.23456X777.ndoc.ext
 	// Library changes need to be committed with PersistChanges.
 	// Filesystem changes have an immediate effect but can be reverted by a subsequent call to RollbackAllFilesystemChanges in case of an error.
 	StandardizeLocation(id document.Id) error
 
+	// SetNameTemplate changes the format of standardized filenames (see document.NameTemplate).
+	// Names following the replaced template remain recognized. Changes need to be committed with PersistChanges.
+	SetNameTemplate(template string) error
+
+	// PrintNameTemplates outputs the current name template, all recognized previous ones, and the ID attribute mode.
+	PrintNameTemplates()
+
+	// SetIdAttributeMode determines whether the ID (and the checksum) of added documents are written to extended attributes
+	// of their files which link them to their records independent of their names. Changes need to be committed with PersistChanges.
+	SetIdAttributeMode(mode library.IdAttributeMode) error
+
+	// WriteIdAttributes writes extended attributes according to the ID attribute mode to the files of all active records which are in order,
+	// e.g. after the mode was changed or to fix inconsistencies reported by status. Attributes cannot be reverted.
+	WriteIdAttributes() (writtenCount int, err error)
+
+	// MigrateFilenames renames the files of all active documents with standardized names (according to any recognized template)
+	// to follow the current name template. Files which are not in order block the migration. Dry run only prints the plan.
+	// Library changes need to be committed with PersistChanges.
+	// Filesystem changes have an immediate effect but can be reverted by a subsequent call to RollbackAllFilesystemChanges in case of an error.
+	MigrateFilenames(dryRun bool) (renamedCount int, err error)
+
+	// RenameRecords renames the files of the selected active records to their standardized names (see StandardizeLocation) or,
+	// in reverse, strips the ID from the names again. The plan is printed first and only applied if no conflicts are found,
+	// e.g. files which are not in order or names which are taken. Dry run only prints the plan.
+	// Library changes need to be committed with PersistChanges.
+	// Filesystem changes have an immediate effect but can be reverted by a subsequent call to RollbackAllFilesystemChanges in case of an error.
+	RenameRecords(selection RecordSelection, reverse bool, dryRun bool) (RenamePlan, error)
+
+	// RemoveWasteByPath deletes the file at the given path if its content is a duplicate of an active record or obsolete.
+	// Attempts to remove files in any other state yield an error.
+	// The deletion has an immediate effect but is only finalized by PersistChanges and can be reverted by RollbackAllFilesystemChanges until then.
+	RemoveWasteByPath(path string) error
+
+	// RelocateDatabase moves the library database file (along with companion files) to the given path or into the given directory.
+	// All library locators in the root directories which refer to the database are updated and the library is reopened for verification.
+	// The relocation is committed immediately, on failure it can be reverted by RollbackAllFilesystemChanges.
+	// The library is saved anew at the new location, i.e. pending changes of records are committed along with it, whereas
+	// pending filesystem changes remain revertible and staged deletions are finalized by the next PersistChanges.
+	RelocateDatabase(newPath string) error
+
+	// MergeFrom imports the records of the library in the given database file (including retired ones) into this library.
+	// Records unknown here are imported as they are, conflicts are resolved according to the given strategy.
+	// Each step is printed so that the merge can be previewed by not committing it. Changes need to be committed with PersistChanges.
+	MergeFrom(otherDatabase string, strategy MergeStrategy) (changeCount int, err error)
+
+	// Split moves all records located below the given directory (retired ones included) into a new library rooted at that directory.
+	// IDs and timestamps are kept, paths are rebased onto the new root. The new library is saved to the given database file immediately
+	// and a library locator is placed in the directory which is in turn ignored by this library (via the ignore file of its parent).
+	// Changes to this library need to be committed with PersistChanges, until then the filesystem changes can be reverted by RollbackAllFilesystemChanges.
+	// The new library is only added to the registry of known libraries once the changes are committed.
+	Split(directory string, database string) (movedCount int, err error)
+
+	// Pack writes the files of the selected active records into a new archive (format by extension: .zip, .tar.gz, or .tgz)
+	// along with a manifest (JSON) that lists IDs, original paths, sizes, checksums, and timestamps of the documents.
+	// The files are either arranged like in the library or placed flat with standardized names.
+	Pack(archive string, selection RecordSelection, flat bool) (packedCount int, err error)
+
+	// Unpack extracts an archive created by Pack into the given directory inside the library and adds all documents,
+	// keeping their IDs unless taken. Archives that do not pass VerifyPack are rejected.
+	// Changes need to be committed with PersistChanges, until then the extracted files can be removed by RollbackAllFilesystemChanges.
+	Unpack(archive string, directory string) (addedCount int, err error)
+
+	// SetRetention defines how long the documents below the given directory have to be kept, replacing any previous rule for it.
+	// The period starts at the given basis date, optionally at the end of its calendar year. The most specific rule applies.
+	// Rules are bound to directories only since records carry no tags.
+	// Changes need to be committed with PersistChanges.
+	SetRetention(directory string, years uint, basis library.RetentionBasis, endOfYear bool) error
+
+	// RemoveRetention deletes the retention rule of the given directory. Changes need to be committed with PersistChanges.
+	RemoveRetention(directory string) error
+
+	// PrintRetentionRules lists all retention rules of the library.
+	PrintRetentionRules()
+
+	// Expire determines all active records whose retention period has ended at the given time and optionally retires them.
+	// Changes need to be committed with PersistChanges.
+	Expire(asOf time.Time, retire bool) (ExpiryReport, error)
+
+	// PrintExpiryReport outputs the result of Expire.
+	PrintExpiryReport(report ExpiryReport)
+
+	// SetDueDate sets the deadline of the given document, the zero time removes it.
+	// Certificate files (X.509) get the end of their validity as due date whenever their content is recorded.
+	// Changes need to be committed with PersistChanges.
+	SetDueDate(id document.Id, due time.Time) error
+
+	// CollectDue determines all active records which are overdue at the given time or become due within the given window.
+	// Certificates on record which were recorded before expiry detection was introduced are examined first if their files
+	// are unchanged, caughtUpCount reports how many. Due dates set already are kept, as are the change dates of the records.
+	// Changes need to be committed with PersistChanges.
+	CollectDue(asOf time.Time, window time.Duration) (report DueReport, caughtUpCount int)
+
+	// PrintDueReport outputs the result of CollectDue.
+	PrintDueReport(report DueReport)
+
+	// FindSimilar groups active records of images whose perceptual hashes differ in at most maxDistance bits (0-64).
+	// Images on record which lack a perceptual hash because they were recorded before hashing was introduced are
+	// hashed first if their files are unchanged, caughtUpCount reports how many (including files which turned out to be
+	// no decodable images). The change dates of the records are kept. Changes need to be committed with PersistChanges.
+	FindSimilar(maxDistance int) (groups []SimilarGroup, caughtUpCount int)
+
+	// PrintSimilar outputs the result of FindSimilar.
+	PrintSimilar(groups []SimilarGroup)
+
+	// CollectDuplicates groups all non-empty files present in the library (on record or not) and all retired records by
+	// content. Files whose content is not in order on record (modified, moved) count as untracked copies, missing and
+	// inaccessible files as well as hardlinks of files on record (which occupy no additional space) are left out.
+	// The first copy of each group is the preferred one: The first matching of the path-priority rules (globs matched
+	// against the anchored path, its parent directories, or the filename) decides, even if it prefers an untracked copy
+	// over files on record. Among equally ranked copies files on record are preferred over untracked ones, then the
+	// shortest path. Retired records are never preferred because their files are gone.
+	CollectDuplicates(preferenceRules []string) DuplicateReport
+
+	// PrintDuplicates outputs the result of CollectDuplicates.
+	PrintDuplicates(report DuplicateReport)
+
+	// ResolveDuplicates retires all records of each group but the preferred copy or, if requested, deletes their files
+	// (and those of untracked copies) as well. If an untracked copy is preferred the best ranked record is moved to it
+	// instead of being retired, hence the content stays on record. Each step is printed. Changes need to be committed with PersistChanges,
+	// until then the deletions can be reverted by RollbackAllFilesystemChanges.
+	ResolveDuplicates(report DuplicateReport, deleteFiles bool) (resolvedCount int, err error)
+
+	// RestoreFrom searches the given backup location for files whose content matches active records with missing files
+	// (or modified ones if requested) and copies them to the recorded location, applying the recorded modification time.
+	// Candidates are matched by content only, i.e. the layout of the backup location does not matter. Each step is printed.
+	// Replaced files are staged for deletion which is finalized by PersistChanges, until then all restored files can be removed
+	// again by RollbackAllFilesystemChanges. In a dry run nothing is changed.
+	RestoreFrom(directory string, includeModified bool, dryRun bool) (restoredCount int, err error)
+
 	// PersistChanges commits all changes to the library database file.
-	// The rollback log of RollbackAllFilesystemChanges is emptied.
+	// Staged file deletions are finalized and the rollback log of RollbackAllFilesystemChanges is emptied.
 	PersistChanges() error
 
 	// RollbackAllFilesystemChanges reverts all filesystem changes since the last call to PersistChanges.
 	RollbackAllFilesystemChanges() (complete bool)
 
+	// DiscardChanges reverts all filesystem changes (see RollbackAllFilesystemChanges) and reloads the library database file,
+	// i.e. all library changes since the last call to PersistChanges are lost.
+	// If the database file cannot be loaded (e.g. corrupted or leftover work-in-progress file) an error is returned
+	// and the library keeps its uncommitted state.
+	DiscardChanges() error
+
+	// SetNamedRoot adds the given directory as an additional library root with the given name or relocates the existing root of that name.
+	// Paths inside additional roots are anchored with the root name as qualifier, e.g. "archive//2021/bill.pdf".
+	// A library locator is placed in the directory so that the library can be opened from inside it.
+	// Changes need to be committed with PersistChanges.
+	SetNamedRoot(name string, directory string) error
+
+	// RemoveNamedRoot removes an additional library root which must no longer be referenced by any record (retired ones included).
+	// Changes need to be committed with PersistChanges.
+	RemoveNamedRoot(name string) error
+
+	// PrintRoots lists the primary library root followed by all additional named roots.
+	PrintRoots()
+
+	// GetIdentity yields the UUID, name, and ID namespace of the library.
+	GetIdentity() LibraryIdentity
+
+	// PrintIdentity outputs the UUID, name, ID namespace, and ID allocation of the library.
+	PrintIdentity()
+
+	// SetName gives the library a human-readable name (empty to remove it).
+	// Changes need to be committed with PersistChanges.
+	SetName(name string)
+
+	// SetIdNamespace confines IDs generated by GetFreeId to the given namespace, 0 disables namespacing.
+	// Giving each library a distinct namespace keeps document IDs unique across libraries.
+	// Changes need to be committed with PersistChanges.
+	SetIdNamespace(namespace uint32)
+
+	// SetIdAllocation chooses how GetFreeId generates IDs. Strategies with a range require a valid one.
+	// Changes need to be committed with PersistChanges.
+	SetIdAllocation(allocation library.IdAllocation) error
+
+	// CompareWith matches the active records of the library with those of the library in the given database file by ID and by content.
+	// The other library is only read, never modified.
+	CompareWith(otherDatabase string) (LibraryComparison, error)
+
+	// PrintComparison outputs the result of CompareWith grouped by the kind of difference.
+	PrintComparison(comparison LibraryComparison)
+
+	// CheckCopy verifies a copy of the given library root (the primary root if empty) in the given directory against the active records.
+	// Files are matched by path first and then by content. The copy does not need a library database or locator of its own.
+	CheckCopy(directory string, rootName string) (CopyCheck, error)
+
+	// PrintCopyCheck outputs the result of CheckCopy grouped by the kind of problem.
+	PrintCopyCheck(check CopyCheck)
+
+	// Export copies the files of the selected active records to the given destination directory outside of the library.
+	// Each copy is verified against the recorded checksum and listed in a manifest file (JSON) in the destination.
+	// Failures of single documents are part of the report, the returned error is reserved for problems of the export as a whole.
+	Export(destination string, options ExportOptions) (ExportReport, error)
+
+	// PrintExportReport outputs a summary of the result of Export.
+	PrintExportReport(report ExportReport)
+
 	// PrintRecord outputs the full state of the given document, uncommitted changes included.
 	PrintRecord(id document.Id)
 
 	// PrintAllRecords outputs the full state of all documents in the library, uncommitted changes included.
 	PrintAllRecords(excludeRetired bool)
 
-	// PrintTree prints a full filesystem tree of the library root directory.
+	// PrintTree prints a full filesystem tree of each library root directory.
 	// For all files that are not in sync with the library records an indicator is attached to reflect their status with respect to the library.
 	PrintTree(excludeUnchanged bool, onlyWorkingDir bool) error
 
@@ -70,8 +264,26 @@ type Doccurator interface {
 	// If no paths are given the full library root directory is scanned recursively and unchanged tracked files are omitted.
 	PrintStatus(paths []string)
 
-	// GetFreeId yields an ID that is not already in use derived from the current time.
-	GetFreeId() document.Id
+	// GetAllPaths compiles the union of all library records and the files currently present in the library, i.e. the content of PrintTree.
+	// The results are sorted by path.
+	GetAllPaths(excludeUnchanged bool) []CheckResult
+
+	// GetStatus performs the same comparison as PrintStatus and yields the results grouped by status in the order of presentation.
+	// Unchanged files whose ID attribute contradicts their record are included at the end even if no paths are given.
+	GetStatus(paths []string) []CheckResult
+
+	// GetRecord retrieves the full state of the given document, uncommitted changes included.
+	GetRecord(id document.Id) (record Record, exists bool)
+
+	// Absolutize turns a path relative to its library root (as found in results and records) into an absolute one.
+	Absolutize(anchoredPath string) string
+
+	// GetFreeId yields an ID that is not already in use according to the ID allocation of the library (placed inside the ID namespace of the library, if any).
+	// Allocations based on the file fall back to the current time. If no ID is left an error is returned.
+	GetFreeId() (document.Id, error)
+
+	// GetFreeIdFor is like GetFreeId but takes the file to be added into account (e.g. its modification time).
+	GetFreeIdFor(path string) (document.Id, error)
 
 	// SearchByIdPart takes a case-insensitive full/partial ID (non-numeric display format) and compiles
 	// a list of all matching record IDs along with their path its current status.
@@ -86,11 +298,197 @@ type Doccurator interface {
 	// Touched, moved, and modified files can have their records updated.
 	// Untracked files with duplicate content (waste) can be deleted.
 	// Obsolete files corresponding to retired records (waste) can be deleted.
-	// Untracked files with duplicate content (waste) can be deleted.
+	// If deduplication is requested untracked files with duplicate content are replaced with hardlinks to the file on record
+	// (or reflink clones where only those are possible) instead of deleting them, regardless of removeWaste.
+	// Untracked files which are hardlinks of a file on record (status Linked) are no waste and left alone.
 	// All decisions are up to the user and nothing is changed without confirmation.
 	// Library changes need to be committed with a subsequent call to PersistChanges.
-	// Filesystem changes have an immediate effect and need to be reverted by RollbackAllFilesystemChanges.
-	InteractiveTidy(prompt RequestChoice, removeWaste bool) (decisionsMade int, foundWaste bool, cancelled bool)
+	// Filesystem changes have an immediate effect and can be reverted by RollbackAllFilesystemChanges until the deletions are finalized by PersistChanges.
+	InteractiveTidy(prompt RequestChoice, removeWaste bool, deduplicate bool) (decisionsMade int, foundWaste bool, cancelled bool)
+}
+
+// LibraryIdentity distinguishes a library from others.
+type LibraryIdentity struct {
+	Uuid         string //assigned on creation, never changes
+	Name         string //human-readable, optional
+	IdNamespace  uint32 //namespace of generated IDs, 0 if none
+	IdAllocation library.IdAllocation
+}
+
+// LibraryComparison groups the active records of two libraries ("here" and "there") by how they correspond to each other.
+// Records whose ID and content both match are only counted. All groups are sorted by path (on this side).
+type LibraryComparison struct {
+	OtherDatabase          string //absolute, system-native path
+	OtherUuid              string
+	OtherName              string
+	OnlyHere               []Record     //neither ID nor content found in the other library
+	OnlyThere              []Record     //neither ID nor content found in this library
+	SameIdDifferentContent []RecordPair //ID exists in both libraries but the content differs
+	SameContentDifferentId []RecordPair //content exists in both libraries but under a different ID which is unknown to the respective other library
+	SameIdDifferentPath    []RecordPair //ID and content match but the path differs
+	Identical              int          //number of records whose ID, content, and path match
+}
+
+// CopyCheck is the result of verifying a copy of a library root against the active records of the library.
+// All groups are sorted by path.
+type CopyCheck struct {
+	Directory  string           //absolute, system-native path of the copy
+	Intact     int              //number of records whose content is found at their path in the copy
+	Missing    []Record         //content found neither at the path of the record nor anywhere else in the copy
+	Corrupted  []Record         //file at the path of the record has different content
+	Misplaced  []MisplacedCopy  //content found in the copy, but not at the path of the record
+	Extra      []string         //files in the copy that do not correspond to any record, relative to the copy directory
+	Unreadable map[string]error //files and directories of the copy that could not be checked, relative to the copy directory
+}
+
+// Complete tells whether all records are present and intact in the copy (extra files do not matter).
+func (check CopyCheck) Complete() bool {
+	return len(check.Missing) == 0 && len(check.Corrupted) == 0 && len(check.Misplaced) == 0 && len(check.Unreadable) == 0
+}
+
+// MisplacedCopy links a record to the file in the copy which has the content of the record but is located elsewhere.
+type MisplacedCopy struct {
+	Record   Record
+	CopyPath string //relative to the copy directory
+}
+
+// RecordSelection narrows down the active records an operation applies to. The zero value selects all active records.
+// Records carry no tags, hence selection by tag is not supported.
+type RecordSelection struct {
+	IdPart  string //only records whose ID contains this case-insensitive full/partial ID (display format)
+	Subtree string //only records located below this directory
+}
+
+// RenamePlan lists the renames planned by RenameRecords and the conflicts preventing them (both sorted by path).
+type RenamePlan struct {
+	Renames   []PlannedRename
+	Conflicts []RenameConflict
+}
+
+type PlannedRename struct {
+	Record  Record
+	NewName string //filename only, the directory is kept
+}
+
+type RenameConflict struct {
+	Record  Record
+	Problem string
+}
+
+// ExportOptions select the records for Export and determine the layout of the copies.
+// The zero value exports all active records with the layout of the library.
+type ExportOptions struct {
+	RecordSelection
+	Flat   bool //all copies in the destination directory itself with standardized names instead of the directory structure of the library
+	Resume bool //continue a previous export into the same destination, existing copies are kept if they match the records
+}
+
+// ExportReport summarizes the result of Export.
+type ExportReport struct {
+	Destination string //absolute, system-native path
+	Manifest    string //absolute, system-native path
+	Copied      int
+	Bytes       int64 //total size of all copied files
+	Present     int   //number of records whose copy existed already (when resuming)
+	Failed      map[document.Id]error
+}
+
+// PackVerification is the result of checking an archive created by Pack against its manifest.
+// All groups list paths inside the archive and are sorted.
+type PackVerification struct {
+	Archive   string //absolute, system-native path
+	Library   string //UUID of the library the archive was packed from
+	Name      string //name of the library the archive was packed from
+	Packed    time.Time
+	Intact    int      //number of documents whose size and checksum match the manifest
+	Missing   []string //listed in the manifest but not contained in the archive
+	Corrupted []string //content differs from the manifest
+	Extra     []string //contained in the archive but not listed in the manifest
+}
+
+// Complete tells whether all documents of the manifest are present and intact in the archive (unlisted entries do not matter).
+func (verification PackVerification) Complete() bool {
+	return len(verification.Missing) == 0 && len(verification.Corrupted) == 0
+}
+
+// ExpiryReport is the result of Expire.
+type ExpiryReport struct {
+	Expired  []ExpiredRecord //sorted by path
+	Retained int             //number of active records whose retention period has not ended yet
+	Unruled  int             //number of active records without retention rule
+	Retired  int
+}
+
+// ExpiredRecord is an active record whose retention period has ended.
+type ExpiredRecord struct {
+	Record Record
+	Rule   library.RetentionRule
+	Expiry time.Time //end of the retention period
+}
+
+// DueReport is the result of CollectDue, both groups are sorted by due date.
+type DueReport struct {
+	Overdue  []Record
+	Upcoming []Record
+}
+
+// SimilarGroup is a result of FindSimilar, the records are sorted by path.
+type SimilarGroup struct {
+	Records   []Record
+	Distances []int //distance of each record's perceptual hash to the one of the first record
+}
+
+// DuplicateReport is the result of CollectDuplicates, the groups are sorted by the path of their preferred copy.
+type DuplicateReport struct {
+	Groups []DuplicateGroup
+	Wasted int64 //total size of all present copies but one of each group
+}
+
+// DuplicateGroup lists all copies of the same content, the preferred copy first.
+type DuplicateGroup struct {
+	Sha256 [sha256.Size]byte
+	Size   int64
+	Copies []DuplicateCopy
+}
+
+// DuplicateCopy is a file or a retired record with the content of the group.
+type DuplicateCopy struct {
+	Kind         DuplicateKind
+	Id           document.Id //missing for untracked files
+	AnchoredPath string      //relative to the library root, qualified with the root name for additional roots
+}
+
+// DuplicateKind tells whether a copy of duplicate content is a file on record, an untracked file, or a retired record.
+type DuplicateKind int
+
+const (
+	TrackedCopy   DuplicateKind = iota //file of an active record
+	UntrackedCopy                      //file whose content is not on record at its path
+	RetiredCopy                        //retired record (whose file may still be present as an untracked copy)
+)
+
+// MergeResolution determines how a conflict between a record of this library ("ours") and a record of another library ("theirs") is resolved.
+type MergeResolution int
+
+const (
+	KeepOurs   MergeResolution = iota //the record of the other library is not imported
+	TakeTheirs                        //the record of the other library is imported, the conflicting record is replaced (same ID) or retired (same path)
+	TakeNewer                         //the record which was changed more recently wins
+	Renumber                          //the record of the other library is imported under a new ID (only for ID collisions)
+)
+
+// MergeStrategy holds the resolution for each kind of conflict that can arise during MergeFrom.
+// The zero value keeps all conflicting records of this library.
+type MergeStrategy struct {
+	IdCollision   MergeResolution //same ID but independent documents (recorded at different times or differing in both path and content)
+	PathCollision MergeResolution //an active record of the other library occupies the path of a different active record
+	Divergence    MergeResolution //the same record was changed differently, i.e. either content hash, path, or retirement differs
+}
+
+// RecordPair links corresponding records of two libraries.
+type RecordPair struct {
+	Here  Record
+	There Record
 }
 
 // SearchResult represents a subset of information taken from an existing library record.
@@ -100,6 +498,32 @@ type SearchResult struct {
 	StatusText string
 }
 
+// CheckResult represents the state of a single path with respect to the library records.
+type CheckResult struct {
+	AnchoredPath      string //relative to the library root, qualified with the root name for additional roots
+	Status            library.PathStatus
+	IsChange          bool        //set if the path is not in sync with the records
+	IsOutdated        bool        //set if the record can be updated to match the file (touched, moved, and modified files)
+	IsWaste           bool        //set if the file has duplicate or obsolete content
+	Reference         document.Id //record the status refers to (e.g. the original location of a moved file), MissingId if none
+	ReferencePath     string      //anchored like AnchoredPath, empty if no record is referenced
+	Problem           error       //only set if the path could not be checked
+	AttributeConflict error       //set if the ID attribute of the file contradicts its record, regardless of the status
+}
+
+// Record represents the full state of a single library record.
+type Record struct {
+	Id           document.Id
+	AnchoredPath string //relative to the library root, qualified with the root name for additional roots
+	Size         int64
+	Sha256       [sha256.Size]byte
+	Recorded     time.Time //when the document entered the library
+	Changed      time.Time //when the record was last changed, i.e. also the retirement date of obsolete records
+	Modified     time.Time //modification timestamp of the file on record
+	Due          time.Time //deadline of the document (e.g. end of a contract or expiry of a certificate), zero if none
+	Retired      bool
+}
+
 // RequestChoice represents a single-choice decision callback, the first option is considered the default "yes"-like choice.
 // If the choice is aborted an empty string must be returned.
 // If cleanup is set the implementation is recommended to remove the choice presentation after selection.
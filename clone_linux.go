@@ -0,0 +1,32 @@
+//go:build linux
+
+package doccurator
+
+import (
+	"golang.org/x/sys/unix"
+	"os"
+)
+
+// cloneFile creates the target as a reflink clone of the source, i.e. both share their data until either is modified.
+// Only some filesystems (e.g. Btrfs, XFS) support this, otherwise an error is returned and no target is created.
+func cloneFile(source string, target string) error {
+	sourceFile, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+	stat, err := sourceFile.Stat()
+	if err != nil {
+		return err
+	}
+	targetFile, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, stat.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if err := unix.IoctlFileClone(int(targetFile.Fd()), int(sourceFile.Fd())); err != nil {
+		targetFile.Close()
+		os.Remove(target)
+		return err
+	}
+	return targetFile.Close()
+}
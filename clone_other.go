@@ -0,0 +1,9 @@
+//go:build !linux
+
+package doccurator
+
+import "errors"
+
+func cloneFile(string, string) error {
+	return errors.New("reflinks not supported on this platform")
+}
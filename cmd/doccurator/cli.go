@@ -6,13 +6,19 @@ import (
 	"fmt"
 	"github.com/n2code/doccurator"
 	cliflags "github.com/n2code/doccurator/cmd/doccurator/flags"
+	"github.com/n2code/doccurator/cmd/doccurator/server"
 	cliverbs "github.com/n2code/doccurator/cmd/doccurator/verbs"
 	"github.com/n2code/doccurator/internal/document"
+	"github.com/n2code/doccurator/internal/library"
 	out "github.com/n2code/doccurator/internal/output"
 	"github.com/n2code/ndocid"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type cliRequest struct {
@@ -28,6 +34,51 @@ type cliRequest struct {
 
 const defaultDbFileName = `doccurator.db`
 
+const mergeKeep = "keep"
+const mergeTheirs = "theirs"
+const mergeNewer = "newer"
+const mergeRenumber = "renumber"
+
+const dateFlagLayout = "2006-01-02"
+
+var mergeResolutions = map[string]doccurator.MergeResolution{
+	mergeKeep:     doccurator.KeepOurs,
+	mergeTheirs:   doccurator.TakeTheirs,
+	mergeNewer:    doccurator.TakeNewer,
+	mergeRenumber: doccurator.Renumber,
+}
+
+var idAllocationStrategies = []library.IdAllocationStrategy{library.AllocateByCurrentTime, library.AllocateByModificationTime, library.AllocateRandomly, library.AllocateFromBlock}
+
+// parseIdAllocation validates the strategy and, if given, the range of the form FIRST-LAST
+func parseIdAllocation(strategy string, idRange string) (allocation library.IdAllocation, err error) {
+	allocation.Strategy = library.IdAllocationStrategy(strategy)
+	known := false
+	for _, candidate := range idAllocationStrategies {
+		known = known || candidate == allocation.Strategy
+	}
+	if !known {
+		return allocation, fmt.Errorf(`unsupported value for flag "-%s": %s`, cliflags.IdentityIdAllocation, strategy)
+	}
+	ranged := allocation.Strategy == library.AllocateRandomly || allocation.Strategy == library.AllocateFromBlock
+	switch {
+	case ranged && idRange == "":
+		return allocation, fmt.Errorf(`flag "-%s" required for ID allocation "%s"`, cliflags.IdentityIdRange, strategy)
+	case !ranged && idRange != "":
+		return allocation, fmt.Errorf(`flag "-%s" not applicable to ID allocation "%s"`, cliflags.IdentityIdRange, strategy)
+	case !ranged:
+		return allocation, nil
+	}
+	first, last, separated := strings.Cut(idRange, "-")
+	firstNumber, firstErr := strconv.ParseUint(first, 10, 32)
+	lastNumber, lastErr := strconv.ParseUint(last, 10, 32)
+	if !separated || firstErr != nil || lastErr != nil || firstNumber == 0 || firstNumber > lastNumber {
+		return allocation, fmt.Errorf(`unsupported value for flag "-%s": %s`, cliflags.IdentityIdRange, idRange)
+	}
+	allocation.First, allocation.Last = uint32(firstNumber), uint32(lastNumber)
+	return allocation, nil
+}
+
 func parseFlags(args []string, errOut io.Writer) (request *cliRequest, exitCode int) {
 	flags := flag.NewFlagSet("", flag.ExitOnError)
 	flags.Usage = func() {
@@ -35,7 +86,7 @@ func parseFlags(args []string, errOut io.Writer) (request *cliRequest, exitCode
 Usage:
    doccurator [-` + cliflags.Verbose + `|-` + cliflags.Quiet + `] [-` + cliflags.Thorough + `] [-` + cliflags.All + `] [-` + cliflags.Plain + `] [-` + cliflags.Help + `] <ACTION> [FLAG] [TARGET]
 
- ACTIONs:  ` + cliverbs.Init + `  ` + cliverbs.Status + `  ` + cliverbs.Add + `  ` + cliverbs.Update + `  ` + cliverbs.Tidy + `  ` + cliverbs.Search + `  ` + cliverbs.Retire + `  ` + cliverbs.Forget + `  ` + cliverbs.Tree + `  ` + cliverbs.Dump + `
+ ACTIONs:  ` + cliverbs.Init + `  ` + cliverbs.Root + `  ` + cliverbs.RelocateDb + `  ` + cliverbs.Identity + `  ` + cliverbs.Naming + `  ` + cliverbs.Libraries + `  ` + cliverbs.Status + `  ` + cliverbs.Add + `  ` + cliverbs.Update + `  ` + cliverbs.Tidy + `  ` + cliverbs.Rename + `  ` + cliverbs.Restore + `  ` + cliverbs.Search + `  ` + cliverbs.Compare + `  ` + cliverbs.CheckCopy + `  ` + cliverbs.Export + `  ` + cliverbs.Pack + `  ` + cliverbs.VerifyPack + `  ` + cliverbs.Unpack + `  ` + cliverbs.Merge + `  ` + cliverbs.Split + `  ` + cliverbs.Retention + `  ` + cliverbs.Expire + `  ` + cliverbs.Due + `  ` + cliverbs.Similar + `  ` + cliverbs.Duplicates + `  ` + cliverbs.Retire + `  ` + cliverbs.Forget + `  ` + cliverbs.Tree + `  ` + cliverbs.Dump + `  ` + cliverbs.Serve + `
 
 `))
 		flags.PrintDefaults()
@@ -115,14 +166,31 @@ Usage of %s action:
 ActionParamCheck:
 	switch request.action {
 	case cliverbs.Status:
+		flagSpecification = " [-" + cliflags.StatusOfAllLibraries + "]"
 		argumentSpecification = " [FILEPATH...]"
 		actionDescription += "Compare files in the library folder to the records.\n" +
 			actionDescriptionIndent + "If one or more FILEPATHs are given only compare those files otherwise\n" +
 			actionDescriptionIndent + "compare all. For an explicit list of paths all states are listed. For\n" +
 			actionDescriptionIndent + "a full scan (no paths specified) unchanged tracked files are omitted."
+		request.actionFlags[cliflags.StatusOfAllLibraries] = actionParams.Bool(cliflags.StatusOfAllLibraries, false, "scan all known libraries (see \""+cliverbs.Libraries+"\") instead of the current one\nand conclude with a combined summary")
 		actionParams.Parse(request.actionArgs)
 		request.actionArgs = actionParams.Args()
+		if *(request.actionFlags[cliflags.StatusOfAllLibraries].(*bool)) && actionParams.NArg() > 0 {
+			err = errors.New(`no FILEPATH can be given with flag "-` + cliflags.StatusOfAllLibraries + `"`)
+			break ActionParamCheck
+		}
 		//beyond flags all arguments are optional
+	case cliverbs.Libraries:
+		actionDescription += "List all known libraries, i.e. those which have been created or opened\n" +
+			actionDescriptionIndent + "by the current user, along with their root directory, database file,\n" +
+			actionDescriptionIndent + "number of records, and time of the last commit. The registry of known\n" +
+			actionDescriptionIndent + "libraries is kept in the user configuration directory."
+		actionParams.Parse(request.actionArgs)
+		request.actionArgs = actionParams.Args()
+		if actionParams.NArg() > 0 {
+			err = errors.New("command accepts no arguments")
+			break ActionParamCheck
+		}
 	case cliverbs.Add, cliverbs.Update, cliverbs.Retire, cliverbs.Forget:
 		argumentSpecification = " [FILEPATH...]"
 		switch request.action {
@@ -134,10 +202,12 @@ ActionParamCheck:
 				actionDescriptionIndent + "Alternatively all untracked files can be added automatically via flag."
 			request.actionFlags[cliflags.AddAllUntracked] = actionParams.Bool(cliflags.AddAllUntracked, false, "add all untracked files anywhere inside the library\n"+
 				`(requires *standardized* filenames or/and flag "-`+cliflags.AddWithAutoId+`")`)
-			request.actionFlags[cliflags.AddWithForce] = actionParams.Bool(cliflags.AddWithForce, false, "allow adding even duplicates, moved, and obsolete files as new\n"+
+			request.actionFlags[cliflags.AddWithForce] = actionParams.Bool(cliflags.AddWithForce, false, "allow adding even duplicates, moved, and obsolete files as new as well\n"+
+				"as files whose ID belongs to a foreign ID namespace (see \""+cliverbs.Identity+"\")\n"+
 				"(because this is likely undesired and thus blocked by default)")
-			request.actionFlags[cliflags.AddWithAutoId] = actionParams.Bool(cliflags.AddWithAutoId, false, "automatically choose free ID based on current time if filename\n"+
-				"is not *standardized* and hence ID cannot be extracted from it")
+			request.actionFlags[cliflags.AddWithAutoId] = actionParams.Bool(cliflags.AddWithAutoId, false, "automatically choose free ID according to the ID allocation of the\n"+
+				"library (see \""+cliverbs.Identity+"\") if filename is not *standardized* and hence\n"+
+				"ID cannot be extracted from it")
 			request.actionFlags[cliflags.AddWithRename] = actionParams.Bool(cliflags.AddWithRename, false, "rename added files to standardized filename")
 			request.actionFlags[cliflags.AddButAbortOnError] = actionParams.Bool(cliflags.AddButAbortOnError, false, "abort if any error occurs, do not skip issues (mass operations)")
 			request.actionFlags[cliflags.AddWithGivenId] = actionParams.String(cliflags.AddWithGivenId, "", "specify new document ID instead of extracting it from filename\n"+
@@ -237,7 +307,7 @@ ActionParamCheck:
 			break ActionParamCheck
 		}
 	case cliverbs.Init:
-		flagSpecification = " [-" + cliflags.InitDatabase + "=...] [-" + cliflags.InitUpdateRoot + "]"
+		flagSpecification = " [-" + cliflags.InitDatabase + "=...] [-" + cliflags.InitUpdateRoot + " [-" + cliflags.InitForHost + "=...]] [-" + cliflags.InitRelativeRoot + "]"
 		argumentSpecification = " DIRECTORY"
 		actionDescription += "Initialize a new library in the given root DIRECTORY. Everything below\n" +
 			actionDescriptionIndent + "the root is considered to be located 'inside' the library. All files\n" +
@@ -245,10 +315,14 @@ ActionParamCheck:
 			actionDescriptionIndent + "The library state is recorded in a single database file. If either\n" +
 			actionDescriptionIndent + "the database file or the library folder is moved the library cannot\n" +
 			actionDescriptionIndent + "be operated on until initialization is repeated with special flags\n" +
-			actionDescriptionIndent + "for migration."
+			actionDescriptionIndent + "for migration. To avoid this for libraries on removable drives the root\n" +
+			actionDescriptionIndent + "can be recorded relative to the database file. If the library is used\n" +
+			actionDescriptionIndent + "on several machines the root can be overridden per host."
 
 		request.actionFlags[cliflags.InitDatabase] = actionParams.String(cliflags.InitDatabase, "", "library database file to be created, relative to current working\ndirectory unless an absolute path is given\n(default if empty or flag omitted: \""+defaultDbFileName+"\" in DIRECTORY)")
 		request.actionFlags[cliflags.InitUpdateRoot] = actionParams.Bool(cliflags.InitUpdateRoot, false, "update existing library file (flag \"-"+cliflags.InitDatabase+"\" is mandatory)\nwith new root DIRECTORY instead of creating a fresh library")
+		request.actionFlags[cliflags.InitRelativeRoot] = actionParams.Bool(cliflags.InitRelativeRoot, false, "record root DIRECTORY relative to the database file so that both\ncan be moved together, e.g. if the drive is mounted elsewhere")
+		request.actionFlags[cliflags.InitForHost] = actionParams.String(cliflags.InitForHost, "", "only use the new root DIRECTORY on the host with the given name\n(requires \"-"+cliflags.InitUpdateRoot+"\", the root for other hosts is kept)")
 		actionParams.Parse(request.actionArgs)
 		request.actionArgs = actionParams.Args()
 		if actionParams.NArg() != 1 {
@@ -257,6 +331,129 @@ ActionParamCheck:
 		}
 		if *(request.actionFlags[cliflags.InitUpdateRoot].(*bool)) && *(request.actionFlags[cliflags.InitDatabase].(*string)) == "" {
 			err = errors.New(`flag "-` + cliflags.InitUpdateRoot + `" requires "-` + cliflags.InitDatabase + `" to be specified`)
+			break ActionParamCheck
+		}
+		if !*(request.actionFlags[cliflags.InitUpdateRoot].(*bool)) && *(request.actionFlags[cliflags.InitForHost].(*string)) != "" {
+			err = errors.New(`flag "-` + cliflags.InitForHost + `" requires "-` + cliflags.InitUpdateRoot + `"`)
+		}
+	case cliverbs.Root:
+		flagSpecification = " [-" + cliflags.RootRemove + "]"
+		argumentSpecification = " [NAME [DIRECTORY]]"
+		actionDescription += "Manage the root directories of the library. Besides the primary root\n" +
+			actionDescriptionIndent + "(see \"" + cliverbs.Init + "\") a library can span additional root directories which\n" +
+			actionDescriptionIndent + "are identified by NAME. Their files are shown as lib:NAME//path/to/file.\n" +
+			actionDescriptionIndent + "Without arguments all roots are listed. Given NAME and DIRECTORY the\n" +
+			actionDescriptionIndent + "root is added or, if it exists already, relocated to DIRECTORY."
+		request.actionFlags[cliflags.RootRemove] = actionParams.Bool(cliflags.RootRemove, false, "remove the root with the given NAME (only possible if no\nrecords refer to it anymore, retired records included)")
+		actionParams.Parse(request.actionArgs)
+		request.actionArgs = actionParams.Args()
+		if *(request.actionFlags[cliflags.RootRemove].(*bool)) {
+			if actionParams.NArg() != 1 {
+				err = errors.New(`exactly one NAME must be given when using flag "-` + cliflags.RootRemove + `"`)
+				break ActionParamCheck
+			}
+		} else if actionParams.NArg() == 1 {
+			err = errors.New("DIRECTORY missing")
+			break ActionParamCheck
+		} else if actionParams.NArg() > 2 {
+			err = errors.New("too many arguments")
+			break ActionParamCheck
+		}
+	case cliverbs.RelocateDb:
+		argumentSpecification = " PATH"
+		actionDescription += "Move the library database file to the given PATH (or into the directory\n" +
+			actionDescriptionIndent + "at PATH keeping its name). All library locators in the library root\n" +
+			actionDescriptionIndent + "directories are updated and the library is reopened for verification."
+		actionParams.Parse(request.actionArgs)
+		request.actionArgs = actionParams.Args()
+		if actionParams.NArg() != 1 {
+			err = errors.New("bad number of arguments, exactly one expected")
+			break ActionParamCheck
+		}
+	case cliverbs.Identity:
+		flagSpecification = " [-" + cliflags.IdentityName + "=...] [-" + cliflags.IdentityIdNamespace + "=...] [-" + cliflags.IdentityIdAllocation + "=... [-" + cliflags.IdentityIdRange + "=...]]"
+		actionDescription += "Display or change the identity of the library. Each library has a unique\n" +
+			actionDescriptionIndent + "ID assigned on creation and can be given a name. To keep document IDs unique\n" +
+			actionDescriptionIndent + "across several libraries each library can generate IDs in its own namespace.\n" +
+			actionDescriptionIndent + "Files whose ID belongs to a different namespace are then recognized when\n" +
+			actionDescriptionIndent + "added. The allocation of IDs for new documents is chosen per library, e.g.\n" +
+			actionDescriptionIndent + "a reserved block of IDs for a device that adds files while offline.\n" +
+			actionDescriptionIndent + "Without flags the current identity is displayed."
+		request.actionFlags[cliflags.IdentityName] = actionParams.String(cliflags.IdentityName, "", "set the name of the library (empty to remove it)")
+		request.actionFlags[cliflags.IdentityIdNamespace] = actionParams.Uint(cliflags.IdentityIdNamespace, 0, "set the namespace of generated document IDs, a number between\n1 and 4294967295 (0 disables namespacing, existing IDs are kept)")
+		request.actionFlags[cliflags.IdentityIdAllocation] = actionParams.String(cliflags.IdentityIdAllocation, string(library.AllocateByCurrentTime), "set how IDs of new documents are generated:\n"+
+			"\""+string(library.AllocateByCurrentTime)+"\" (counting down from the current time, default),\n"+
+			"\""+string(library.AllocateByModificationTime)+"\" (counting down from the modification time of the file),\n"+
+			"\""+string(library.AllocateRandomly)+"\" (random ID inside the range), or\n"+
+			"\""+string(library.AllocateFromBlock)+"\" (counting up inside the range)")
+		request.actionFlags[cliflags.IdentityIdRange] = actionParams.String(cliflags.IdentityIdRange, "", "range FIRST-LAST of IDs for allocation \""+string(library.AllocateRandomly)+"\" and \""+string(library.AllocateFromBlock)+"\", given\nas numbers between 1 and 4294967295")
+		actionParams.Parse(request.actionArgs)
+		request.actionArgs = actionParams.Args()
+		given := make(map[string]bool)
+		actionParams.Visit(func(f *flag.Flag) { given[f.Name] = true })
+		for _, name := range []string{cliflags.IdentityName, cliflags.IdentityIdNamespace, cliflags.IdentityIdAllocation, cliflags.IdentityIdRange} {
+			if !given[name] {
+				delete(request.actionFlags, name) //only flags given explicitly change the identity
+			}
+		}
+		if actionParams.NArg() > 0 {
+			err = errors.New("command accepts no arguments, only flags")
+			break ActionParamCheck
+		}
+		if namespace, given := request.actionFlags[cliflags.IdentityIdNamespace]; given && *(namespace.(*uint)) > math.MaxUint32 {
+			err = errors.New(`value of flag "-` + cliflags.IdentityIdNamespace + `" out of range`)
+			break ActionParamCheck
+		}
+		_, allocationGiven := request.actionFlags[cliflags.IdentityIdAllocation]
+		if idRange, rangeGiven := request.actionFlags[cliflags.IdentityIdRange]; rangeGiven && !allocationGiven {
+			err = fmt.Errorf(`flag "-%s" requires "-%s"`, cliflags.IdentityIdRange, cliflags.IdentityIdAllocation)
+			break ActionParamCheck
+		} else if allocationGiven {
+			rangeValue := ""
+			if rangeGiven {
+				rangeValue = *(idRange.(*string))
+			}
+			if _, err = parseIdAllocation(*(request.actionFlags[cliflags.IdentityIdAllocation].(*string)), rangeValue); err != nil {
+				break ActionParamCheck
+			}
+		}
+	case cliverbs.Naming:
+		flagSpecification = " [-" + cliflags.NamingTemplate + "=...] [-" + cliflags.NamingMigrate + " [-" + cliflags.NamingDryRun + "]] [-" + cliflags.NamingIdAttribute + "=...] [-" + cliflags.NamingWriteIdAttributes + "]"
+		actionDescription += "Display or change the template of standardized filenames which contain the\n" +
+			actionDescriptionIndent + "document ID (see \"" + cliverbs.Add + "\"). Placeholders are {name} (filename without\n" +
+			actionDescriptionIndent + "extension), {ext} (extension with dot), {id}, and {date} (modification date\n" +
+			actionDescriptionIndent + "YYYY-MM-DD), e.g. \"{date}_{name}.{id}{ext}\" or \"{id}-{name}{ext}\". Names\n" +
+			actionDescriptionIndent + "following a previous template are still recognized and can be migrated.\n" +
+			actionDescriptionIndent + "Alternatively the ID can be stored in an extended attribute of the file\n" +
+			actionDescriptionIndent + "(" + library.IdAttributeName + ") which keeps a file linked to its record even if\n" +
+			actionDescriptionIndent + "it is renamed, moved, and edited. Inconsistent attributes are shown by\n" +
+			actionDescriptionIndent + "\"" + cliverbs.Status + "\"."
+		request.actionFlags[cliflags.NamingTemplate] = actionParams.String(cliflags.NamingTemplate, "", "set the template (default: \""+string(document.DefaultNameTemplate)+"\")")
+		request.actionFlags[cliflags.NamingMigrate] = actionParams.Bool(cliflags.NamingMigrate, false, "rename all files with standardized names to follow the current template")
+		request.actionFlags[cliflags.NamingDryRun] = actionParams.Bool(cliflags.NamingDryRun, false, "only show what would be renamed, do not change any files")
+		request.actionFlags[cliflags.NamingIdAttribute] = actionParams.String(cliflags.NamingIdAttribute, "", "set which extended attributes are written when files are added:\n"+
+			"\""+string(library.NoIdAttribute)+"\" (default), \""+string(library.IdAttributeOnly)+"\", or \""+string(library.IdAttributeWithHash)+"\" (ID and checksum of the content)")
+		request.actionFlags[cliflags.NamingWriteIdAttributes] = actionParams.Bool(cliflags.NamingWriteIdAttributes, false, "write the extended attributes to the files of all records which are in\norder, e.g. to fix inconsistent attributes")
+		actionParams.Parse(request.actionArgs)
+		request.actionArgs = actionParams.Args()
+		if actionParams.NArg() > 0 {
+			err = errors.New("command accepts no arguments, only flags")
+			break ActionParamCheck
+		}
+		if *(request.actionFlags[cliflags.NamingDryRun].(*bool)) && !*(request.actionFlags[cliflags.NamingMigrate].(*bool)) {
+			err = fmt.Errorf(`flag "-%s" requires "-%s"`, cliflags.NamingDryRun, cliflags.NamingMigrate)
+			break ActionParamCheck
+		}
+		if template := *(request.actionFlags[cliflags.NamingTemplate].(*string)); template != "" {
+			if err = document.NameTemplate(template).Validate(); err != nil {
+				break ActionParamCheck
+			}
+		}
+		switch mode := library.IdAttributeMode(*(request.actionFlags[cliflags.NamingIdAttribute].(*string))); mode {
+		case "", library.NoIdAttribute, library.IdAttributeOnly, library.IdAttributeWithHash:
+		default:
+			err = fmt.Errorf(`unsupported value for flag "-%s": %s`, cliflags.NamingIdAttribute, mode)
+			break ActionParamCheck
 		}
 	case cliverbs.Search:
 		argumentSpecification = " ID"
@@ -267,18 +464,331 @@ ActionParamCheck:
 			err = errors.New("bad number of arguments, exactly one expected")
 			break ActionParamCheck
 		}
+	case cliverbs.Compare:
+		flagSpecification = " [-" + cliflags.CompareAsJson + "]"
+		argumentSpecification = " OTHER_DB"
+		actionDescription += "Compare the library with the library in the database file OTHER_DB, e.g.\n" +
+			actionDescriptionIndent + "a backup. Active records are matched by ID and by content to list those\n" +
+			actionDescriptionIndent + "that exist on one side only, those whose content differs despite the same\n" +
+			actionDescriptionIndent + "ID, those whose content exists under a different ID, and those whose\n" +
+			actionDescriptionIndent + "path differs. The other library is not modified."
+		request.actionFlags[cliflags.CompareAsJson] = actionParams.Bool(cliflags.CompareAsJson, false, "output the comparison as JSON (with paths relative to the respective\nlibrary root)")
+		actionParams.Parse(request.actionArgs)
+		request.actionArgs = actionParams.Args()
+		if actionParams.NArg() != 1 {
+			err = errors.New("bad number of arguments, exactly one expected")
+			break ActionParamCheck
+		}
+	case cliverbs.CheckCopy:
+		flagSpecification = " [-" + cliflags.CheckCopyRoot + "=...]"
+		argumentSpecification = " DIR"
+		actionDescription += "Verify a copy of the library in DIR, e.g. a backup on an external disk.\n" +
+			actionDescriptionIndent + "All files in DIR are hashed and matched to the active records by path and\n" +
+			actionDescriptionIndent + "then by content to list the records that are missing, corrupted, or\n" +
+			actionDescriptionIndent + "misplaced in the copy as well as extra files. No database is needed in DIR.\n" +
+			actionDescriptionIndent + "Fails unless all records are present and intact."
+		request.actionFlags[cliflags.CheckCopyRoot] = actionParams.String(cliflags.CheckCopyRoot, "", "name of the library root that DIR is a copy of\n(default if empty or flag omitted: primary root)")
+		actionParams.Parse(request.actionArgs)
+		request.actionArgs = actionParams.Args()
+		if actionParams.NArg() != 1 {
+			err = errors.New("bad number of arguments, exactly one expected")
+			break ActionParamCheck
+		}
+	case cliverbs.Export:
+		flagSpecification = " [-" + cliflags.ExportIdPart + "=...] [-" + cliflags.ExportSubtree + "=...] [-" + cliflags.ExportFlat + "] [-" + cliflags.ExportResume + "]"
+		argumentSpecification = " DEST"
+		actionDescription += "Copy the files of active records to the directory DEST outside of the\n" +
+			actionDescriptionIndent + "library, e.g. to hand them over. Each copy is verified against the\n" +
+			actionDescriptionIndent + "recorded checksum and listed in the manifest file " + doccurator.ExportManifestFileName + "\n" +
+			actionDescriptionIndent + "in DEST. An interrupted or partially failed export can be resumed.\n" +
+			actionDescriptionIndent + "Records are selected by ID or directory, records carry no tags to\n" +
+			actionDescriptionIndent + "select them by."
+		request.actionFlags[cliflags.ExportIdPart] = actionParams.String(cliflags.ExportIdPart, "", "only export records whose ID contains the given full/partial ID")
+		request.actionFlags[cliflags.ExportSubtree] = actionParams.String(cliflags.ExportSubtree, "", "only export records located below the given directory")
+		request.actionFlags[cliflags.ExportFlat] = actionParams.Bool(cliflags.ExportFlat, false, "place all copies in DEST itself with standardized names (which contain\nthe ID) instead of keeping the directory structure of the library")
+		request.actionFlags[cliflags.ExportResume] = actionParams.Bool(cliflags.ExportResume, false, "continue a previous export to DEST, copies that match the records are\nkept")
+		actionParams.Parse(request.actionArgs)
+		request.actionArgs = actionParams.Args()
+		if actionParams.NArg() != 1 {
+			err = errors.New("bad number of arguments, exactly one expected")
+			break ActionParamCheck
+		}
+	case cliverbs.Pack:
+		flagSpecification = " [-" + cliflags.PackIdPart + "=...] [-" + cliflags.PackSubtree + "=...] [-" + cliflags.PackFlat + "]"
+		argumentSpecification = " ARCHIVE"
+		actionDescription += "Create the archive ARCHIVE (.zip, .tar.gz, or .tgz) with the files of\n" +
+			actionDescriptionIndent + "active records, e.g. to send a bundle of documents to an advisor. The\n" +
+			actionDescriptionIndent + "archive contains the manifest file " + doccurator.PackManifestFileName + " which lists\n" +
+			actionDescriptionIndent + "IDs, original paths, sizes, checksums, and timestamps of all documents."
+		request.actionFlags[cliflags.PackIdPart] = actionParams.String(cliflags.PackIdPart, "", "only pack records whose ID contains the given full/partial ID")
+		request.actionFlags[cliflags.PackSubtree] = actionParams.String(cliflags.PackSubtree, "", "only pack records located below the given directory")
+		request.actionFlags[cliflags.PackFlat] = actionParams.Bool(cliflags.PackFlat, false, "place all files at the top of the archive with standardized names\n(which contain the ID) instead of keeping the directory structure")
+		actionParams.Parse(request.actionArgs)
+		request.actionArgs = actionParams.Args()
+		if actionParams.NArg() != 1 {
+			err = errors.New("bad number of arguments, exactly one expected")
+			break ActionParamCheck
+		}
+	case cliverbs.VerifyPack:
+		argumentSpecification = " ARCHIVE"
+		actionDescription += "Check the integrity of the archive ARCHIVE created by \"" + cliverbs.Pack + "\" against the\n" +
+			actionDescriptionIndent + "manifest it contains. No library is needed."
+		actionParams.Parse(request.actionArgs)
+		request.actionArgs = actionParams.Args()
+		if actionParams.NArg() != 1 {
+			err = errors.New("bad number of arguments, exactly one expected")
+			break ActionParamCheck
+		}
+	case cliverbs.Unpack:
+		flagSpecification = " [-" + cliflags.UnpackDirectory + "=...]"
+		argumentSpecification = " ARCHIVE"
+		actionDescription += "Extract the archive ARCHIVE created by \"" + cliverbs.Pack + "\" into the library and add\n" +
+			actionDescriptionIndent + "all documents, keeping their IDs unless taken. The archive is verified first,\n" +
+			actionDescriptionIndent + "existing files are never overwritten."
+		request.actionFlags[cliflags.UnpackDirectory] = actionParams.String(cliflags.UnpackDirectory, "", "directory inside the library to extract to\n(default if empty or flag omitted: current working directory)")
+		actionParams.Parse(request.actionArgs)
+		request.actionArgs = actionParams.Args()
+		if actionParams.NArg() != 1 {
+			err = errors.New("bad number of arguments, exactly one expected")
+			break ActionParamCheck
+		}
+	case cliverbs.Merge:
+		flagSpecification = " [-" + cliflags.MergePreview + "] [-" + cliflags.MergeOnIdCollision + "=...] [-" + cliflags.MergeOnPathCollision + "=...] [-" + cliflags.MergeOnDivergence + "=...]"
+		argumentSpecification = " OTHER_DB"
+		actionDescription += "Import the records of the library in the database file OTHER_DB, e.g. of a\n" +
+			actionDescriptionIndent + "copy of the library that was changed independently, retired records and\n" +
+			actionDescriptionIndent + "timestamps included. Conflicts are resolved as specified by the flags\n" +
+			actionDescriptionIndent + "which accept \"" + mergeKeep + "\" (default), \"" + mergeTheirs + "\", or \"" + mergeNewer + "\" (the more recently\n" +
+			actionDescriptionIndent + "changed record wins). The other library is not modified."
+		request.actionFlags[cliflags.MergePreview] = actionParams.Bool(cliflags.MergePreview, false, "only show the merge plan, do not change the library")
+		request.actionFlags[cliflags.MergeOnIdCollision] = actionParams.String(cliflags.MergeOnIdCollision, mergeKeep, "resolution for records with the same ID but unrelated documents\n(additionally accepts \""+mergeRenumber+"\" to import them under a new ID)")
+		request.actionFlags[cliflags.MergeOnPathCollision] = actionParams.String(cliflags.MergeOnPathCollision, mergeKeep, "resolution for active records of different documents at the same\npath (the losing record here is retired)")
+		request.actionFlags[cliflags.MergeOnDivergence] = actionParams.String(cliflags.MergeOnDivergence, mergeKeep, "resolution for records of the same document that were changed in\nboth libraries, e.g. content hashes differ")
+		actionParams.Parse(request.actionArgs)
+		request.actionArgs = actionParams.Args()
+		if actionParams.NArg() != 1 {
+			err = errors.New("bad number of arguments, exactly one expected")
+			break ActionParamCheck
+		}
+		for _, conflictFlag := range []string{cliflags.MergeOnIdCollision, cliflags.MergeOnPathCollision, cliflags.MergeOnDivergence} {
+			resolution := *(request.actionFlags[conflictFlag].(*string))
+			if _, valid := mergeResolutions[resolution]; !valid || (resolution == mergeRenumber && conflictFlag != cliflags.MergeOnIdCollision) {
+				err = fmt.Errorf(`unsupported value for flag "-%s": %s`, conflictFlag, resolution)
+				break ActionParamCheck
+			}
+		}
+	case cliverbs.Split:
+		flagSpecification = " [-" + cliflags.SplitDatabase + "=...]"
+		argumentSpecification = " DIRECTORY"
+		actionDescription += "Turn the given DIRECTORY inside the library into a library of its own,\n" +
+			actionDescriptionIndent + "e.g. to archive a finished project. All records below DIRECTORY are moved\n" +
+			actionDescriptionIndent + "into the new library and keep their IDs and history. The DIRECTORY is\n" +
+			actionDescriptionIndent + "ignored by the current library from then on (see ignore files)."
+		request.actionFlags[cliflags.SplitDatabase] = actionParams.String(cliflags.SplitDatabase, "", "database file of the new library to be created, relative to current\nworking directory unless an absolute path is given\n(default if empty or flag omitted: \""+defaultDbFileName+"\" in DIRECTORY)")
+		actionParams.Parse(request.actionArgs)
+		request.actionArgs = actionParams.Args()
+		if actionParams.NArg() != 1 {
+			err = errors.New("bad number of arguments, exactly one expected")
+			break ActionParamCheck
+		}
+	case cliverbs.Retention:
+		flagSpecification = " [-" + cliflags.RetentionRemove + "] [-" + cliflags.RetentionYears + "=... [-" + cliflags.RetentionBasis + "=...] [-" + cliflags.RetentionFromEndOfYear + "]]"
+		argumentSpecification = " [DIRECTORY]"
+		actionDescription += "Manage the retention rules of the library which determine how long the\n" +
+			actionDescriptionIndent + "documents below DIRECTORY have to be kept (see \"" + cliverbs.Expire + "\"). If rules exist\n" +
+			actionDescriptionIndent + "for nested directories the most specific one applies. Rules are bound\n" +
+			actionDescriptionIndent + "to directories only, records carry no tags to bind them to. Without\n" +
+			actionDescriptionIndent + "arguments all rules are listed."
+		request.actionFlags[cliflags.RetentionRemove] = actionParams.Bool(cliflags.RetentionRemove, false, "remove the rule of DIRECTORY")
+		request.actionFlags[cliflags.RetentionYears] = actionParams.Uint(cliflags.RetentionYears, 0, "set the rule of DIRECTORY to keep documents for the given number of years")
+		request.actionFlags[cliflags.RetentionBasis] = actionParams.String(cliflags.RetentionBasis, string(library.RetainSinceRecorded), "start of the retention period: \""+string(library.RetainSinceRecorded)+"\" (when the document entered the\nlibrary) or \""+string(library.RetainSinceModified)+"\" (modification time of the file on record)")
+		request.actionFlags[cliflags.RetentionFromEndOfYear] = actionParams.Bool(cliflags.RetentionFromEndOfYear, false, "start the retention period at the end of the calendar year of the basis\ndate (as required e.g. for German bookkeeping records)")
+		actionParams.Parse(request.actionArgs)
+		request.actionArgs = actionParams.Args()
+		remove, years := *(request.actionFlags[cliflags.RetentionRemove].(*bool)), *(request.actionFlags[cliflags.RetentionYears].(*uint))
+		switch {
+		case actionParams.NArg() > 1:
+			err = errors.New("too many arguments")
+		case remove && years > 0:
+			err = fmt.Errorf(`flags "-%s" and "-%s" are mutually exclusive`, cliflags.RetentionRemove, cliflags.RetentionYears)
+		case (remove || years > 0) && actionParams.NArg() == 0:
+			err = errors.New("DIRECTORY missing")
+		case !remove && years == 0 && actionParams.NArg() == 1:
+			err = fmt.Errorf(`either flag "-%s" or "-%s" is required with DIRECTORY`, cliflags.RetentionYears, cliflags.RetentionRemove)
+		}
+		if err != nil {
+			break ActionParamCheck
+		}
+		if basis := library.RetentionBasis(*(request.actionFlags[cliflags.RetentionBasis].(*string))); basis != library.RetainSinceRecorded && basis != library.RetainSinceModified {
+			err = fmt.Errorf(`unsupported value for flag "-%s": %s`, cliflags.RetentionBasis, basis)
+			break ActionParamCheck
+		}
+	case cliverbs.Expire:
+		flagSpecification = " [-" + cliflags.ExpireRetiring + "] [-" + cliflags.ExpireAsOf + "=...]"
+		actionDescription += "List all active records whose retention period has ended according to the\n" +
+			actionDescriptionIndent + "retention rules (see \"" + cliverbs.Retention + "\"), i.e. documents that may be destroyed.\n" +
+			actionDescriptionIndent + "Records without applicable rule are never considered expired."
+		request.actionFlags[cliflags.ExpireRetiring] = actionParams.Bool(cliflags.ExpireRetiring, false, "retire all listed records at once (files are not touched)")
+		request.actionFlags[cliflags.ExpireAsOf] = actionParams.String(cliflags.ExpireAsOf, "", "reference date YYYY-MM-DD (default if empty or flag omitted: today)")
+		actionParams.Parse(request.actionArgs)
+		request.actionArgs = actionParams.Args()
+		if actionParams.NArg() > 0 {
+			err = errors.New("command accepts no arguments, only flags")
+			break ActionParamCheck
+		}
+		if asOf := *(request.actionFlags[cliflags.ExpireAsOf].(*string)); asOf != "" {
+			if _, parseErr := time.ParseInLocation(dateFlagLayout, asOf, time.Local); parseErr != nil {
+				err = fmt.Errorf(`unsupported value for flag "-%s": %s`, cliflags.ExpireAsOf, asOf)
+				break ActionParamCheck
+			}
+		}
+	case cliverbs.Due:
+		flagSpecification = " [-" + cliflags.DueWithinDays + "=...] | -" + cliflags.DueSet + "=... | -" + cliflags.DueClear
+		argumentSpecification = " [ID]"
+		actionDescription += "List documents whose due date (e.g. end of a contract) has passed or is\n" +
+			actionDescriptionIndent + "near. Fails if any document is overdue, e.g. to alert via cron. Given an\n" +
+			actionDescriptionIndent + "ID the due date of the document is set or cleared instead. Certificate files\n" +
+			actionDescriptionIndent + "(X.509: .crt, .cer, .pem, .der) are due when they expire automatically."
+		request.actionFlags[cliflags.DueWithinDays] = actionParams.Uint(cliflags.DueWithinDays, 30, "number of days ahead to list upcoming documents for")
+		request.actionFlags[cliflags.DueSet] = actionParams.String(cliflags.DueSet, "", "set the due date YYYY-MM-DD of the document with the given ID")
+		request.actionFlags[cliflags.DueClear] = actionParams.Bool(cliflags.DueClear, false, "clear the due date of the document with the given ID")
+		actionParams.Parse(request.actionArgs)
+		request.actionArgs = actionParams.Args()
+		set, clear := *(request.actionFlags[cliflags.DueSet].(*string)), *(request.actionFlags[cliflags.DueClear].(*bool))
+		switch {
+		case set != "" && clear:
+			err = fmt.Errorf(`flags "-%s" and "-%s" are mutually exclusive`, cliflags.DueSet, cliflags.DueClear)
+		case (set != "" || clear) && actionParams.NArg() != 1:
+			err = errors.New("exactly one ID expected")
+		case set == "" && !clear && actionParams.NArg() > 0:
+			err = fmt.Errorf(`either flag "-%s" or "-%s" is required with ID`, cliflags.DueSet, cliflags.DueClear)
+		}
+		if err != nil {
+			break ActionParamCheck
+		}
+		if set != "" {
+			if _, parseErr := time.ParseInLocation(dateFlagLayout, set, time.Local); parseErr != nil {
+				err = fmt.Errorf(`unsupported value for flag "-%s": %s`, cliflags.DueSet, set)
+				break ActionParamCheck
+			}
+		}
+	case cliverbs.Similar:
+		flagSpecification = " [-" + cliflags.SimilarDistance + "=...]"
+		actionDescription += "List groups of visually near-identical images (PNG, JPEG, GIF) on record,\n" +
+			actionDescriptionIndent + "e.g. re-encoded or resized copies of a scan. The distance (0-64) of the\n" +
+			actionDescriptionIndent + "perceptual hashes tolerated within a group is configurable."
+		request.actionFlags[cliflags.SimilarDistance] = actionParams.Uint(cliflags.SimilarDistance, 5, "maximum number of differing bits of the perceptual hashes of two similar images")
+		actionParams.Parse(request.actionArgs)
+		request.actionArgs = actionParams.Args()
+		if actionParams.NArg() > 0 {
+			err = errors.New("command accepts no arguments, only flags")
+			break ActionParamCheck
+		}
+		if distance := *(request.actionFlags[cliflags.SimilarDistance].(*uint)); distance > document.PerceptualHashBits {
+			err = fmt.Errorf(`unsupported value for flag "-%s": %d`, cliflags.SimilarDistance, distance)
+			break ActionParamCheck
+		}
+	case cliverbs.Duplicates:
+		flagSpecification = " [-" + cliflags.DuplicatesPrefer + "=...] [-" + cliflags.DuplicatesRetire + " | -" + cliflags.DuplicatesDelete + "]"
+		actionDescription += "List all files and records (tracked, untracked, retired) which share the\n" +
+			actionDescriptionIndent + "same content, and the space wasted by the surplus copies. Optionally all\n" +
+			actionDescriptionIndent + "records but the preferred copy are retired, or their files are deleted.\n" +
+			actionDescriptionIndent + "The first matching path-priority rule decides which copy is preferred,\n" +
+			actionDescriptionIndent + "even over files on record. Among equally ranked copies files on record\n" +
+			actionDescriptionIndent + "are preferred over untracked ones, then the shortest path. If an\n" +
+			actionDescriptionIndent + "untracked copy is preferred a record is moved to it instead of retired."
+		request.actionFlags[cliflags.DuplicatesPrefer] = actionParams.String(cliflags.DuplicatesPrefer, "", "comma-separated globs matched against path, directories, or filename, highest priority first")
+		request.actionFlags[cliflags.DuplicatesRetire] = actionParams.Bool(cliflags.DuplicatesRetire, false, "retire all records of duplicate content but the preferred copy")
+		request.actionFlags[cliflags.DuplicatesDelete] = actionParams.Bool(cliflags.DuplicatesDelete, false, "delete all files of duplicate content but the preferred copy and retire their records")
+		actionParams.Parse(request.actionArgs)
+		request.actionArgs = actionParams.Args()
+		if actionParams.NArg() > 0 {
+			err = errors.New("command accepts no arguments, only flags")
+			break ActionParamCheck
+		}
+		if *(request.actionFlags[cliflags.DuplicatesRetire].(*bool)) && *(request.actionFlags[cliflags.DuplicatesDelete].(*bool)) {
+			err = fmt.Errorf(`flags "-%s" and "-%s" are mutually exclusive`, cliflags.DuplicatesRetire, cliflags.DuplicatesDelete)
+			break ActionParamCheck
+		}
+		for _, rule := range strings.Split(*(request.actionFlags[cliflags.DuplicatesPrefer].(*string)), ",") {
+			if _, matchErr := filepath.Match(rule, ""); matchErr != nil {
+				err = fmt.Errorf(`unsupported value for flag "-%s": %s`, cliflags.DuplicatesPrefer, rule)
+				break ActionParamCheck
+			}
+		}
 	case cliverbs.Tidy:
-		flagSpecification = " [-" + cliflags.TidyWithoutConfirmation + "] [-" + cliflags.TidyRemovingWaste + "]"
+		flagSpecification = " [-" + cliflags.TidyWithoutConfirmation + "] [-" + cliflags.TidyRemovingWaste + "] [-" + cliflags.TidyDeduplicating + "]"
 		actionDescription += "Interactively do the needful to get the library in sync with the filesystem.\n" +
 			actionDescriptionIndent + "By default only known records are considered and the filesystem is not touched."
 		request.actionFlags[cliflags.TidyWithoutConfirmation] = actionParams.Bool(cliflags.TidyWithoutConfirmation, false, "suppress prompts and choose defaults (\"yes to all\")")
 		request.actionFlags[cliflags.TidyRemovingWaste] = actionParams.Bool(cliflags.TidyRemovingWaste, false, "remove superfluous files with duplicate or obsolete content")
+		request.actionFlags[cliflags.TidyDeduplicating] = actionParams.Bool(cliflags.TidyDeduplicating, false, "replace files with duplicate content by hardlinks (or reflinks where only those are possible) instead of removing them")
 		actionParams.Parse(request.actionArgs)
 		request.actionArgs = actionParams.Args()
 		if actionParams.NArg() > 0 {
 			err = errors.New("command accepts no arguments, only flags")
 			break ActionParamCheck
 		}
+	case cliverbs.Rename:
+		flagSpecification = " [-" + cliflags.RenameIdPart + "=...] [-" + cliflags.RenameSubtree + "=...] [-" + cliflags.RenameReverse + "] [-" + cliflags.RenameDryRun + "]"
+		actionDescription += "Rename the files of active records to standardized filenames which contain\n" +
+			actionDescriptionIndent + "the ID (see \"" + cliverbs.Naming + "\"), or strip the ID again in reverse. The plan\n" +
+			actionDescriptionIndent + "is shown first and nothing is renamed if any conflict is found, e.g. a\n" +
+			actionDescriptionIndent + "file that is not in order or a name that is taken. If a rename fails all\n" +
+			actionDescriptionIndent + "previous ones are reverted."
+		request.actionFlags[cliflags.RenameIdPart] = actionParams.String(cliflags.RenameIdPart, "", "only rename records whose ID contains the given full/partial ID")
+		request.actionFlags[cliflags.RenameSubtree] = actionParams.String(cliflags.RenameSubtree, "", "only rename records located below the given directory")
+		request.actionFlags[cliflags.RenameReverse] = actionParams.Bool(cliflags.RenameReverse, false, "restore the original filenames, i.e. remove the ID")
+		request.actionFlags[cliflags.RenameDryRun] = actionParams.Bool(cliflags.RenameDryRun, false, "only show the plan, do not change any files")
+		actionParams.Parse(request.actionArgs)
+		request.actionArgs = actionParams.Args()
+		if actionParams.NArg() > 0 {
+			err = errors.New("command accepts no arguments, only flags")
+			break ActionParamCheck
+		}
+	case cliverbs.Restore:
+		flagSpecification = " -" + cliflags.RestoreFrom + "=... [-" + cliflags.RestoreModified + "] [-" + cliflags.RestoreDryRun + "]"
+		actionDescription += "Restore the files of records with status \"Missing\" from a backup location,\n" +
+			actionDescriptionIndent + "e.g. an old copy of the library with a different layout. All files below\n" +
+			actionDescriptionIndent + "the backup location are hashed and matching content is copied back to the\n" +
+			actionDescriptionIndent + "recorded path with the recorded modification time."
+		request.actionFlags[cliflags.RestoreFrom] = actionParams.String(cliflags.RestoreFrom, "", "backup location (directory) to search for matching content")
+		request.actionFlags[cliflags.RestoreModified] = actionParams.Bool(cliflags.RestoreModified, false, "also replace files whose content differs from the record")
+		request.actionFlags[cliflags.RestoreDryRun] = actionParams.Bool(cliflags.RestoreDryRun, false, "only show what would be restored, do not change any files")
+		actionParams.Parse(request.actionArgs)
+		request.actionArgs = actionParams.Args()
+		if actionParams.NArg() > 0 {
+			err = errors.New("command accepts no arguments, only flags")
+			break ActionParamCheck
+		}
+		if *(request.actionFlags[cliflags.RestoreFrom].(*string)) == "" {
+			err = fmt.Errorf(`flag "-%s" is required`, cliflags.RestoreFrom)
+			break ActionParamCheck
+		}
+	case cliverbs.Serve:
+		flagSpecification = " -" + cliflags.ServeSocket + "=... | -" + cliflags.ServePort + "=..."
+		actionDescription += "Serve the library as a JSON API via HTTP until interrupted (SIGINT/Ctrl+C).\n" +
+			actionDescriptionIndent + "Status, search, record lookup, add, update, retire, and tidy decisions are\n" +
+			actionDescriptionIndent + "available below /api/. All paths are relative to the library root.\n" +
+			actionDescriptionIndent + "Changes are kept in memory until POST /api/commit (or /api/discard).\n" +
+			actionDescriptionIndent + "POST requests must be sent as application/json. Requests addressed to a\n" +
+			actionDescriptionIndent + "host other than localhost or from a foreign origin are rejected.\n" +
+			actionDescriptionIndent + "A read-only web interface to browse the library, look up documents,\n" +
+			actionDescriptionIndent + "and download files is available at the server root (/)."
+		request.actionFlags[cliflags.ServeSocket] = actionParams.String(cliflags.ServeSocket, "", "path of Unix socket to listen on")
+		request.actionFlags[cliflags.ServePort] = actionParams.Uint(cliflags.ServePort, 0, "TCP port to listen on (bound to localhost only)")
+		actionParams.Parse(request.actionArgs)
+		request.actionArgs = actionParams.Args()
+		if actionParams.NArg() > 0 {
+			err = errors.New("command accepts no arguments, only flags")
+			break ActionParamCheck
+		}
+		socketGiven := *(request.actionFlags[cliflags.ServeSocket].(*string)) != ""
+		portGiven := *(request.actionFlags[cliflags.ServePort].(*uint)) != 0
+		if socketGiven == portGiven {
+			err = errors.New(`exactly one of the flags "-` + cliflags.ServeSocket + `" and "-` + cliflags.ServePort + `" must be given`)
+			break ActionParamCheck
+		}
 	default:
 		err = fmt.Errorf(`unknown action "%s"`, request.action)
 	}
@@ -305,17 +815,38 @@ func (rq *cliRequest) execute() (execErr error) {
 
 	if rq.action == cliverbs.Init {
 		database := *(rq.actionFlags[cliflags.InitDatabase].(*string))
+		mapping := doccurator.RootMapping{
+			RelativeToDatabase: *(rq.actionFlags[cliflags.InitRelativeRoot].(*bool)),
+			Host:               *(rq.actionFlags[cliflags.InitForHost].(*string)),
+		}
 		if *(rq.actionFlags[cliflags.InitUpdateRoot].(*bool)) {
-			return doccurator.Move(rq.actionArgs[0], database, config)
+			return doccurator.Move(rq.actionArgs[0], database, mapping, config)
 		} else {
 			if database == "" {
 				database = filepath.Join(rq.actionArgs[0], defaultDbFileName)
 			}
-			_, err := doccurator.New(rq.actionArgs[0], database, config)
+			_, err := doccurator.New(rq.actionArgs[0], database, mapping, config)
 			return err
 		}
 	}
 
+	switch {
+	case rq.action == cliverbs.Libraries:
+		return doccurator.PrintKnownLibraries(config)
+	case rq.action == cliverbs.Status && *(rq.actionFlags[cliflags.StatusOfAllLibraries].(*bool)):
+		return doccurator.PrintStatusOfKnownLibraries(config)
+	case rq.action == cliverbs.VerifyPack:
+		verification, err := doccurator.VerifyPack(rq.actionArgs[0])
+		if err != nil {
+			return err
+		}
+		doccurator.PrintPackVerification(verification, config)
+		if !verification.Complete() {
+			return errors.New("archive damaged")
+		}
+		return nil
+	}
+
 	workingDir, _ := os.Getwd()
 	api, err := doccurator.Open(workingDir, config)
 	if err != nil {
@@ -414,6 +945,85 @@ func (rq *cliRequest) execute() (execErr error) {
 			}
 		}
 		return api.PersistChanges()
+	case cliverbs.Root:
+		if *(rq.actionFlags[cliflags.RootRemove].(*bool)) {
+			if err := api.RemoveNamedRoot(rq.actionArgs[0]); err != nil {
+				return err
+			}
+			return api.PersistChanges()
+		}
+		if len(rq.actionArgs) == 2 {
+			if err := api.SetNamedRoot(rq.actionArgs[0], rq.actionArgs[1]); err != nil {
+				return err
+			}
+			return api.PersistChanges()
+		}
+		api.PrintRoots()
+		return nil
+	case cliverbs.RelocateDb:
+		return api.RelocateDatabase(rq.actionArgs[0])
+	case cliverbs.Identity:
+		name, nameGiven := rq.actionFlags[cliflags.IdentityName]
+		namespace, namespaceGiven := rq.actionFlags[cliflags.IdentityIdNamespace]
+		strategy, allocationGiven := rq.actionFlags[cliflags.IdentityIdAllocation]
+		if !nameGiven && !namespaceGiven && !allocationGiven {
+			api.PrintIdentity()
+			return nil
+		}
+		if nameGiven {
+			api.SetName(*(name.(*string)))
+		}
+		if namespaceGiven {
+			api.SetIdNamespace(uint32(*(namespace.(*uint))))
+		}
+		if allocationGiven {
+			idRange := ""
+			if given, rangeGiven := rq.actionFlags[cliflags.IdentityIdRange]; rangeGiven {
+				idRange = *(given.(*string))
+			}
+			allocation, _ := parseIdAllocation(*(strategy.(*string)), idRange) //validated during flag parsing
+			if err := api.SetIdAllocation(allocation); err != nil {
+				return err
+			}
+		}
+		return api.PersistChanges()
+	case cliverbs.Naming:
+		template := *(rq.actionFlags[cliflags.NamingTemplate].(*string))
+		migrate := *(rq.actionFlags[cliflags.NamingMigrate].(*bool))
+		attributeMode := library.IdAttributeMode(*(rq.actionFlags[cliflags.NamingIdAttribute].(*string)))
+		writeAttributes := *(rq.actionFlags[cliflags.NamingWriteIdAttributes].(*bool))
+		if template == "" && !migrate && attributeMode == "" && !writeAttributes {
+			api.PrintNameTemplates()
+			return nil
+		}
+		if template != "" {
+			if err := api.SetNameTemplate(template); err != nil {
+				return err
+			}
+		}
+		if attributeMode != "" {
+			if err := api.SetIdAttributeMode(attributeMode); err != nil {
+				return err
+			}
+		}
+		if migrate {
+			dryRun := *(rq.actionFlags[cliflags.NamingDryRun].(*bool))
+			if _, err := api.MigrateFilenames(dryRun); err != nil {
+				return err
+			}
+			if dryRun {
+				if !rq.quiet {
+					fmt.Fprintf(os.Stdout, "Dry run only, no files changed.\n")
+				}
+				return nil
+			}
+		}
+		if writeAttributes {
+			if _, err := api.WriteIdAttributes(); err != nil {
+				return err
+			}
+		}
+		return api.PersistChanges()
 	case cliverbs.Status:
 		api.PrintStatus(rq.actionArgs)
 		return nil
@@ -429,6 +1039,176 @@ func (rq *cliRequest) execute() (execErr error) {
 		}
 		fmt.Fprintf(os.Stdout, "\n\n%d %s found\n", matchCount, out.Plural(matchCount, "match", "matches"))
 		return nil
+	case cliverbs.Compare:
+		comparison, err := api.CompareWith(rq.actionArgs[0])
+		if err != nil {
+			return err
+		}
+		if *(rq.actionFlags[cliflags.CompareAsJson].(*bool)) {
+			return writeComparisonJson(os.Stdout, comparison)
+		}
+		api.PrintComparison(comparison)
+		return nil
+	case cliverbs.CheckCopy:
+		check, err := api.CheckCopy(rq.actionArgs[0], *(rq.actionFlags[cliflags.CheckCopyRoot].(*string)))
+		if err != nil {
+			return err
+		}
+		api.PrintCopyCheck(check)
+		if !check.Complete() {
+			return errors.New("copy incomplete or damaged")
+		}
+		return nil
+	case cliverbs.Export:
+		report, err := api.Export(rq.actionArgs[0], doccurator.ExportOptions{
+			RecordSelection: doccurator.RecordSelection{
+				IdPart:  *(rq.actionFlags[cliflags.ExportIdPart].(*string)),
+				Subtree: *(rq.actionFlags[cliflags.ExportSubtree].(*string)),
+			},
+			Flat:   *(rq.actionFlags[cliflags.ExportFlat].(*bool)),
+			Resume: *(rq.actionFlags[cliflags.ExportResume].(*bool)),
+		})
+		if err != nil {
+			return err
+		}
+		api.PrintExportReport(report)
+		if len(report.Failed) > 0 {
+			return errors.New("export incomplete")
+		}
+		return nil
+	case cliverbs.Pack:
+		selection := doccurator.RecordSelection{
+			IdPart:  *(rq.actionFlags[cliflags.PackIdPart].(*string)),
+			Subtree: *(rq.actionFlags[cliflags.PackSubtree].(*string)),
+		}
+		_, err := api.Pack(rq.actionArgs[0], selection, *(rq.actionFlags[cliflags.PackFlat].(*bool)))
+		return err
+	case cliverbs.Unpack:
+		directory := *(rq.actionFlags[cliflags.UnpackDirectory].(*string))
+		if directory == "" {
+			directory = workingDir
+		}
+		if _, err := api.Unpack(rq.actionArgs[0], directory); err != nil {
+			return err
+		}
+		return api.PersistChanges()
+	case cliverbs.Merge:
+		strategy := doccurator.MergeStrategy{
+			IdCollision:   mergeResolutions[*(rq.actionFlags[cliflags.MergeOnIdCollision].(*string))],
+			PathCollision: mergeResolutions[*(rq.actionFlags[cliflags.MergeOnPathCollision].(*string))],
+			Divergence:    mergeResolutions[*(rq.actionFlags[cliflags.MergeOnDivergence].(*string))],
+		}
+		changeCount, err := api.MergeFrom(rq.actionArgs[0], strategy)
+		if err != nil {
+			return err
+		}
+		if *(rq.actionFlags[cliflags.MergePreview].(*bool)) {
+			if !rq.quiet {
+				fmt.Fprintf(os.Stdout, "Preview only, library not modified.\n")
+			}
+			return nil
+		}
+		if changeCount == 0 {
+			return nil
+		}
+		return api.PersistChanges()
+	case cliverbs.Split:
+		database := *(rq.actionFlags[cliflags.SplitDatabase].(*string))
+		if database == "" {
+			database = filepath.Join(rq.actionArgs[0], defaultDbFileName)
+		}
+		if _, err := api.Split(rq.actionArgs[0], database); err != nil {
+			return err
+		}
+		return api.PersistChanges()
+	case cliverbs.Retention:
+		switch {
+		case *(rq.actionFlags[cliflags.RetentionRemove].(*bool)):
+			if err := api.RemoveRetention(rq.actionArgs[0]); err != nil {
+				return err
+			}
+			return api.PersistChanges()
+		case len(rq.actionArgs) == 1:
+			basis := library.RetentionBasis(*(rq.actionFlags[cliflags.RetentionBasis].(*string)))
+			if err := api.SetRetention(rq.actionArgs[0], *(rq.actionFlags[cliflags.RetentionYears].(*uint)), basis, *(rq.actionFlags[cliflags.RetentionFromEndOfYear].(*bool))); err != nil {
+				return err
+			}
+			return api.PersistChanges()
+		}
+		api.PrintRetentionRules()
+		return nil
+	case cliverbs.Expire:
+		asOf := time.Now()
+		if date := *(rq.actionFlags[cliflags.ExpireAsOf].(*string)); date != "" {
+			asOf, _ = time.ParseInLocation(dateFlagLayout, date, time.Local)
+		}
+		retire := *(rq.actionFlags[cliflags.ExpireRetiring].(*bool))
+		report, err := api.Expire(asOf, retire)
+		if err != nil {
+			return err
+		}
+		api.PrintExpiryReport(report)
+		if !retire || report.Retired == 0 {
+			return nil
+		}
+		return api.PersistChanges()
+	case cliverbs.Due:
+		set, clear := *(rq.actionFlags[cliflags.DueSet].(*string)), *(rq.actionFlags[cliflags.DueClear].(*bool))
+		if set != "" || clear {
+			numId, err, complete := ndocid.Decode(rq.actionArgs[0])
+			if err != nil {
+				return fmt.Errorf(`error in ID "%s" (%w)`, rq.actionArgs[0], err)
+			}
+			if !complete {
+				return fmt.Errorf(`incomplete ID "%s"`, rq.actionArgs[0])
+			}
+			var due time.Time
+			if set != "" {
+				due, _ = time.ParseInLocation(dateFlagLayout, set, time.Local)
+			}
+			if err := api.SetDueDate(document.Id(numId), due); err != nil {
+				return err
+			}
+			return api.PersistChanges()
+		}
+		window := time.Duration(*(rq.actionFlags[cliflags.DueWithinDays].(*uint))) * 24 * time.Hour
+		report, caughtUpCount := api.CollectDue(time.Now(), window)
+		api.PrintDueReport(report)
+		if caughtUpCount > 0 {
+			if err := api.PersistChanges(); err != nil {
+				return err
+			}
+		}
+		if len(report.Overdue) > 0 {
+			return fmt.Errorf("%d %s overdue", len(report.Overdue), out.Plural(report.Overdue, "document", "documents"))
+		}
+		return nil
+	case cliverbs.Similar:
+		groups, caughtUpCount := api.FindSimilar(int(*(rq.actionFlags[cliflags.SimilarDistance].(*uint))))
+		api.PrintSimilar(groups)
+		if caughtUpCount == 0 {
+			return nil
+		}
+		return api.PersistChanges()
+	case cliverbs.Duplicates:
+		var rules []string
+		if prefer := *(rq.actionFlags[cliflags.DuplicatesPrefer].(*string)); prefer != "" {
+			rules = strings.Split(prefer, ",")
+		}
+		report := api.CollectDuplicates(rules)
+		api.PrintDuplicates(report)
+		retire, deleteFiles := *(rq.actionFlags[cliflags.DuplicatesRetire].(*bool)), *(rq.actionFlags[cliflags.DuplicatesDelete].(*bool))
+		if !retire && !deleteFiles {
+			return nil
+		}
+		resolvedCount, err := api.ResolveDuplicates(report, deleteFiles)
+		if err != nil {
+			return err
+		}
+		if resolvedCount == 0 {
+			return nil
+		}
+		return api.PersistChanges()
 	case cliverbs.Tidy:
 		choice := PromptUser(!rq.plain)
 		if *(rq.actionFlags[cliflags.TidyWithoutConfirmation].(*bool)) {
@@ -437,18 +1217,61 @@ func (rq *cliRequest) execute() (execErr error) {
 			fmt.Fprint(os.Stdout, "(To abort and undo everything: SIGINT/Ctrl+C during prompts)\n")
 		}
 		removeWaste := *(rq.actionFlags[cliflags.TidyRemovingWaste].(*bool))
-		decisionsMade, foundWaste, cancelled := api.InteractiveTidy(choice, removeWaste)
+		deduplicate := *(rq.actionFlags[cliflags.TidyDeduplicating].(*bool))
+		decisionsMade, foundWaste, cancelled := api.InteractiveTidy(choice, removeWaste, deduplicate)
 		if cancelled {
 			return fmt.Errorf("operation aborted, undo requested")
 		}
 		if decisionsMade == 0 && !rq.quiet {
 			fmt.Fprint(os.Stdout, "Nothing to do!\n")
 			if foundWaste && !removeWaste {
-				fmt.Fprint(os.Stdout, "(Duplicate or obsolete files exist. Repeat with flag -"+cliflags.TidyRemovingWaste+" to remove.)\n")
+				fmt.Fprint(os.Stdout, "(Duplicate or obsolete files exist. Repeat with flag -"+cliflags.TidyRemovingWaste+" to remove or -"+cliflags.TidyDeduplicating+" to link duplicates.)\n")
 			}
 			fmt.Fprint(os.Stdout, "\n")
 		}
 		return api.PersistChanges()
+	case cliverbs.Rename:
+		selection := doccurator.RecordSelection{
+			IdPart:  *(rq.actionFlags[cliflags.RenameIdPart].(*string)),
+			Subtree: *(rq.actionFlags[cliflags.RenameSubtree].(*string)),
+		}
+		dryRun := *(rq.actionFlags[cliflags.RenameDryRun].(*bool))
+		plan, err := api.RenameRecords(selection, *(rq.actionFlags[cliflags.RenameReverse].(*bool)), dryRun)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			if !rq.quiet {
+				fmt.Fprintf(os.Stdout, "Dry run only, no files changed.\n")
+			}
+			return nil
+		}
+		if len(plan.Renames) == 0 {
+			return nil
+		}
+		return api.PersistChanges()
+	case cliverbs.Restore:
+		dryRun := *(rq.actionFlags[cliflags.RestoreDryRun].(*bool))
+		restoredCount, err := api.RestoreFrom(*(rq.actionFlags[cliflags.RestoreFrom].(*string)), *(rq.actionFlags[cliflags.RestoreModified].(*bool)), dryRun)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			if !rq.quiet {
+				fmt.Fprintf(os.Stdout, "Dry run only, no files changed.\n")
+			}
+			return nil
+		}
+		if restoredCount == 0 {
+			return nil
+		}
+		return api.PersistChanges()
+	case cliverbs.Serve:
+		listener, err := server.Listen(*(rq.actionFlags[cliflags.ServeSocket].(*string)), *(rq.actionFlags[cliflags.ServePort].(*uint)))
+		if err != nil {
+			return fmt.Errorf("cannot listen: %w", err)
+		}
+		return server.New(api).Run(listener)
 	default:
 		panic("bad action")
 	}
@@ -469,7 +1292,7 @@ func main() {
 		}
 		fmt.Fprintln(os.Stderr)
 		switch rq.action {
-		case cliverbs.Add, cliverbs.Update, cliverbs.Tidy, cliverbs.Retire, cliverbs.Forget:
+		case cliverbs.Add, cliverbs.Update, cliverbs.Tidy, cliverbs.Retire, cliverbs.Forget, cliverbs.Root, cliverbs.RelocateDb, cliverbs.Identity, cliverbs.Naming, cliverbs.Merge, cliverbs.Split, cliverbs.Rename, cliverbs.Restore, cliverbs.Unpack, cliverbs.Retention, cliverbs.Expire, cliverbs.Duplicates:
 			fmt.Fprintln(os.Stderr, "(library not modified because of errors)")
 		}
 		os.Exit(1)
@@ -8,6 +8,8 @@ const Help = `h`
 const All = `a`
 const InitDatabase = `database`
 const InitUpdateRoot = `update-root`
+const InitRelativeRoot = `relative-root`
+const InitForHost = `for-host`
 const AddAllUntracked = `all-untracked`
 const AddWithRename = `rename`
 const AddWithForce = `force`
@@ -21,3 +23,52 @@ const TreeWithOnlyDifferences = `diff`
 const TreeOfCurrentLocation = `here`
 const TidyWithoutConfirmation = `no-confirm`
 const TidyRemovingWaste = `remove-waste-files`
+const ServeSocket = `socket`
+const ServePort = `port`
+const RootRemove = `remove`
+const IdentityName = `name`
+const IdentityIdNamespace = `id-namespace`
+const IdentityIdAllocation = `id-allocation`
+const IdentityIdRange = `id-range`
+const StatusOfAllLibraries = `all-libraries`
+const CompareAsJson = `json`
+const MergePreview = `preview`
+const MergeOnIdCollision = `id-collision`
+const MergeOnPathCollision = `path-collision`
+const MergeOnDivergence = `divergence`
+const SplitDatabase = `database`
+const CheckCopyRoot = `root`
+const ExportIdPart = `id`
+const ExportSubtree = `subtree`
+const ExportFlat = `flat`
+const ExportResume = `resume`
+const RestoreFrom = `from`
+const RestoreModified = `modified`
+const RestoreDryRun = `dry-run`
+const PackIdPart = `id`
+const PackSubtree = `subtree`
+const PackFlat = `flat`
+const UnpackDirectory = `directory`
+const RetentionRemove = `remove`
+const RetentionYears = `years`
+const RetentionBasis = `basis`
+const RetentionFromEndOfYear = `end-of-year`
+const ExpireRetiring = `retire`
+const ExpireAsOf = `as-of`
+const DueWithinDays = `within`
+const DueSet = `set`
+const DueClear = `clear`
+const NamingTemplate = `template`
+const NamingMigrate = `migrate`
+const NamingDryRun = `dry-run`
+const RenameIdPart = `id`
+const RenameSubtree = `subtree`
+const RenameReverse = `reverse`
+const RenameDryRun = `dry-run`
+const NamingIdAttribute = `attribute`
+const NamingWriteIdAttributes = `write-attributes`
+const SimilarDistance = `distance`
+const DuplicatesPrefer = `prefer`
+const DuplicatesRetire = `retire`
+const DuplicatesDelete = `delete`
+const TidyDeduplicating = `dedupe`
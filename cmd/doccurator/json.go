@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/n2code/doccurator"
+	"github.com/n2code/doccurator/cmd/doccurator/recordjson"
+	"io"
+)
+
+//all paths are anchored, i.e. relative to the respective library root, and slash-separated
+
+type comparisonJson struct {
+	Other                  libraryJson         `json:"other"`
+	OnlyHere               []recordjson.Record `json:"onlyHere"`
+	OnlyThere              []recordjson.Record `json:"onlyThere"`
+	SameIdDifferentContent []recordPairJson    `json:"sameIdDifferentContent"`
+	SameContentDifferentId []recordPairJson    `json:"sameContentDifferentId"`
+	SameIdDifferentPath    []recordPairJson    `json:"sameIdDifferentPath"`
+	Identical              int                 `json:"identical"`
+}
+
+type libraryJson struct {
+	Database string `json:"database"`
+	Uuid     string `json:"uuid"`
+	Name     string `json:"name,omitempty"`
+}
+
+type recordPairJson struct {
+	Here  recordjson.Record `json:"here"`
+	There recordjson.Record `json:"there"`
+}
+
+func makeRecordPairsJson(pairs []doccurator.RecordPair) []recordPairJson {
+	converted := make([]recordPairJson, 0, len(pairs)) //empty instead of null
+	for _, pair := range pairs {
+		converted = append(converted, recordPairJson{Here: recordjson.Make(pair.Here), There: recordjson.Make(pair.There)})
+	}
+	return converted
+}
+
+func makeRecordsJson(records []doccurator.Record) []recordjson.Record {
+	converted := make([]recordjson.Record, 0, len(records)) //empty instead of null
+	for _, record := range records {
+		converted = append(converted, recordjson.Make(record))
+	}
+	return converted
+}
+
+func writeComparisonJson(w io.Writer, comparison doccurator.LibraryComparison) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(comparisonJson{
+		Other:                  libraryJson{Database: comparison.OtherDatabase, Uuid: comparison.OtherUuid, Name: comparison.OtherName},
+		OnlyHere:               makeRecordsJson(comparison.OnlyHere),
+		OnlyThere:              makeRecordsJson(comparison.OnlyThere),
+		SameIdDifferentContent: makeRecordPairsJson(comparison.SameIdDifferentContent),
+		SameContentDifferentId: makeRecordPairsJson(comparison.SameContentDifferentId),
+		SameIdDifferentPath:    makeRecordPairsJson(comparison.SameIdDifferentPath),
+		Identical:              comparison.Identical,
+	})
+}
@@ -0,0 +1,35 @@
+package recordjson
+
+import (
+	"encoding/hex"
+	"github.com/n2code/doccurator"
+	"path/filepath"
+	"time"
+)
+
+// Record is the JSON representation of a record shared by the web API and the JSON output of the CLI.
+// The path is anchored, i.e. relative to the respective library root, and slash-separated.
+type Record struct {
+	Id       string    `json:"id"`
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Sha256   string    `json:"sha256"`
+	Recorded time.Time `json:"recorded"`
+	Changed  time.Time `json:"changed"`
+	Modified time.Time `json:"modified"`
+	Retired  bool      `json:"retired"`
+}
+
+// Make converts a record into its JSON representation
+func Make(record doccurator.Record) Record {
+	return Record{
+		Id:       record.Id.String(),
+		Path:     filepath.ToSlash(record.AnchoredPath),
+		Size:     record.Size,
+		Sha256:   hex.EncodeToString(record.Sha256[:]),
+		Recorded: record.Recorded,
+		Changed:  record.Changed,
+		Modified: record.Modified,
+		Retired:  record.Retired,
+	}
+}
@@ -0,0 +1,370 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/n2code/doccurator"
+	"github.com/n2code/doccurator/cmd/doccurator/recordjson"
+	"github.com/n2code/doccurator/internal/document"
+	"github.com/n2code/ndocid"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+const apiPrefix = "/api/"
+
+//all paths exchanged with clients are anchored, i.e. relative to the library root, and slash-separated
+
+type checkResultJson struct {
+	Path              string         `json:"path"`
+	Status            string         `json:"status"`
+	Symbol            string         `json:"symbol"`
+	Change            bool           `json:"change"`
+	Outdated          bool           `json:"outdated"`
+	Waste             bool           `json:"waste"`
+	Reference         *referenceJson `json:"reference,omitempty"`
+	Error             string         `json:"error,omitempty"`
+	AttributeConflict string         `json:"attributeConflict,omitempty"`
+}
+
+type referenceJson struct {
+	Id   string `json:"id"`
+	Path string `json:"path"`
+}
+
+type searchResultJson struct {
+	Id     string `json:"id"`
+	Path   string `json:"path"`
+	Status string `json:"status"`
+}
+
+type pathOutcomeJson struct {
+	Path  string `json:"path"`
+	Done  bool   `json:"done"`
+	Error string `json:"error,omitempty"`
+}
+
+type pathsRequestJson struct {
+	Paths []string `json:"paths"`
+}
+
+type addRequestJson struct {
+	Paths  []string `json:"paths"`
+	Id     string   `json:"id"`     //only allowed for a single path
+	AutoId bool     `json:"autoId"` //generate IDs for paths without standardized filename
+	Force  bool     `json:"force"`  //allow adding duplicate, moved, and obsolete files
+	Empty  bool     `json:"empty"`  //allow adding empty files
+}
+
+type tidyRequestJson struct {
+	Decisions []struct {
+		Path   string `json:"path"`
+		Accept bool   `json:"accept"`
+	} `json:"decisions"`
+}
+
+type sessionJson struct {
+	Uncommitted bool `json:"uncommitted"`
+}
+
+type errorJson struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) registerApiRoutes() {
+	s.routes.HandleFunc(apiPrefix+"status", s.serialized(http.MethodGet, s.handleStatus))
+	s.routes.HandleFunc(apiPrefix+"search", s.serialized(http.MethodGet, s.handleSearch))
+	s.routes.HandleFunc(apiPrefix+"records/", s.serialized(http.MethodGet, s.handleRecord))
+	s.routes.HandleFunc(apiPrefix+"add", s.serialized(http.MethodPost, s.handleAdd))
+	s.routes.HandleFunc(apiPrefix+"update", s.serialized(http.MethodPost, s.handleUpdate))
+	s.routes.HandleFunc(apiPrefix+"retire", s.serialized(http.MethodPost, s.handleRetire))
+	s.routes.HandleFunc(apiPrefix+"tidy", s.serialized("", s.handleTidy))
+	s.routes.HandleFunc(apiPrefix+"session", s.serialized(http.MethodGet, s.handleSession))
+	s.routes.HandleFunc(apiPrefix+"commit", s.serialized(http.MethodPost, s.handleCommit))
+	s.routes.HandleFunc(apiPrefix+"discard", s.serialized(http.MethodPost, s.handleDiscard))
+}
+
+// serialized wraps a handler such that it has exclusive access to the doccurator handle.
+// If a method is given all requests using a different method are rejected.
+// POST requests must declare JSON content (even without a body) because browsers do not send such requests to other
+// sites without asking for permission first, which the server never grants.
+func (s *Server) serialized(method string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if method != "" && r.Method != method {
+			w.Header().Set("Allow", method)
+			respondWithError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		if r.Method == http.MethodPost {
+			if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+				respondWithError(w, http.StatusUnsupportedMediaType, errors.New("content type application/json required"))
+				return
+			}
+		}
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		handler(w, r)
+	}
+}
+
+func respond(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	_ = encoder.Encode(body) //client may have gone away, nothing to be done about it
+}
+
+func respondWithError(w http.ResponseWriter, status int, err error) {
+	respond(w, status, errorJson{Error: err.Error()})
+}
+
+func decodeBody(r *http.Request, target interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(target); err != nil {
+		return fmt.Errorf("bad request body: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) absolutize(anchored string) string {
+	return s.api.Absolutize(filepath.FromSlash(anchored))
+}
+
+func parseId(text string) (document.Id, error) {
+	numId, err, complete := ndocid.Decode(text)
+	if err != nil {
+		return document.MissingId, fmt.Errorf(`error in ID "%s" (%w)`, text, err)
+	}
+	if !complete {
+		return document.MissingId, fmt.Errorf(`incomplete ID "%s"`, text)
+	}
+	return document.Id(numId), nil
+}
+
+func makeCheckResultJson(result doccurator.CheckResult) checkResultJson {
+	converted := checkResultJson{
+		Path:     filepath.ToSlash(result.AnchoredPath),
+		Status:   result.Status.String(),
+		Symbol:   string(rune(result.Status)),
+		Change:   result.IsChange,
+		Outdated: result.IsOutdated,
+		Waste:    result.IsWaste,
+	}
+	if result.Reference != document.MissingId {
+		converted.Reference = &referenceJson{Id: result.Reference.String(), Path: filepath.ToSlash(result.ReferencePath)}
+	}
+	if result.Problem != nil {
+		converted.Error = result.Problem.Error()
+	}
+	if result.AttributeConflict != nil {
+		converted.AttributeConflict = result.AttributeConflict.Error()
+	}
+	return converted
+}
+
+// handleStatus reports the status of the paths given as query parameters or, if none are given, of all changed paths in the library.
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	var paths []string
+	for _, anchored := range r.URL.Query()["path"] {
+		paths = append(paths, s.absolutize(anchored))
+	}
+	results := make([]checkResultJson, 0)
+	for _, result := range s.api.GetStatus(paths) {
+		results = append(results, makeCheckResultJson(result))
+	}
+	respond(w, http.StatusOK, results)
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	part := r.URL.Query().Get("id")
+	if part == "" {
+		respondWithError(w, http.StatusBadRequest, errors.New(`query parameter "id" missing`))
+		return
+	}
+	results := make([]searchResultJson, 0)
+	for _, match := range s.api.SearchByIdPart(part) {
+		record, _ := s.api.GetRecord(match.Id) //exists because it was just found
+		results = append(results, searchResultJson{Id: match.Id.String(), Path: filepath.ToSlash(record.AnchoredPath), Status: match.StatusText})
+	}
+	respond(w, http.StatusOK, results)
+}
+
+func (s *Server) handleRecord(w http.ResponseWriter, r *http.Request) {
+	id, err := parseId(strings.TrimPrefix(r.URL.Path, apiPrefix+"records/"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	record, exists := s.api.GetRecord(id)
+	if !exists {
+		respondWithError(w, http.StatusNotFound, fmt.Errorf("document with ID %s unknown", id))
+		return
+	}
+	respond(w, http.StatusOK, recordjson.Make(record))
+}
+
+// handleAdd creates documents for all given paths. If any path cannot be added the library remains unchanged.
+func (s *Server) handleAdd(w http.ResponseWriter, r *http.Request) {
+	var request addRequestJson
+	if err := decodeBody(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(request.Paths) == 0 {
+		respondWithError(w, http.StatusBadRequest, errors.New("no paths given"))
+		return
+	}
+	var absolutePaths []string
+	for _, anchored := range request.Paths {
+		absolutePaths = append(absolutePaths, s.absolutize(anchored))
+	}
+
+	var added []document.Id
+	if request.Id != "" {
+		if len(request.Paths) != 1 || request.AutoId {
+			respondWithError(w, http.StatusBadRequest, errors.New("explicit ID requires exactly one path and excludes automatic IDs"))
+			return
+		}
+		id, err := parseId(request.Id)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := s.api.AddWithId(id, absolutePaths[0], request.Force, request.Empty); err != nil {
+			respondWithError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+		added = append(added, id)
+	} else {
+		var err error
+		if added, err = s.api.AddMultiple(absolutePaths, request.Force, request.Empty, request.AutoId, true); err != nil {
+			respondWithError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+	}
+
+	records := make([]recordjson.Record, 0, len(added))
+	for _, id := range added {
+		record, _ := s.api.GetRecord(id) //exists because it was just added
+		records = append(records, recordjson.Make(record))
+	}
+	s.uncommitted = true
+	respond(w, http.StatusOK, records)
+}
+
+func (s *Server) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	s.processPaths(w, r, s.api.UpdateByPath)
+}
+
+func (s *Server) handleRetire(w http.ResponseWriter, r *http.Request) {
+	s.processPaths(w, r, s.api.RetireByPath)
+}
+
+// processPaths applies the given operation to each path in the request body.
+// Failures do not stop processing, changes of successful operations remain uncommitted regardless.
+func (s *Server) processPaths(w http.ResponseWriter, r *http.Request, operation func(path string) error) {
+	var request pathsRequestJson
+	if err := decodeBody(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(request.Paths) == 0 {
+		respondWithError(w, http.StatusBadRequest, errors.New("no paths given"))
+		return
+	}
+	outcomes := make([]pathOutcomeJson, 0, len(request.Paths))
+	failed := false
+	for _, anchored := range request.Paths {
+		outcome := pathOutcomeJson{Path: anchored, Done: true}
+		if err := operation(s.absolutize(anchored)); err != nil {
+			outcome.Done, outcome.Error = false, err.Error()
+			failed = true
+		} else {
+			s.uncommitted = true
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	status := http.StatusOK
+	if failed {
+		status = http.StatusUnprocessableEntity
+	}
+	respond(w, status, outcomes)
+}
+
+// handleTidy lists all paths that tidying would ask about (GET) or applies the decisions made about them (POST):
+// Accepting an outdated record updates it, accepting a file with duplicate or obsolete content (waste) deletes it.
+func (s *Server) handleTidy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		candidates := make([]checkResultJson, 0)
+		for _, result := range s.api.GetStatus(nil) {
+			if result.IsOutdated || result.IsWaste {
+				candidates = append(candidates, makeCheckResultJson(result))
+			}
+		}
+		respond(w, http.StatusOK, candidates)
+	case http.MethodPost:
+		var request tidyRequestJson
+		if err := decodeBody(r, &request); err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+		outcomes := make([]pathOutcomeJson, 0, len(request.Decisions))
+		failed := false
+		for _, decision := range request.Decisions {
+			outcome := pathOutcomeJson{Path: decision.Path}
+			if decision.Accept {
+				absolute := s.absolutize(decision.Path)
+				var err error
+				if check := s.api.GetStatus([]string{absolute}); len(check) == 1 && check[0].IsWaste {
+					err = s.api.RemoveWasteByPath(absolute)
+				} else {
+					err = s.api.UpdateByPath(absolute)
+				}
+				if err != nil {
+					outcome.Error = err.Error()
+					failed = true
+				} else {
+					outcome.Done = true
+					s.uncommitted = true
+				}
+			}
+			outcomes = append(outcomes, outcome)
+		}
+		status := http.StatusOK
+		if failed {
+			status = http.StatusUnprocessableEntity
+		}
+		respond(w, status, outcomes)
+	default:
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		respondWithError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	}
+}
+
+func (s *Server) handleSession(w http.ResponseWriter, _ *http.Request) {
+	respond(w, http.StatusOK, sessionJson{Uncommitted: s.uncommitted})
+}
+
+func (s *Server) handleCommit(w http.ResponseWriter, _ *http.Request) {
+	if err := s.api.PersistChanges(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.uncommitted = false
+	respond(w, http.StatusOK, sessionJson{Uncommitted: s.uncommitted})
+}
+
+func (s *Server) handleDiscard(w http.ResponseWriter, _ *http.Request) {
+	if err := s.api.DiscardChanges(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.uncommitted = false
+	respond(w, http.StatusOK, sessionJson{Uncommitted: s.uncommitted})
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/n2code/doccurator"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Server exposes a doccurator handle as a JSON API via HTTP along with a read-only web interface for browsing the library.
+// All requests are processed strictly one after another because the handle must not be used concurrently.
+// Library changes are accumulated in memory until they are explicitly committed (or discarded) by the client.
+type Server struct {
+	api         doccurator.Doccurator
+	lock        sync.Mutex //guards the handle and the uncommitted flag
+	uncommitted bool       //set if changes were made since the last commit or discard
+	routes      *http.ServeMux
+}
+
+const shutdownGracePeriod = 5 * time.Second
+
+// New creates a server for the given doccurator handle. The handle must not be used elsewhere while the server is running.
+func New(api doccurator.Doccurator) *Server {
+	s := &Server{api: api, routes: http.NewServeMux()}
+	s.registerApiRoutes()
+	s.registerWebRoutes()
+	return s
+}
+
+// Listen opens a Unix socket at the given path if it is not empty, otherwise a TCP port bound to the loopback interface.
+func Listen(socketPath string, port uint) (net.Listener, error) {
+	if socketPath != "" {
+		return net.Listen("unix", socketPath)
+	}
+	return net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
+}
+
+// ServeHTTP rejects requests which may originate from other websites before dispatching them: Requests via TCP must be
+// addressed to a loopback host (thwarting DNS rebinding) and requests issued by a browser must come from the server itself.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := checkRequestSource(r); err != nil {
+		respondWithError(w, http.StatusForbidden, err)
+		return
+	}
+	s.routes.ServeHTTP(w, r)
+}
+
+func checkRequestSource(r *http.Request) error {
+	local, _ := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	viaSocket := local != nil && local.Network() == "unix" //not reachable by browsers, the host is arbitrary
+	if !viaSocket && !isLoopbackHost(r.Host) {
+		return fmt.Errorf("host %q not allowed", r.Host)
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if parsed, err := url.Parse(origin); err != nil || parsed.Host != r.Host {
+			return fmt.Errorf("origin %q not allowed", origin)
+		}
+	}
+	return nil
+}
+
+func isLoopbackHost(hostWithPort string) bool {
+	host, _, err := net.SplitHostPort(hostWithPort)
+	if err != nil {
+		host = hostWithPort //no port given
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+// Run serves requests on the given listener until SIGINT or SIGTERM is received.
+// Changes not committed by then are discarded.
+func (s *Server) Run(listener net.Listener) error {
+	httpServer := &http.Server{Handler: s}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- httpServer.Serve(listener)
+	}()
+	fmt.Fprintf(os.Stdout, "Serving library on %s://%s (stop with SIGINT/Ctrl+C)\n", listener.Addr().Network(), listener.Addr())
+
+	var serveErr error
+	select {
+	case serveErr = <-served:
+	case <-interrupt:
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownGracePeriod)
+		defer cancel()
+		serveErr = httpServer.Shutdown(ctx)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.uncommitted {
+		s.api.RollbackAllFilesystemChanges()
+		fmt.Fprint(os.Stdout, "Uncommitted library changes discarded.\n")
+	}
+
+	if errors.Is(serveErr, http.ErrServerClosed) {
+		return nil
+	}
+	return serveErr
+}
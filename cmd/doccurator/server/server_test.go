@@ -0,0 +1,279 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/n2code/doccurator"
+	"github.com/n2code/doccurator/cmd/doccurator/recordjson"
+	"github.com/n2code/doccurator/internal/library"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testConfig keeps the test libraries out of the registry of known libraries of the user
+func testConfig(t *testing.T) doccurator.HandleConfig {
+	return doccurator.HandleConfig{Verbosity: doccurator.QuietMode, RegistryFile: filepath.Join(t.TempDir(), "libraries.json")}
+}
+
+func setupServedLibrary(t *testing.T) (root string, database string, served *httptest.Server) {
+	root = t.TempDir()
+	database = filepath.Join(t.TempDir(), "test.db")
+	api, err := doccurator.New(root, database, doccurator.RootMapping{}, testConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	served = httptest.NewServer(New(api))
+	t.Cleanup(served.Close)
+	return
+}
+
+func call(t *testing.T, served *httptest.Server, method string, endpoint string, body string, expectedStatus int, result interface{}) {
+	request, _ := http.NewRequest(method, served.URL+apiPrefix+endpoint, strings.NewReader(body))
+	if method == http.MethodPost {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	response, err := served.Client().Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != expectedStatus {
+		t.Fatalf("%s %s: expected status %d but got %d", method, endpoint, expectedStatus, response.StatusCode)
+	}
+	if result != nil {
+		if err := json.NewDecoder(response.Body).Decode(result); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCommitSemantics(t *testing.T) {
+	//GIVEN
+	root, database, served := setupServedLibrary(t)
+	os.WriteFile(filepath.Join(root, "first"), []byte("1"), fs.ModePerm)
+	os.WriteFile(filepath.Join(root, "second"), []byte("2"), fs.ModePerm)
+	var session sessionJson
+	var added []recordjson.Record
+
+	t.Run("AddIsNotPersisted", func(Test *testing.T) {
+		//WHEN
+		call(Test, served, http.MethodPost, "add", `{"paths":["first"],"autoId":true}`, http.StatusOK, &added)
+		call(Test, served, http.MethodGet, "session", "", http.StatusOK, &session)
+		//THEN
+		if len(added) != 1 || added[0].Path != "first" {
+			Test.Fatal("added record not reported")
+		}
+		if !session.Uncommitted {
+			Test.Fatal("change not reported as uncommitted")
+		}
+		reopened, _ := doccurator.Open(root, testConfig(t))
+		id, _ := parseId(added[0].Id)
+		if _, exists := reopened.GetRecord(id); exists {
+			Test.Fatal("uncommitted change persisted")
+		}
+	})
+
+	t.Run("CommitPersists", func(Test *testing.T) {
+		//WHEN
+		call(Test, served, http.MethodPost, "commit", "", http.StatusOK, &session)
+		//THEN
+		if session.Uncommitted {
+			Test.Fatal("commit did not clear uncommitted flag")
+		}
+		if _, err := os.Stat(database); err != nil {
+			Test.Fatal("database missing")
+		}
+		reopened, _ := doccurator.Open(root, testConfig(t))
+		id, _ := parseId(added[0].Id)
+		if _, exists := reopened.GetRecord(id); !exists {
+			Test.Fatal("committed change not persisted")
+		}
+	})
+
+	t.Run("DiscardReverts", func(Test *testing.T) {
+		//WHEN
+		call(Test, served, http.MethodPost, "add", `{"paths":["second"],"autoId":true}`, http.StatusOK, nil)
+		call(Test, served, http.MethodPost, "discard", "", http.StatusOK, &session)
+		var results []checkResultJson
+		call(Test, served, http.MethodGet, "status?path=second", "", http.StatusOK, &results)
+		//THEN
+		if len(results) != 1 || results[0].Status != "Untracked" {
+			Test.Fatal("discarded addition still on record")
+		}
+	})
+
+	t.Run("DiscardOfUnreadableDatabaseFails", func(Test *testing.T) {
+		//GIVEN
+		call(Test, served, http.MethodPost, "add", `{"paths":["second"],"autoId":true}`, http.StatusOK, nil)
+		intact, _ := os.ReadFile(database)
+		os.WriteFile(database, []byte("garbage"), fs.ModePerm)
+		defer os.WriteFile(database, intact, fs.ModePerm)
+		//WHEN
+		call(Test, served, http.MethodPost, "discard", "", http.StatusInternalServerError, nil)
+		call(Test, served, http.MethodGet, "session", "", http.StatusOK, &session)
+		//THEN
+		if !session.Uncommitted {
+			Test.Fatal("failed discard reported as success")
+		}
+	})
+}
+
+func TestRequestSourceChecks(t *testing.T) {
+	//GIVEN
+	root, _, served := setupServedLibrary(t)
+	tests := []struct {
+		name           string
+		host           string //empty to keep the address of the server
+		origin         string
+		contentType    string
+		expectedStatus int
+	}{
+		{name: "PlainRequest", contentType: "application/json", expectedStatus: http.StatusOK},
+		{name: "JsonWithCharset", contentType: "application/json; charset=utf-8", expectedStatus: http.StatusOK},
+		{name: "SameOrigin", origin: served.URL, contentType: "application/json", expectedStatus: http.StatusOK},
+		{name: "LocalhostName", host: "localhost:" + served.URL[strings.LastIndex(served.URL, ":")+1:], contentType: "application/json", expectedStatus: http.StatusOK},
+		{name: "ForeignHost", host: "rebound.example:80", contentType: "application/json", expectedStatus: http.StatusForbidden},
+		{name: "ForeignOrigin", origin: "http://attacker.example", contentType: "application/json", expectedStatus: http.StatusForbidden},
+		{name: "OpaqueOrigin", origin: "null", contentType: "application/json", expectedStatus: http.StatusForbidden},
+		{name: "PlainTextBody", contentType: "text/plain", expectedStatus: http.StatusUnsupportedMediaType},
+		{name: "MissingContentType", expectedStatus: http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(Test *testing.T) {
+			request, _ := http.NewRequest(http.MethodPost, served.URL+apiPrefix+"discard", nil)
+			if tt.host != "" {
+				request.Host = tt.host
+			}
+			if tt.origin != "" {
+				request.Header.Set("Origin", tt.origin)
+			}
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+
+			//WHEN
+			response, err := served.Client().Do(request)
+
+			//THEN
+			if err != nil {
+				Test.Fatal(err)
+			}
+			response.Body.Close()
+			if response.StatusCode != tt.expectedStatus {
+				Test.Errorf("expected status %d but got %d", tt.expectedStatus, response.StatusCode)
+			}
+		})
+	}
+
+	t.Run("AnyHostViaSocket", func(Test *testing.T) {
+		//GIVEN
+		api, _ := doccurator.Open(root, testConfig(Test))
+		socket := filepath.Join(Test.TempDir(), "api.sock")
+		listener, err := Listen(socket, 0)
+		if err != nil {
+			Test.Skip("Unix sockets unsupported:", err)
+		}
+		viaSocket := httptest.NewUnstartedServer(New(api))
+		viaSocket.Listener = listener
+		viaSocket.Start()
+		defer viaSocket.Close()
+		client := &http.Client{Transport: &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		}}}
+
+		//WHEN
+		response, err := client.Get("http://doccurator" + apiPrefix + "session")
+
+		//THEN
+		if err != nil {
+			Test.Fatal(err)
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			Test.Errorf("request via socket rejected with status %d", response.StatusCode)
+		}
+	})
+}
+
+func TestBrowsingAndDownload(t *testing.T) {
+	//GIVEN
+	root, _, served := setupServedLibrary(t)
+	os.Mkdir(filepath.Join(root, "sub"), fs.ModePerm)
+	os.WriteFile(filepath.Join(root, "sub", "file"), []byte("content"), fs.ModePerm)
+	var added []recordjson.Record
+	call(t, served, http.MethodPost, "add", `{"paths":["sub/file"],"autoId":true}`, http.StatusOK, &added)
+	get := func(Test *testing.T, path string, expectedStatus int) string {
+		response, err := served.Client().Get(served.URL + path)
+		if err != nil {
+			Test.Fatal(err)
+		}
+		defer response.Body.Close()
+		if response.StatusCode != expectedStatus {
+			Test.Fatalf("GET %s: expected status %d but got %d", path, expectedStatus, response.StatusCode)
+		}
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+
+	t.Run("TreeLinksDocument", func(Test *testing.T) {
+		//WHEN
+		page := get(Test, "/", http.StatusOK)
+		//THEN
+		if !strings.Contains(page, "sub/") || !strings.Contains(page, documentsPrefix+added[0].Id) {
+			Test.Fatal("tree misses directory or document link")
+		}
+	})
+
+	t.Run("DownloadMatchingFile", func(Test *testing.T) {
+		//WHEN
+		content := get(Test, documentsPrefix+added[0].Id+downloadSuffix, http.StatusOK)
+		//THEN
+		if content != "content" {
+			Test.Fatal("downloaded content differs")
+		}
+	})
+
+	//GIVEN
+	os.WriteFile(filepath.Join(root, "sub", "file"), []byte("modified"), fs.ModePerm)
+
+	t.Run("RefuseDownloadOfModifiedFile", func(Test *testing.T) {
+		//WHEN+THEN
+		get(Test, documentsPrefix+added[0].Id+downloadSuffix, http.StatusConflict)
+	})
+}
+
+func TestStatusReportsAttributeConflict(t *testing.T) {
+	//GIVEN
+	root := t.TempDir()
+	api, err := doccurator.New(root, filepath.Join(t.TempDir(), "test.db"), doccurator.RootMapping{}, testConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(root, "file")
+	os.WriteFile(path, []byte("content"), fs.ModePerm)
+	api.SetIdAttributeMode(library.IdAttributeOnly)
+	if err := api.AddWithId(1, path, false, false); err != nil {
+		t.Skipf("extended attributes unsupported: %s", err)
+	}
+	api.ForgetById(1, true)
+	api.SetIdAttributeMode(library.NoIdAttribute)
+	api.AddWithId(2, path, false, false) //the attribute still names the forgotten record
+	served := httptest.NewServer(New(api))
+	t.Cleanup(served.Close)
+	var results []checkResultJson
+
+	//WHEN
+	call(t, served, http.MethodGet, "status", "", http.StatusOK, &results)
+
+	//THEN
+	if len(results) != 1 || results[0].Path != "file" || results[0].AttributeConflict == "" {
+		t.Errorf("conflicting ID attribute of unchanged file not reported: %+v", results)
+	}
+}
@@ -0,0 +1,249 @@
+package server
+
+import (
+	"bytes"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"github.com/n2code/doccurator"
+	"github.com/n2code/doccurator/internal/document"
+	"github.com/n2code/doccurator/internal/library"
+	"github.com/n2code/doccurator/internal/output"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+//go:embed templates
+var templateFiles embed.FS
+
+var pages = template.Must(template.New("").Funcs(template.FuncMap{
+	"filesize": output.Filesize,
+	"datetime": func(t time.Time) string { return t.Local().Format(time.RFC1123) },
+	"symbol": func(status library.PathStatus) string {
+		if status == library.Tracked {
+			return "" //to reduce clutter for the majority of entries
+		}
+		return fmt.Sprintf("[%c]", status)
+	},
+}).ParseFS(templateFiles, "templates/*.html"))
+
+const documentsPrefix = "/documents/"
+const downloadSuffix = "/download"
+
+// treeNode represents a directory (if it has no result) or a file in the browsable library tree
+type treeNode struct {
+	Name      string
+	Children  []*treeNode
+	Result    *doccurator.CheckResult
+	MovedAway bool   //set for missing records that have been moved to a path shown elsewhere
+	Relation  string //describes how the referenced record relates to a file that is not on record itself
+}
+
+type layoutData struct {
+	Title string
+	Root  string
+	Query string
+}
+
+type treePage struct {
+	Layout         layoutData
+	Tree           *treeNode
+	OnlyDifference bool
+	ErrorCount     int
+}
+
+type documentPage struct {
+	Layout       layoutData
+	Record       doccurator.Record
+	Sha256       string
+	Status       *doccurator.CheckResult
+	Downloadable bool
+}
+
+type searchPage struct {
+	Layout  layoutData
+	Matches []doccurator.SearchResult
+	Paths   map[document.Id]string
+}
+
+func (s *Server) registerWebRoutes() {
+	s.routes.HandleFunc("/", s.serialized(http.MethodGet, s.handleTreePage))
+	s.routes.HandleFunc(documentsPrefix, s.serialized(http.MethodGet, s.handleDocumentPage))
+	s.routes.HandleFunc("/search", s.serialized(http.MethodGet, s.handleSearchPage))
+}
+
+func (s *Server) layout(title string, query string) layoutData {
+	return layoutData{Title: title, Root: s.api.Absolutize(""), Query: query}
+}
+
+func renderPage(w http.ResponseWriter, name string, data interface{}) {
+	var page bytes.Buffer //to avoid sending partial output on failure
+	if err := pages.ExecuteTemplate(&page, name, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = page.WriteTo(w) //client may have gone away, nothing to be done about it
+}
+
+// buildTree arranges the given results (sorted by path) in a hierarchy of directories.
+// Additional library roots appear as top-level directories named after their root qualifier (e.g. "archive//").
+// Directories are listed before files, both in alphabetical order.
+func buildTree(results []doccurator.CheckResult) *treeNode {
+	root := &treeNode{}
+	dirs := map[string]*treeNode{"": root}
+	var getDir func(rootName string, path string) *treeNode
+	getDir = func(rootName string, path string) *treeNode {
+		if path == "." {
+			path = ""
+		}
+		anchored := document.QualifyAnchoredPath(rootName, path)
+		dir, exists := dirs[anchored]
+		if !exists {
+			if path == "" { //top-level directory of an additional root
+				dir = &treeNode{Name: anchored}
+				root.Children = append(root.Children, dir)
+			} else {
+				parent := getDir(rootName, filepath.Dir(path))
+				dir = &treeNode{Name: filepath.Base(path)}
+				parent.Children = append(parent.Children, dir)
+			}
+			dirs[anchored] = dir
+		}
+		return dir
+	}
+
+	movedIds := make(map[document.Id]bool)
+	for _, result := range results {
+		if result.Status == library.Moved || result.Status == library.MovedModified {
+			movedIds[result.Reference] = true
+		}
+	}
+	for i := range results {
+		result := &results[i]
+		rootName, relative := document.SplitAnchoredPath(result.AnchoredPath)
+		dir := getDir(rootName, filepath.Dir(relative))
+		node := &treeNode{
+			Name:      filepath.Base(relative),
+			Result:    result,
+			MovedAway: result.Status == library.Missing && movedIds[result.Reference],
+		}
+		switch result.Status {
+		case library.Moved, library.MovedModified:
+			node.Relation = "moved from"
+		case library.Duplicate:
+			node.Relation = "identical to"
+		case library.Linked:
+			node.Relation = "same file as"
+		case library.Obsolete:
+			node.Relation = "obsoleted as"
+		}
+		dir.Children = append(dir.Children, node)
+	}
+
+	for _, dir := range dirs {
+		children := dir.Children
+		sort.SliceStable(children, func(i, j int) bool {
+			if isDir, otherIsDir := children[i].Result == nil, children[j].Result == nil; isDir != otherIsDir {
+				return isDir
+			}
+			return children[i].Name < children[j].Name
+		})
+	}
+	return root
+}
+
+func (s *Server) handleTreePage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	onlyDifference := r.URL.Query().Get("diff") != ""
+	results := s.api.GetAllPaths(onlyDifference)
+	errorCount := 0
+	for _, result := range results {
+		if result.Problem != nil {
+			errorCount++
+		}
+	}
+	renderPage(w, "tree.html", treePage{
+		Layout:         s.layout("Library", ""),
+		Tree:           buildTree(results),
+		OnlyDifference: onlyDifference,
+		ErrorCount:     errorCount,
+	})
+}
+
+func (s *Server) handleDocumentPage(w http.ResponseWriter, r *http.Request) {
+	idText := strings.TrimPrefix(r.URL.Path, documentsPrefix)
+	download := strings.HasSuffix(idText, downloadSuffix)
+	idText = strings.TrimSuffix(idText, downloadSuffix)
+	id, err := parseId(idText)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	record, exists := s.api.GetRecord(id)
+	if !exists {
+		http.Error(w, fmt.Sprintf("document with ID %s unknown", id), http.StatusNotFound)
+		return
+	}
+
+	absolute := s.api.Absolutize(record.AnchoredPath)
+	var status *doccurator.CheckResult
+	if results := s.api.GetStatus([]string{absolute}); len(results) == 1 {
+		status = &results[0]
+	}
+	//only offer files whose content matches the record (touched files just have a different modification time)
+	downloadable := !record.Retired && status != nil && (status.Status == library.Tracked || status.Status == library.Touched)
+
+	if download {
+		if !downloadable {
+			http.Error(w, "file not available or content differs from record", http.StatusConflict)
+			return
+		}
+		s.serveFile(w, r, absolute)
+		return
+	}
+	renderPage(w, "document.html", documentPage{
+		Layout:       s.layout("Document "+id.String(), ""),
+		Record:       record,
+		Sha256:       hex.EncodeToString(record.Sha256[:]),
+		Status:       status,
+		Downloadable: downloadable,
+	})
+}
+
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request, absolute string) {
+	file, err := os.Open(absolute)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(absolute)))
+	http.ServeContent(w, r, filepath.Base(absolute), stat.ModTime(), file)
+}
+
+func (s *Server) handleSearchPage(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("id"))
+	page := searchPage{Layout: s.layout("Search", query), Paths: make(map[document.Id]string)}
+	if query != "" {
+		page.Matches = s.api.SearchByIdPart(query)
+		for _, match := range page.Matches {
+			record, _ := s.api.GetRecord(match.Id) //exists because it was just found
+			page.Paths[match.Id] = filepath.ToSlash(record.AnchoredPath)
+		}
+	}
+	renderPage(w, "search.html", page)
+}
@@ -10,3 +10,24 @@ const Retire = "retire"
 const Forget = "forget"
 const Tree = "tree"
 const Dump = "dump"
+const Serve = "serve"
+const Root = "root"
+const RelocateDb = "relocate-db"
+const Identity = "identity"
+const Libraries = "libraries"
+const Compare = "compare"
+const Merge = "merge"
+const Split = "split"
+const CheckCopy = "check-copy"
+const Export = "export"
+const Restore = "restore"
+const Pack = "pack"
+const VerifyPack = "verify-pack"
+const Unpack = "unpack"
+const Retention = "retention"
+const Expire = "expire"
+const Due = "due"
+const Naming = "naming"
+const Rename = "rename"
+const Similar = "similar"
+const Duplicates = "duplicates"
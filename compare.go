@@ -0,0 +1,162 @@
+package doccurator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/n2code/doccurator/internal/document"
+	"github.com/n2code/doccurator/internal/library"
+	out "github.com/n2code/doccurator/internal/output"
+	"sort"
+)
+
+func (d *doccurator) CompareWith(otherDatabase string) (comparison LibraryComparison, err error) {
+	absoluteOther := mustAbsFilepath(otherDatabase)
+	if absoluteOther == d.libFile {
+		return comparison, fmt.Errorf("library database %s cannot be compared with itself", absoluteOther)
+	}
+	other, err := loadForeignLibrary(absoluteOther)
+	if err != nil {
+		return comparison, err
+	}
+	comparison.OtherDatabase = absoluteOther
+	comparison.OtherUuid = other.GetUuid()
+	comparison.OtherName = other.GetName()
+
+	here, there := collectActiveRecords(d.appLib), collectActiveRecords(other)
+	hereById, thereById := indexById(here), indexById(there)
+	hereByContent, thereByContent := indexByContent(here), indexByContent(there)
+
+	for _, record := range here {
+		if counterpart, exists := thereById[record.Id]; exists {
+			switch {
+			case counterpart.Sha256 != record.Sha256:
+				comparison.SameIdDifferentContent = append(comparison.SameIdDifferentContent, RecordPair{Here: record, There: counterpart})
+			case counterpart.AnchoredPath != record.AnchoredPath:
+				comparison.SameIdDifferentPath = append(comparison.SameIdDifferentPath, RecordPair{Here: record, There: counterpart})
+			default:
+				comparison.Identical++
+			}
+		} else if counterparts := thereByContent[record.Sha256]; len(counterparts) > 0 {
+			for _, counterpart := range counterparts {
+				comparison.SameContentDifferentId = append(comparison.SameContentDifferentId, RecordPair{Here: record, There: counterpart})
+			}
+		} else {
+			comparison.OnlyHere = append(comparison.OnlyHere, record)
+		}
+	}
+	for _, record := range there {
+		if _, exists := hereById[record.Id]; exists {
+			continue
+		}
+		counterparts := hereByContent[record.Sha256]
+		if len(counterparts) == 0 {
+			comparison.OnlyThere = append(comparison.OnlyThere, record)
+		}
+		for _, counterpart := range counterparts {
+			if _, counterpartMatchedById := thereById[counterpart.Id]; counterpartMatchedById { //otherwise paired above already
+				comparison.SameContentDifferentId = append(comparison.SameContentDifferentId, RecordPair{Here: counterpart, There: record})
+			}
+		}
+	}
+	sort.SliceStable(comparison.SameContentDifferentId, func(i, j int) bool {
+		return comparison.SameContentDifferentId[i].Here.AnchoredPath < comparison.SameContentDifferentId[j].Here.AnchoredPath
+	})
+	return comparison, nil
+}
+
+func (d *doccurator) PrintComparison(comparison LibraryComparison) {
+	otherName := comparison.OtherName
+	if otherName == "" {
+		otherName = "(unnamed)"
+	}
+	d.Print(out.Normal, "Comparison with %s [%s] in %s\n\n", otherName, comparison.OtherUuid, comparison.OtherDatabase)
+
+	printHeader := func(title string, count int) bool {
+		if count == 0 {
+			return false
+		}
+		d.Print(out.Normal, " %s (%d %s)\n", title, count, out.Plural(count, "record", "records"))
+		return true
+	}
+	if printHeader("Only here", len(comparison.OnlyHere)) {
+		for _, record := range comparison.OnlyHere {
+			d.Print(out.Required, "  [%s] %s\n", record.Id, displayableAnchoredPath(record.AnchoredPath))
+		}
+		d.Print(out.Normal, "\n")
+	}
+	if printHeader("Only there", len(comparison.OnlyThere)) {
+		for _, record := range comparison.OnlyThere {
+			d.Print(out.Required, "  [%s] %s\n", record.Id, displayableAnchoredPath(record.AnchoredPath))
+		}
+		d.Print(out.Normal, "\n")
+	}
+	if printHeader("Same ID, different content", len(comparison.SameIdDifferentContent)) {
+		for _, pair := range comparison.SameIdDifferentContent {
+			d.Print(out.Required, "  [%s] %s\n", pair.Here.Id, displayableAnchoredPath(pair.Here.AnchoredPath))
+			d.Print(out.Normal, "      there: %s (%s instead of %s)\n", displayableAnchoredPath(pair.There.AnchoredPath), out.Filesize(pair.There.Size), out.Filesize(pair.Here.Size))
+		}
+		d.Print(out.Normal, "\n")
+	}
+	if printHeader("Same content, different ID", len(comparison.SameContentDifferentId)) {
+		for _, pair := range comparison.SameContentDifferentId {
+			d.Print(out.Required, "  [%s] %s\n", pair.Here.Id, displayableAnchoredPath(pair.Here.AnchoredPath))
+			d.Print(out.Normal, "      there: [%s] %s\n", pair.There.Id, displayableAnchoredPath(pair.There.AnchoredPath))
+		}
+		d.Print(out.Normal, "\n")
+	}
+	if printHeader("Same ID and content, different path", len(comparison.SameIdDifferentPath)) {
+		for _, pair := range comparison.SameIdDifferentPath {
+			d.Print(out.Required, "  [%s] %s\n", pair.Here.Id, displayableAnchoredPath(pair.Here.AnchoredPath))
+			d.Print(out.Normal, "      there: %s\n", displayableAnchoredPath(pair.There.AnchoredPath))
+		}
+		d.Print(out.Normal, "\n")
+	}
+	d.Print(out.Normal, " %d %s identical in both libraries.\n", comparison.Identical, out.Plural(comparison.Identical, "record", "records"))
+}
+
+// loadForeignLibrary reads the database of another library without taking ownership of it, i.e. it is never saved
+func loadForeignLibrary(absoluteDatabase string) (lib library.Api, err error) {
+	defer func() {
+		if loadErr := recover(); loadErr != nil {
+			err = fmt.Errorf("library %s cannot be loaded: %v", absoluteDatabase, loadErr)
+		}
+	}()
+	lib = library.NewLibrary()
+	lib.LoadFromLocalFile(absoluteDatabase)
+	return lib, nil
+}
+
+// collectActiveRecords yields all records which are not retired, sorted by path
+func collectActiveRecords(lib library.Api) (records []Record) {
+	lib.VisitAllRecords(func(doc library.Document) {
+		if !doc.IsObsolete() {
+			records = append(records, makeRecord(doc))
+		}
+	})
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].AnchoredPath < records[j].AnchoredPath
+	})
+	return
+}
+
+func indexByContent(records []Record) map[[sha256.Size]byte][]Record {
+	index := make(map[[sha256.Size]byte][]Record)
+	for _, record := range records {
+		index[record.Sha256] = append(index[record.Sha256], record)
+	}
+	return index
+}
+
+func indexById(records []Record) map[document.Id]Record {
+	index := make(map[document.Id]Record, len(records))
+	for _, record := range records {
+		index[record.Id] = record
+	}
+	return index
+}
+
+// displayableAnchoredPath prefixes an anchored path with the scheme of its library root
+func displayableAnchoredPath(anchored string) string {
+	scheme, relative := rootSchemeOf(anchored)
+	return scheme + relative
+}
@@ -0,0 +1,43 @@
+package doccurator
+
+import (
+	"github.com/n2code/doccurator/internal/document"
+	"testing"
+)
+
+func TestCompareWith(t *testing.T) {
+	//GIVEN
+	_, here := newTestLibrary(t, map[document.Id]string{1: "same", 2: "moved", 4: "local", 5: "renumbered"})
+	addTestFile(t, here, 3, "changed", "old")
+	here.PersistChanges()
+	_, there := newTestLibrary(t, map[document.Id]string{1: "same", 6: "remote", 7: "renumbered"})
+	addTestFile(t, there, 2, "elsewhere", "moved")
+	addTestFile(t, there, 3, "changed", "new")
+	there.PersistChanges()
+
+	//WHEN
+	comparison, err := here.CompareWith(there.libFile)
+
+	//THEN
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comparison.Identical != 1 {
+		t.Errorf("expected 1 identical record but got %d", comparison.Identical)
+	}
+	if len(comparison.OnlyHere) != 1 || comparison.OnlyHere[0].Id != 4 {
+		t.Errorf("only-here group wrong: %+v", comparison.OnlyHere)
+	}
+	if len(comparison.OnlyThere) != 1 || comparison.OnlyThere[0].Id != 6 {
+		t.Errorf("only-there group wrong: %+v", comparison.OnlyThere)
+	}
+	if len(comparison.SameIdDifferentContent) != 1 || comparison.SameIdDifferentContent[0].Here.Id != 3 {
+		t.Errorf("same-ID-different-content group wrong: %+v", comparison.SameIdDifferentContent)
+	}
+	if len(comparison.SameContentDifferentId) != 1 || comparison.SameContentDifferentId[0].Here.Id != 5 || comparison.SameContentDifferentId[0].There.Id != 7 {
+		t.Errorf("same-content-different-ID group wrong: %+v", comparison.SameContentDifferentId)
+	}
+	if len(comparison.SameIdDifferentPath) != 1 || comparison.SameIdDifferentPath[0].There.AnchoredPath != "elsewhere" {
+		t.Errorf("same-ID-different-path group wrong: %+v", comparison.SameIdDifferentPath)
+	}
+}
@@ -0,0 +1,193 @@
+package doccurator
+
+import (
+	checksum "crypto/sha256"
+	"fmt"
+	"github.com/n2code/doccurator/internal"
+	"github.com/n2code/doccurator/internal/document"
+	"github.com/n2code/doccurator/internal/library"
+	out "github.com/n2code/doccurator/internal/output"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func (d *doccurator) CheckCopy(directory string, rootName string) (result CopyCheck, err error) {
+	absoluteCopy := mustAbsFilepath(directory)
+	if stat, statErr := os.Stat(absoluteCopy); statErr != nil {
+		return result, fmt.Errorf("copy inaccessible: %w", statErr)
+	} else if !stat.IsDir() {
+		return result, fmt.Errorf("copy %s is not a directory", absoluteCopy)
+	}
+	rootDir := d.appLib.GetRoot()
+	if rootName != "" {
+		var known bool
+		if rootDir, known = d.appLib.GetNamedRoots()[rootName]; !known {
+			return result, fmt.Errorf("library root %q unknown", rootName)
+		}
+	}
+	if absoluteCopy == rootDir {
+		return result, fmt.Errorf("copy %s is the library root itself", absoluteCopy)
+	}
+	databaseInCopy := "" //a copy of the library database is not considered extra
+	if isChildOf(d.libFile, rootDir) {
+		databaseInCopy, _ = filepath.Rel(rootDir, d.libFile)
+	}
+	result.Directory = absoluteCopy
+	result.Unreadable = make(map[string]error)
+
+	//expectation: all active records of the root, by path relative to it
+	expected := make(map[string]Record)
+	byContent := make(map[[checksum.Size]byte][]Record)
+	sizes := make(map[int64]bool)
+	d.appLib.VisitAllRecords(func(doc library.Document) {
+		root, relative := document.SplitAnchoredPath(doc.AnchoredPath())
+		if doc.IsObsolete() || root != rootName {
+			return
+		}
+		record := makeRecord(doc)
+		expected[relative] = record
+		byContent[record.Sha256] = append(byContent[record.Sha256], record)
+		sizes[record.Size] = true
+	})
+
+	found := make(map[document.Id]bool)
+	misplacedAt := make(map[document.Id]string)
+	_ = filepath.WalkDir(absoluteCopy, func(path string, entry fs.DirEntry, walkErr error) error {
+		relative, _ := filepath.Rel(absoluteCopy, path)
+		if walkErr != nil {
+			result.Unreadable[relative] = walkErr
+			return nil //continue, directories are skipped automatically
+		}
+		if path == absoluteCopy {
+			return nil
+		}
+		if relative == databaseInCopy || entry.Name() == library.LocatorFileName || (!d.scanAll && strings.HasPrefix(entry.Name(), ".")) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil //directories are descended into, other special files are not considered
+		}
+		info, statErr := entry.Info()
+		if statErr != nil {
+			result.Unreadable[relative] = statErr
+			return nil
+		}
+
+		//by path
+		if record, onRecord := expected[relative]; onRecord {
+			if info.Size() == record.Size {
+				sum, hashErr := internal.Sha256OfFile(path)
+				if hashErr != nil {
+					result.Unreadable[relative] = hashErr
+					return nil
+				}
+				if sum == record.Sha256 {
+					found[record.Id] = true
+					result.Intact++
+					return nil
+				}
+			}
+			result.Corrupted = append(result.Corrupted, record)
+			found[record.Id] = true
+			return nil
+		}
+
+		//by content
+		if sizes[info.Size()] {
+			sum, hashErr := internal.Sha256OfFile(path)
+			if hashErr != nil {
+				result.Unreadable[relative] = hashErr
+				return nil
+			}
+			for _, record := range byContent[sum] {
+				if _, alreadyFound := misplacedAt[record.Id]; !alreadyFound {
+					misplacedAt[record.Id] = relative
+					return nil
+				}
+			}
+		}
+		result.Extra = append(result.Extra, relative)
+		return nil
+	})
+
+	for _, record := range expected {
+		if found[record.Id] {
+			if copyPath, misplaced := misplacedAt[record.Id]; misplaced {
+				result.Extra = append(result.Extra, copyPath) //redundant copy of a record which is in place
+			}
+			continue
+		}
+		if copyPath, misplaced := misplacedAt[record.Id]; misplaced {
+			result.Misplaced = append(result.Misplaced, MisplacedCopy{Record: record, CopyPath: copyPath})
+		} else {
+			result.Missing = append(result.Missing, record)
+		}
+	}
+
+	sortRecords := func(records []Record) {
+		sort.Slice(records, func(i, j int) bool { return records[i].AnchoredPath < records[j].AnchoredPath })
+	}
+	sortRecords(result.Missing)
+	sortRecords(result.Corrupted)
+	sort.Slice(result.Misplaced, func(i, j int) bool {
+		return result.Misplaced[i].Record.AnchoredPath < result.Misplaced[j].Record.AnchoredPath
+	})
+	sort.Strings(result.Extra)
+	return result, nil
+}
+
+func (d *doccurator) PrintCopyCheck(check CopyCheck) {
+	d.Print(out.Normal, "Copy in %s compared to library records:\n\n", check.Directory)
+
+	printHeader := func(title string, count int, subject string) bool {
+		if count == 0 {
+			return false
+		}
+		d.Print(out.Normal, " %s (%d %s)\n", title, count, out.Plural(count, subject, subject+"s"))
+		return true
+	}
+	if printHeader("Missing", len(check.Missing), "record") {
+		for _, record := range check.Missing {
+			d.Print(out.Required, "  [%s] %s\n", record.Id, displayableAnchoredPath(record.AnchoredPath))
+		}
+		d.Print(out.Normal, "\n")
+	}
+	if printHeader("Corrupted", len(check.Corrupted), "record") {
+		for _, record := range check.Corrupted {
+			d.Print(out.Required, "  [%s] %s\n", record.Id, displayableAnchoredPath(record.AnchoredPath))
+		}
+		d.Print(out.Normal, "\n")
+	}
+	if printHeader("Misplaced", len(check.Misplaced), "record") {
+		for _, misplaced := range check.Misplaced {
+			d.Print(out.Required, "  [%s] %s\n", misplaced.Record.Id, displayableAnchoredPath(misplaced.Record.AnchoredPath))
+			d.Print(out.Normal, "      found at: %s\n", filepath.Join(check.Directory, misplaced.CopyPath))
+		}
+		d.Print(out.Normal, "\n")
+	}
+	if printHeader("Extra", len(check.Extra), "file") {
+		for _, path := range check.Extra {
+			d.Print(out.Required, "  %s\n", filepath.Join(check.Directory, path))
+		}
+		d.Print(out.Normal, "\n")
+	}
+	if len(check.Unreadable) > 0 {
+		paths := make([]string, 0, len(check.Unreadable))
+		for path := range check.Unreadable {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		d.Print(out.Normal, " %s occurred:\n", out.Plural(paths, "Error", "Errors"))
+		for _, path := range paths {
+			d.Print(out.Error, "  %s: %s\n", filepath.Join(check.Directory, path), check.Unreadable[path])
+		}
+		d.Print(out.Normal, "\n")
+	}
+	d.Print(out.Normal, " %d %s intact.\n", check.Intact, out.Plural(check.Intact, "record", "records"))
+}
@@ -0,0 +1,76 @@
+package doccurator
+
+import (
+	"github.com/n2code/doccurator/internal/document"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckCopy(t *testing.T) {
+	//GIVEN
+	files := map[document.Id]string{1: "intact", 2: "missing", 3: "corrupted", 4: filepath.Join("sub", "misplaced"), 5: "retired"}
+	root, lib := newTestLibrary(t, files)
+	lib.RetireByPath(filepath.Join(root, "retired"))
+	lib.PersistChanges()
+
+	copyDir := t.TempDir()
+	os.MkdirAll(filepath.Join(copyDir, "elsewhere"), 0o700)
+	copyFiles := map[string]string{
+		"intact":                              "intact",
+		"corrupted":                           "corrupteD",
+		filepath.Join("elsewhere", "moved"):   filepath.Join("sub", "misplaced"),
+		"extra":                               "unknown",
+		"library.db":                          "database copy",
+		filepath.Join("elsewhere", ".hidden"): "hidden",
+	}
+	for path, content := range copyFiles {
+		os.WriteFile(filepath.Join(copyDir, path), []byte(content), 0o600)
+	}
+
+	//WHEN
+	check, err := lib.CheckCopy(copyDir, "")
+
+	//THEN
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Intact != 1 {
+		t.Errorf("expected 1 intact record but got %d", check.Intact)
+	}
+	if len(check.Missing) != 1 || check.Missing[0].Id != 2 {
+		t.Errorf("missing record not detected: %+v", check.Missing)
+	}
+	if len(check.Corrupted) != 1 || check.Corrupted[0].Id != 3 {
+		t.Errorf("corrupted record not detected: %+v", check.Corrupted)
+	}
+	if len(check.Misplaced) != 1 || check.Misplaced[0].Record.Id != 4 || check.Misplaced[0].CopyPath != filepath.Join("elsewhere", "moved") {
+		t.Errorf("misplaced record not detected: %+v", check.Misplaced)
+	}
+	if len(check.Extra) != 1 || check.Extra[0] != "extra" {
+		t.Errorf("expected only extra file to be reported but got %v", check.Extra)
+	}
+	if check.Complete() {
+		t.Error("damaged copy reported as complete")
+	}
+
+	t.Run("CompleteCopy", func(Test *testing.T) {
+		complete := t.TempDir()
+		for _, path := range files {
+			writeTestFile(Test, complete, path, path)
+		}
+		check, err := lib.CheckCopy(complete, "")
+		if err != nil {
+			Test.Fatal(err)
+		}
+		if !check.Complete() || check.Intact != 4 || len(check.Extra) != 1 {
+			Test.Errorf("complete copy misjudged (retired file should be extra): %+v", check)
+		}
+	})
+
+	t.Run("UnknownRoot", func(Test *testing.T) {
+		if _, err := lib.CheckCopy(copyDir, "unknown"); err == nil {
+			Test.Error("unknown root accepted")
+		}
+	})
+}
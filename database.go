@@ -1,10 +1,10 @@
 package doccurator
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	out "github.com/n2code/doccurator/internal/output"
-	"net/url"
 	"os"
 	"path/filepath"
 
@@ -17,6 +17,23 @@ func (d *doccurator) PersistChanges() error {
 	}
 	d.rollbackLog = nil
 	d.Print(out.Verbose, "Saved library rooted at %s to %s\n", d.appLib.GetRoot(), d.libFile)
+	d.commitStagedDeletions()
+	d.register()
+	for _, split := range d.registrationQueue {
+		split.register()
+	}
+	d.registrationQueue = nil
+	return nil
+}
+
+func (d *doccurator) DiscardChanges() error {
+	d.RollbackAllFilesystemChanges()
+	reloaded, err := loadForeignLibrary(d.libFile) //the library in memory is kept if the database cannot be loaded
+	if err != nil {
+		return err
+	}
+	d.appLib = reloaded
+	d.Print(out.Verbose, "Loaded library rooted at %s from %s\n", d.appLib.GetRoot(), d.libFile)
 	return nil
 }
 
@@ -46,45 +63,109 @@ func (d *doccurator) RollbackAllFilesystemChanges() (complete bool) {
 	} else {
 		d.Print(out.Normal, "  Rollback completed partially, issues occurred.\n")
 	}
-	d.rollbackLog = nil //note: failed rollback steps are not preserved
+	d.rollbackLog = nil         //note: failed rollback steps are not preserved
+	d.deletionCommitQueue = nil //staging directories are cleaned up by the rollback steps
+	d.registrationQueue = nil   //the libraries have been removed by the rollback steps
 	return
 }
 
-func (d *doccurator) createLibrary(absoluteRoot string, absoluteDbFilePath string) error {
-	d.appLib = library.NewLibrary()
-
-	d.appLib.SetRoot(absoluteRoot)
+// stageFileDeletion moves the given file into a temporary staging directory next to it.
+// The deletion is finalized by PersistChanges and can be reverted by RollbackAllFilesystemChanges until then.
+func (d *doccurator) stageFileDeletion(absolute string) error {
+	tempDir, err := os.MkdirTemp(filepath.Dir(absolute), ".doccurator-tidy-delete-staging-*")
+	if err != nil {
+		return fmt.Errorf("preparation failed: %w", err)
+	}
+	deleteStagingDir := func(stagingDir string) func() error {
+		return func() error {
+			if err := os.RemoveAll(stagingDir); err != nil {
+				return fmt.Errorf("could not delete temporary staging directory (%s): %w", stagingDir, err)
+			}
+			return nil
+		}
+	}(tempDir)
 
-	d.libFile = absoluteDbFilePath
-	if err := d.appLib.SaveToLocalFile(absoluteDbFilePath, false); err != nil {
+	backup := filepath.Join(tempDir, filepath.Base(absolute))
+	if err := os.Rename(absolute, backup); err != nil {
+		if cleanupErr := deleteStagingDir(); cleanupErr != nil {
+			d.Print(out.Error, "%s\n", cleanupErr)
+		}
 		return err
 	}
-	d.Print(out.Verbose, "Database saved in %s\n", absoluteDbFilePath)
+	d.deletionCommitQueue = append(d.deletionCommitQueue, deleteStagingDir)
+
+	d.rollbackLog = append(d.rollbackLog, func(source string, target string, stagingDir string) func() error {
+		return func() error {
+			if err := os.Rename(source, target); err != nil {
+				return err
+			}
+			return os.RemoveAll(stagingDir)
+		}
+	}(backup, absolute, tempDir))
 	return nil
 }
 
-func (d *doccurator) discoverLibraryFile(startingDirectoryAbsolute string) (err error) {
-	defer func() {
-		if err != nil {
-			err = fmt.Errorf("library not found: %w", err)
-		}
-	}()
-	currentDir := startingDirectoryAbsolute
-	for {
-		locatorPath := filepath.Join(currentDir, library.LocatorFileName)
-		stat, statErr := os.Stat(locatorPath)
-		if statErr == nil && stat.Mode().IsRegular() {
-			err = d.loadLibFilePathFromLocatorFile(locatorPath)
-			return
-		} else if errors.Is(statErr, os.ErrNotExist) {
-			if currentDir == "/" {
-				return errors.New("stopping at filesystem root")
-			}
-			currentDir = filepath.Dir(currentDir)
+// stageFileDeduplication replaces the given file with a hardlink to the original or, if the original cannot be linked
+// (e.g. due to a link limit), with a reflink clone where the filesystem supports it. Only hardlinks are recognized
+// afterward (status Linked), clones are still reported as duplicates. The replaced file is staged like a deletion (see
+// stageFileDeletion), i.e. the deduplication is finalized by PersistChanges and can be reverted by
+// RollbackAllFilesystemChanges until then.
+func (d *doccurator) stageFileDeduplication(absolute string, original string) (linkKind string, err error) {
+	stat, err := os.Stat(absolute)
+	if err != nil {
+		return "", err
+	}
+	if err := d.stageFileDeletion(absolute); err != nil {
+		return "", err
+	}
+	linkKind = "hardlink"
+	if linkErr := os.Link(original, absolute); linkErr != nil {
+		linkKind = "reflink"
+		if cloneErr := cloneFile(original, absolute); cloneErr == nil {
+			os.Chtimes(absolute, stat.ModTime(), stat.ModTime()) //the clone is a new file which shall look like the replaced one
 		} else {
-			return statErr
+			//undo the staging right away because the file must not be lost when the other deletions are committed
+			restore := d.rollbackLog[len(d.rollbackLog)-1]
+			d.rollbackLog = d.rollbackLog[:len(d.rollbackLog)-1]
+			d.deletionCommitQueue = d.deletionCommitQueue[:len(d.deletionCommitQueue)-1]
+			if restoreErr := restore(); restoreErr != nil {
+				d.Print(out.Error, "%s\n", restoreErr)
+			}
+			return "", fmt.Errorf("neither hardlink nor reflink possible: %w", linkErr)
 		}
 	}
+	d.rollbackLog = append(d.rollbackLog, func() error {
+		return os.Remove(absolute)
+	})
+	return linkKind, nil
+}
+
+func (d *doccurator) commitStagedDeletions() {
+	if len(d.deletionCommitQueue) == 0 {
+		return
+	}
+	d.Print(out.Normal, "Committing deletions...\n")
+	for _, commitDelete := range d.deletionCommitQueue {
+		if err := commitDelete(); err != nil {
+			//errors are reported but do not constitute an overall failure as a rollback would not work and removal from the original directory is already complete by now
+			// => failure is only possible theoretically anyway as the application should be able to remove the staging directory it has just created
+			d.Print(out.Error, "deletion has leftovers: %s\n", err)
+		}
+	}
+	d.deletionCommitQueue = nil
+}
+
+func (d *doccurator) createLibrary(absoluteRoot string, absoluteDbFilePath string, rootRelativeToDatabase bool) error {
+	d.appLib = library.NewLibrary()
+
+	d.appLib.MapRoot(absoluteRoot, rootRelativeToDatabase, "")
+
+	d.libFile = absoluteDbFilePath
+	if err := d.appLib.SaveToLocalFile(absoluteDbFilePath, false); err != nil {
+		return err
+	}
+	d.Print(out.Verbose, "Database saved in %s\n", absoluteDbFilePath)
+	return nil
 }
 
 func (d *doccurator) loadLibrary() {
@@ -93,41 +174,99 @@ func (d *doccurator) loadLibrary() {
 	d.Print(out.Verbose, "Loaded library rooted at %s from %s\n", d.appLib.GetRoot(), d.libFile)
 }
 
-const libraryLocatorPermissions = 0o440 //owner and group can read
+func (d *doccurator) RelocateDatabase(newPath string) (err error) {
+	oldPath := d.libFile
+	earlierSteps := len(d.rollbackLog) //of uncommitted changes preceding the relocation
+	absNewPath := mustAbsFilepath(newPath)
+	if stat, statErr := os.Stat(absNewPath); statErr == nil && stat.IsDir() {
+		absNewPath = filepath.Join(absNewPath, filepath.Base(oldPath))
+	}
+	if absNewPath == oldPath {
+		return fmt.Errorf("library database is already located at %s", oldPath)
+	}
 
-func (d *doccurator) createLocatorFile(directory string, deleteExisting bool) error {
-	path := filepath.Join(directory, library.LocatorFileName)
+	//the library is saved anew instead of renaming the file because roots may be persisted relative to the database location
+	if err = d.appLib.SaveToLocalFile(absNewPath, false); err != nil {
+		return err
+	}
+	d.libFile = absNewPath
+	d.rollbackLog = append(d.rollbackLog, func() error {
+		d.libFile = oldPath //the old database is only removed once the relocation is complete
+		return os.Remove(absNewPath)
+	})
 
-	if deleteExisting {
-		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("updating library locator (%s) failed: %w", path, err)
+	rootDirectories := []string{d.appLib.GetRoot()}
+	for _, directory := range d.appLib.GetNamedRoots() {
+		rootDirectories = append(rootDirectories, directory)
+	}
+	for _, directory := range rootDirectories {
+		locator := filepath.Join(directory, library.LocatorFileName)
+		existing, readErr := readLocatorFile(locator)
+		if readErr != nil || !existing.refersTo(oldPath) {
+			continue //locators of other libraries are not touched
 		}
+		relative := existing.candidates[0].relative //keep priority
+		original, readErr := os.ReadFile(locator)
+		if readErr != nil {
+			return readErr
+		}
+		d.rollbackLog = append(d.rollbackLog, func() error { //registered upfront because the rewrite may fail halfway
+			if current, _ := os.ReadFile(locator); bytes.Equal(current, original) {
+				return nil
+			}
+			if err := os.Remove(locator); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			return os.WriteFile(locator, original, libraryLocatorPermissions)
+		})
+		if err = d.createLocatorFile(directory, relative, true); err != nil {
+			return err
+		}
+		d.Print(out.Normal, "Updated library locator %s\n", locator)
 	}
 
-	locationUri := url.URL{Scheme: "file", Path: d.libFile}
-	if err := os.WriteFile(path, []byte(locationUri.String()), libraryLocatorPermissions); err != nil {
-		return fmt.Errorf("writing library locator (%s) failed: %w", path, err)
+	if err = d.verifyDatabaseLocation(); err != nil {
+		return fmt.Errorf("verification of relocated library failed: %w", err)
+	}
+	//companions are moved after the verification because a leftover work-in-progress file prevents loading
+	for _, suffix := range library.DatabaseCompanionSuffixes {
+		source, target := oldPath+suffix, absNewPath+suffix
+		if _, statErr := os.Lstat(source); errors.Is(statErr, os.ErrNotExist) {
+			continue
+		}
+		if err = os.Rename(source, target); err != nil {
+			return fmt.Errorf("moving %s failed: %w", source, err)
+		}
+		d.rollbackLog = append(d.rollbackLog, func() error {
+			return os.Rename(target, source)
+		})
+	}
+	if err = os.Remove(oldPath); err != nil {
+		return fmt.Errorf("removing old library database failed: %w", err)
 	}
-	d.Print(out.Verbose, "Created library locator %s\n", path)
+	d.Print(out.Normal, "Moved library database from %s to %s\n", oldPath, absNewPath)
+
+	d.rollbackLog = d.rollbackLog[:earlierSteps] //relocation complete, only the preceding changes remain revertible
+	d.register()
 	return nil
 }
 
-func (d *doccurator) loadLibFilePathFromLocatorFile(path string) error {
-	contents, err := os.ReadFile(path)
-	if err != nil {
-		return err
-	}
-	url, err := url.Parse(string(contents))
-	if err != nil {
+// verifyDatabaseLocation reopens the library from its root directory and compares the result with the current state
+func (d *doccurator) verifyDatabaseLocation() (err error) {
+	reopened := makeDoccurator(HandleConfig{Verbosity: QuietMode})
+	defer func() {
+		if loadErr := recover(); loadErr != nil {
+			err = fmt.Errorf("library cannot be loaded: %v", loadErr)
+		}
+	}()
+	if err = reopened.discoverLibrary(d.appLib.GetRoot()); err != nil {
 		return err
 	}
-	if url.Scheme != "file" {
-		return fmt.Errorf(`scheme of URL in library locator file (%s) missing or unsupported: "%s"`, path, url.Scheme)
+	if reopened.libFile != d.libFile {
+		return fmt.Errorf("library locator refers to %s instead of %s", reopened.libFile, d.libFile)
 	}
-	if !filepath.IsAbs(url.Path) {
-		return fmt.Errorf(`no absolute path in library locator file (%s): "%s"`, path, url.Path)
+	if reopened.appLib.GetRoot() != d.appLib.GetRoot() {
+		return fmt.Errorf("library root resolves to %s instead of %s", reopened.appLib.GetRoot(), d.appLib.GetRoot())
 	}
-	d.libFile = url.Path
-	d.Print(out.Verbose, "Used library locator %s\n", path)
 	return nil
 }
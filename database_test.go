@@ -0,0 +1,124 @@
+package doccurator
+
+import (
+	"github.com/n2code/doccurator/internal/library"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRelocateDatabase(t *testing.T) {
+	setup := func(Test *testing.T, relative bool) (root string, lib *doccurator) {
+		root = Test.TempDir()
+		handle, err := New(root, filepath.Join(Test.TempDir(), "library.db"), RootMapping{RelativeToDatabase: relative}, testConfig(Test))
+		if err != nil {
+			Test.Fatal(err)
+		}
+		lib = handle.(*doccurator)
+		addTestFile(Test, lib, 1, "file", "file")
+		lib.PersistChanges()
+		return
+	}
+	exists := func(path string) bool {
+		_, err := os.Lstat(path)
+		return err == nil
+	}
+	companion := library.DatabaseCompanionSuffixes[0]
+
+	for _, relative := range []bool{false, true} {
+		name := map[bool]string{false: "AbsoluteRoot", true: "RelativeRoot"}[relative]
+		t.Run(name, func(Test *testing.T) {
+			//GIVEN
+			root, lib := setup(Test, relative)
+			oldDatabase := lib.libFile
+			os.WriteFile(oldDatabase+companion, []byte("leftover"), 0o600)
+			target := Test.TempDir()
+			newDatabase := filepath.Join(target, "library.db")
+
+			//WHEN
+			err := lib.RelocateDatabase(target)
+
+			//THEN
+			if err != nil {
+				Test.Fatal(err)
+			}
+			if lib.libFile != newDatabase || exists(oldDatabase) || !exists(newDatabase) {
+				Test.Errorf("database not moved from %s to %s", oldDatabase, newDatabase)
+			}
+			if exists(oldDatabase+companion) || !exists(newDatabase+companion) {
+				Test.Error("companion file not moved along")
+			}
+			locator, err := readLocatorFile(filepath.Join(root, library.LocatorFileName))
+			if err != nil || !locator.refersTo(newDatabase) || locator.candidates[0].relative != relative {
+				Test.Errorf("locator not rewritten keeping its priority (%v): %+v", err, locator)
+			}
+			os.Remove(newDatabase + companion) //prevents loading
+			reopened, err := Open(root, testConfig(Test))
+			if err != nil {
+				Test.Fatal(err)
+			}
+			if reloaded := reopened.(*doccurator); reloaded.libFile != newDatabase || reloaded.appLib.GetRoot() != root || reloaded.appLib.IsRootRelativeToDatabase() != relative {
+				Test.Error("relocated library not reopened as before")
+			}
+			if _, exists := reopened.GetRecord(1); !exists {
+				Test.Error("record lost")
+			}
+		})
+	}
+
+	t.Run("EarlierChangesStayRevertible", func(Test *testing.T) {
+		//GIVEN
+		root, lib := setup(Test, false)
+		duplicate := writeTestFile(Test, root, "duplicate", "file")
+		if err := lib.RemoveWasteByPath(duplicate); err != nil {
+			Test.Fatal(err)
+		}
+
+		//WHEN
+		err := lib.RelocateDatabase(Test.TempDir())
+		lib.RollbackAllFilesystemChanges()
+
+		//THEN
+		if err != nil {
+			Test.Fatal(err)
+		}
+		if !exists(duplicate) {
+			Test.Error("deletion preceding the relocation not reverted")
+		}
+		if !exists(lib.libFile) {
+			Test.Error("completed relocation reverted")
+		}
+	})
+
+	t.Run("RollbackAfterFailedLocatorRewrite", func(Test *testing.T) {
+		if os.Geteuid() == 0 {
+			Test.Skip("permissions are not enforced for root")
+		}
+		//GIVEN
+		root, lib := setup(Test, false)
+		oldDatabase := lib.libFile
+		locator := filepath.Join(root, library.LocatorFileName)
+		original, _ := os.ReadFile(locator)
+		os.Chmod(root, 0o500) //locator cannot be replaced
+		defer os.Chmod(root, 0o700)
+		target := Test.TempDir()
+
+		//WHEN
+		err := lib.RelocateDatabase(target)
+		lib.RollbackAllFilesystemChanges()
+
+		//THEN
+		if err == nil {
+			Test.Fatal("relocation succeeded without updating the locator")
+		}
+		if lib.libFile != oldDatabase || !exists(oldDatabase) || exists(filepath.Join(target, "library.db")) {
+			Test.Error("database not restored at its old location")
+		}
+		if current, _ := os.ReadFile(locator); string(current) != string(original) {
+			Test.Errorf("locator not restored, reads %q", current)
+		}
+		if _, err := Open(root, testConfig(Test)); err != nil {
+			Test.Errorf("library not usable after rollback: %v", err)
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package doccurator
+
+import (
+	"fmt"
+	"github.com/n2code/doccurator/internal/document"
+	"github.com/n2code/doccurator/internal/library"
+	out "github.com/n2code/doccurator/internal/output"
+	"sort"
+	"time"
+)
+
+const dueDateLayout = "2006-01-02"
+
+func (d *doccurator) SetDueDate(id document.Id, due time.Time) error {
+	doc, exists := d.appLib.GetDocumentById(id)
+	if !exists {
+		return fmt.Errorf("document %s does not exist", id)
+	}
+	d.appLib.SetDocumentDue(doc, due)
+	if due.IsZero() {
+		d.Print(out.Normal, "Due date of %s removed\n", id)
+	} else {
+		d.Print(out.Normal, "Due date of %s set to %s\n", id, due.Local().Format(dueDateLayout))
+	}
+	return nil
+}
+
+func (d *doccurator) CollectDue(asOf time.Time, window time.Duration) (report DueReport, caughtUpCount int) {
+	horizon := asOf.Add(window)
+	d.appLib.VisitAllRecords(func(doc library.Document) {
+		if doc.IsObsolete() {
+			return
+		}
+		if document.IsCertificateFilename(doc.AnchoredPath()) {
+			stored, err := d.appLib.CatchUpCertificateExpiry(doc) //no-op for content examined before
+			if err != nil {
+				d.Print(out.Error, "expiry detection failed (%s): %s\n", displayableAnchoredPath(doc.AnchoredPath()), err)
+			} else if stored {
+				caughtUpCount++
+			}
+		}
+		due, set := doc.DueDate()
+		if !set {
+			return
+		}
+		switch {
+		case !due.After(asOf):
+			report.Overdue = append(report.Overdue, makeRecord(doc))
+		case !due.After(horizon):
+			report.Upcoming = append(report.Upcoming, makeRecord(doc))
+		}
+	})
+	byDueDate := func(records []Record) {
+		sort.SliceStable(records, func(i, j int) bool { return records[i].Due.Before(records[j].Due) })
+	}
+	byDueDate(report.Overdue)
+	byDueDate(report.Upcoming)
+	if caughtUpCount > 0 {
+		d.Print(out.Verbose, "Expiry examined for %d %s.\n", caughtUpCount, out.Plural(caughtUpCount, "certificate", "certificates"))
+	}
+	return
+}
+
+func (d *doccurator) PrintDueReport(report DueReport) {
+	printGroup := func(title string, records []Record) {
+		if len(records) == 0 {
+			return
+		}
+		d.Print(out.Normal, " %s (%d %s)\n", title, len(records), out.Plural(records, "document", "documents"))
+		for _, record := range records {
+			d.Print(out.Required, "  %s [%s] %s\n", record.Due.Local().Format(dueDateLayout), record.Id, displayableAnchoredPath(record.AnchoredPath))
+		}
+		d.Print(out.Normal, "\n")
+	}
+	printGroup("Overdue", report.Overdue)
+	printGroup("Upcoming", report.Upcoming)
+	if len(report.Overdue) == 0 && len(report.Upcoming) == 0 {
+		d.Print(out.Normal, "Nothing due.\n")
+	}
+}
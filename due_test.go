@@ -0,0 +1,126 @@
+package doccurator
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/n2code/doccurator/internal/document"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestCollectDue(t *testing.T) {
+	//GIVEN
+	root, lib := newTestLibrary(t, nil)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name     string
+		due      time.Time //zero if none is set
+		retired  bool
+		overdue  bool
+		upcoming bool
+	}{
+		{name: "Overdue", due: now.AddDate(0, 0, -1), overdue: true},
+		{name: "Upcoming", due: now.AddDate(0, 0, 10), upcoming: true},
+		{name: "BeyondWindow", due: now.AddDate(0, 3, 0)},
+		{name: "Retired", due: now.AddDate(0, 0, -5), retired: true},
+		{name: "Undated"},
+	}
+	for i, tt := range tests {
+		id := document.Id(i + 1)
+		path := addTestFile(t, lib, id, tt.name, tt.name)
+		if !tt.due.IsZero() {
+			lib.SetDueDate(id, tt.due)
+		}
+		if tt.retired {
+			lib.RetireByPath(path)
+		}
+	}
+	lib.PersistChanges()
+
+	//WHEN
+	report, _ := lib.CollectDue(now, 30*24*time.Hour)
+
+	//THEN
+	listed := func(records []Record, id document.Id) bool {
+		for _, record := range records {
+			if record.Id == id {
+				return true
+			}
+		}
+		return false
+	}
+	for i, tt := range tests {
+		id := document.Id(i + 1)
+		t.Run(tt.name, func(Test *testing.T) {
+			if listed(report.Overdue, id) != tt.overdue {
+				Test.Errorf("expected overdue=%t but got %+v", tt.overdue, report.Overdue)
+			}
+			if listed(report.Upcoming, id) != tt.upcoming {
+				Test.Errorf("expected upcoming=%t but got %+v", tt.upcoming, report.Upcoming)
+			}
+		})
+	}
+
+	t.Run("Persistence", func(Test *testing.T) {
+		reopened, err := Open(root, testConfig(Test))
+		if err != nil {
+			Test.Fatal(err)
+		}
+		if record, _ := reopened.GetRecord(3); !record.Due.Equal(tests[2].due) {
+			Test.Errorf("due date not persisted: %s", record.Due)
+		}
+	})
+
+	t.Run("Clearing", func(Test *testing.T) {
+		lib.SetDueDate(1, time.Time{})
+		if report, _ := lib.CollectDue(now, 0); len(report.Overdue) != 0 {
+			Test.Error("cleared due date still considered")
+		}
+		if err := lib.SetDueDate(99, now); err == nil {
+			Test.Error("due date set for unknown document")
+		}
+	})
+
+	t.Run("CertificatesWithoutExpiryCaughtUp", func(Test *testing.T) {
+		//GIVEN
+		notAfter := now.AddDate(0, 0, 20).Truncate(time.Second)
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		template := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: notAfter.AddDate(-1, 0, 0), NotAfter: notAfter}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		if err != nil {
+			Test.Fatal(err)
+		}
+		addTestFile(Test, lib, 9, "server.crt", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+		lib.PersistChanges()
+		stripFromDatabase(Test, lib.libFile, `,\s*"(Due": \d+|DueChecked": true)`) //as recorded before expiry detection was introduced
+		reopened, err := Open(root, testConfig(Test))
+		if err != nil {
+			Test.Fatal(err)
+		}
+		legacy := reopened.(*doccurator)
+		before, _ := legacy.GetRecord(9)
+
+		//WHEN
+		report, caughtUpCount := legacy.CollectDue(now, 30*24*time.Hour)
+		_, repeatedCount := legacy.CollectDue(now, 30*24*time.Hour)
+
+		//THEN
+		if caughtUpCount != 1 || repeatedCount != 0 {
+			Test.Errorf("expected the certificate to be caught up once, got %d and %d", caughtUpCount, repeatedCount)
+		}
+		if len(report.Upcoming) != 1 || report.Upcoming[0].Id != 9 || !report.Upcoming[0].Due.Equal(notAfter) {
+			Test.Errorf("expiry of certificate not listed: %+v", report.Upcoming)
+		}
+		if after, _ := legacy.GetRecord(9); !after.Changed.Equal(before.Changed) {
+			Test.Error("change date of record touched by catching up")
+		}
+		legacy.SetDueDate(9, time.Time{})
+		if report, _ := legacy.CollectDue(now, 30*24*time.Hour); len(report.Upcoming) != 0 {
+			Test.Error("cleared due date of certificate restored")
+		}
+	})
+}
@@ -0,0 +1,215 @@
+package doccurator
+
+import (
+	checksum "crypto/sha256"
+	"fmt"
+	"github.com/n2code/doccurator/internal"
+	"github.com/n2code/doccurator/internal/document"
+	"github.com/n2code/doccurator/internal/library"
+	out "github.com/n2code/doccurator/internal/output"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+func (d *doccurator) CollectDuplicates(preferenceRules []string) (report DuplicateReport) {
+	groups := make(map[[checksum.Size]byte]*DuplicateGroup)
+	addCopy := func(sha256 [checksum.Size]byte, size int64, duplicate DuplicateCopy) {
+		if size == 0 {
+			return //empty files are not considered duplicates of each other
+		}
+		group, known := groups[sha256]
+		if !known {
+			group = &DuplicateGroup{Sha256: sha256, Size: size}
+			groups[sha256] = group
+		}
+		group.Copies = append(group.Copies, duplicate)
+	}
+
+	results, _ := d.appLib.Scan(d.getScanSkipEvaluators(), nil, d.optimizedFsAccess) //full scan may optimize performance if allowed to
+	for _, checked := range results {
+		switch checked.Status() {
+		case library.Tracked, library.Touched:
+			doc := checked.ReferencedDocument()
+			size, _, sha256 := doc.RecordProperties()
+			addCopy(sha256, size, DuplicateCopy{Kind: TrackedCopy, Id: doc.Id(), AnchoredPath: checked.AnchoredPath()})
+		case library.Untracked, library.Duplicate, library.Obsolete, library.Modified, library.Moved, library.MovedModified:
+			//the content of files not in order is not (or not at this path) on record, hence it is read like untracked content
+			absolute := d.appLib.Absolutize(checked.AnchoredPath())
+			stat, err := os.Stat(absolute)
+			if err != nil {
+				d.Print(out.Error, "Skipping unreadable (%s): %s\n", d.displayablePath(absolute, true, false), err)
+				continue
+			}
+			sha256, err := internal.Sha256OfFile(absolute)
+			if err != nil {
+				d.Print(out.Error, "Skipping unreadable (%s): %s\n", d.displayablePath(absolute, true, false), err)
+				continue
+			}
+			addCopy(sha256, stat.Size(), DuplicateCopy{Kind: UntrackedCopy, AnchoredPath: checked.AnchoredPath()})
+		}
+	}
+	d.appLib.VisitAllRecords(func(doc library.Document) {
+		if doc.IsObsolete() {
+			size, _, sha256 := doc.RecordProperties()
+			addCopy(sha256, size, DuplicateCopy{Kind: RetiredCopy, Id: doc.Id(), AnchoredPath: doc.AnchoredPath()})
+		}
+	})
+
+	rank := func(duplicate DuplicateCopy) int {
+		for i, rule := range preferenceRules {
+			if matchesPreferenceRule(rule, duplicate.AnchoredPath) {
+				return i
+			}
+		}
+		return len(preferenceRules)
+	}
+	for _, group := range groups {
+		if len(group.Copies) < 2 {
+			continue
+		}
+		copies := group.Copies
+		sort.SliceStable(copies, func(i, j int) bool {
+			if retiredI, retiredJ := copies[i].Kind == RetiredCopy, copies[j].Kind == RetiredCopy; retiredI != retiredJ {
+				return retiredJ //retired records cannot be kept because no file is present
+			}
+			if rankI, rankJ := rank(copies[i]), rank(copies[j]); rankI != rankJ {
+				return rankI < rankJ //explicit preference overrides whether a copy is on record
+			}
+			if copies[i].Kind != copies[j].Kind {
+				return copies[i].Kind < copies[j].Kind //tracked before untracked
+			}
+			if lengthI, lengthJ := len(copies[i].AnchoredPath), len(copies[j].AnchoredPath); lengthI != lengthJ {
+				return lengthI < lengthJ //the shortest path is likely the most canonical location
+			}
+			return copies[i].AnchoredPath < copies[j].AnchoredPath
+		})
+		present := 0
+		for _, duplicate := range copies {
+			if duplicate.Kind != RetiredCopy {
+				present++
+			}
+		}
+		if present > 1 {
+			report.Wasted += int64(present-1) * group.Size
+		}
+		report.Groups = append(report.Groups, *group)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		return report.Groups[i].Copies[0].AnchoredPath < report.Groups[j].Copies[0].AnchoredPath
+	})
+	return
+}
+
+// matchesPreferenceRule checks the glob against the anchored path, each of its parent directories, and the filename
+func matchesPreferenceRule(rule string, anchored string) bool {
+	_, relative := document.SplitAnchoredPath(anchored)
+	candidates := []string{anchored, filepath.Base(relative)}
+	for directory := filepath.Dir(relative); directory != "." && directory != string(filepath.Separator); directory = filepath.Dir(directory) {
+		candidates = append(candidates, directory)
+	}
+	for _, candidate := range candidates {
+		if matches, _ := filepath.Match(rule, candidate); matches {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *doccurator) PrintDuplicates(report DuplicateReport) {
+	for _, group := range report.Groups {
+		wasted := int64(0)
+		for _, duplicate := range group.Copies[1:] {
+			if duplicate.Kind != RetiredCopy {
+				wasted += group.Size
+			}
+		}
+		d.Print(out.Normal, " Same content of %s (%d %s, %s wasted)\n", out.Filesize(group.Size), len(group.Copies), out.Plural(group.Copies, "copy", "copies"), out.Filesize(wasted))
+		for i, duplicate := range group.Copies {
+			marker := " "
+			if i == 0 && duplicate.Kind != RetiredCopy {
+				marker = "*"
+			}
+			d.Print(out.Required, "  %s %s\n", marker, describeDuplicateCopy(duplicate))
+		}
+		d.Print(out.Normal, "\n")
+	}
+	if len(report.Groups) == 0 {
+		d.Print(out.Normal, "No duplicates found.\n")
+		return
+	}
+	d.Print(out.Normal, "%s wasted in %d %s (* marks the preferred copy).\n", out.Filesize(report.Wasted), len(report.Groups), out.Plural(report.Groups, "group", "groups"))
+}
+
+func describeDuplicateCopy(duplicate DuplicateCopy) string {
+	switch duplicate.Kind {
+	case TrackedCopy:
+		return "[" + string(library.Tracked) + "] [" + duplicate.Id.String() + "] " + displayableAnchoredPath(duplicate.AnchoredPath)
+	case UntrackedCopy:
+		return "[" + string(library.Untracked) + "] " + displayableAnchoredPath(duplicate.AnchoredPath)
+	default:
+		return "[" + string(library.Removed) + "] [" + duplicate.Id.String() + "] " + displayableAnchoredPath(duplicate.AnchoredPath) + " (retired)"
+	}
+}
+
+func (d *doccurator) ResolveDuplicates(report DuplicateReport, deleteFiles bool) (resolvedCount int, err error) {
+	for _, group := range report.Groups {
+		if group.Copies[0].Kind == RetiredCopy {
+			continue //nothing present to keep
+		}
+		copies := group.Copies
+		if copies[0].Kind == UntrackedCopy {
+			if copies, err = d.moveRecordToPreferredCopy(copies); err != nil {
+				return resolvedCount, err
+			}
+		}
+		for _, duplicate := range copies[1:] {
+			if duplicate.Kind == RetiredCopy || duplicate.Kind == UntrackedCopy && !deleteFiles {
+				continue
+			}
+			if deleteFiles {
+				absolute := d.appLib.Absolutize(duplicate.AnchoredPath)
+				if err = d.stageFileDeletion(absolute); err != nil {
+					return resolvedCount, fmt.Errorf("deletion failed: %s: %w", displayableAnchoredPath(duplicate.AnchoredPath), err)
+				}
+				d.Print(out.Normal, "Marked for delete: %s\n", d.displayablePath(absolute, true, false))
+			}
+			if duplicate.Kind == TrackedCopy {
+				doc, _ := d.appLib.GetDocumentById(duplicate.Id)
+				d.appLib.MarkDocumentAsObsolete(doc)
+				d.Print(out.Normal, "Retired [%s] %s\n", duplicate.Id, displayableAnchoredPath(duplicate.AnchoredPath))
+			}
+			resolvedCount++
+		}
+	}
+	return
+}
+
+// moveRecordToPreferredCopy keeps the content on record if an untracked copy is preferred: the best ranked record of the
+// group is moved to the preferred copy, its previous file becomes an untracked copy. If the path of the preferred copy is
+// on record already (with different content) the record is excluded from the resolution instead.
+func (d *doccurator) moveRecordToPreferredCopy(copies []DuplicateCopy) ([]DuplicateCopy, error) {
+	for i, duplicate := range copies {
+		if duplicate.Kind != TrackedCopy {
+			continue
+		}
+		preferred := d.appLib.Absolutize(copies[0].AnchoredPath)
+		if _, occupied := d.appLib.GetActiveDocumentByPath(preferred); occupied {
+			d.Print(out.Normal, "Kept [%s] %s: preferred copy %s is not on record\n", duplicate.Id, displayableAnchoredPath(duplicate.AnchoredPath), displayableAnchoredPath(copies[0].AnchoredPath))
+			return append(append([]DuplicateCopy{}, copies[:i]...), copies[i+1:]...), nil
+		}
+		doc, _ := d.appLib.GetDocumentById(duplicate.Id)
+		if err := d.appLib.SetDocumentPath(doc, preferred); err != nil {
+			return nil, err
+		}
+		if _, err := d.appLib.UpdateDocumentFromFile(doc); err != nil { //the modification time of the copy may differ
+			return nil, fmt.Errorf("update failed: %s: %w", displayableAnchoredPath(copies[0].AnchoredPath), err)
+		}
+		d.Print(out.Normal, "Moved record [%s] %s -> %s\n", duplicate.Id, displayableAnchoredPath(duplicate.AnchoredPath), displayableAnchoredPath(copies[0].AnchoredPath))
+		moved := append([]DuplicateCopy{}, copies...)
+		moved[0] = DuplicateCopy{Kind: TrackedCopy, Id: duplicate.Id, AnchoredPath: copies[0].AnchoredPath}
+		moved[i] = DuplicateCopy{Kind: UntrackedCopy, AnchoredPath: duplicate.AnchoredPath}
+		return moved, nil
+	}
+	return copies, nil //no record to keep
+}
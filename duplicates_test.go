@@ -0,0 +1,191 @@
+package doccurator
+
+import (
+	"github.com/n2code/doccurator/internal/document"
+	"github.com/n2code/doccurator/internal/library"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDuplicates(t *testing.T) {
+	setup := func(t *testing.T) (root string, lib *doccurator) {
+		root, lib = newTestLibrary(t, map[document.Id]string{4: "unique.txt"})
+		for id, name := range []string{"inbox/bill.pdf", "archive/2021_bill.pdf", "old/bill.pdf"} {
+			addTestFile(t, lib, document.Id(id+1), filepath.FromSlash(name), "bill")
+		}
+		lib.RetireByPath(filepath.Join(root, "old/bill.pdf"))
+		os.Remove(filepath.Join(root, "old/bill.pdf"))
+		writeTestFile(t, root, "copy.pdf", "bill")
+		lib.PersistChanges()
+		return
+	}
+
+	t.Run("Report", func(Test *testing.T) {
+		//GIVEN
+		_, lib := setup(Test)
+
+		//WHEN
+		report := lib.CollectDuplicates([]string{"archive"})
+
+		//THEN
+		if len(report.Groups) != 1 {
+			Test.Fatalf("expected one group but got %d", len(report.Groups))
+		}
+		expected := []DuplicateCopy{
+			{Kind: TrackedCopy, Id: 2, AnchoredPath: filepath.FromSlash("archive/2021_bill.pdf")},
+			{Kind: TrackedCopy, Id: 1, AnchoredPath: filepath.FromSlash("inbox/bill.pdf")},
+			{Kind: UntrackedCopy, AnchoredPath: "copy.pdf"},
+			{Kind: RetiredCopy, Id: 3, AnchoredPath: filepath.FromSlash("old/bill.pdf")},
+		}
+		if copies := report.Groups[0].Copies; len(copies) != len(expected) {
+			Test.Fatalf("expected %d copies but got %v", len(expected), copies)
+		}
+		for i, duplicate := range report.Groups[0].Copies {
+			if duplicate != expected[i] {
+				Test.Errorf("expected copy %d to be %v but got %v", i, expected[i], duplicate)
+			}
+		}
+		if report.Wasted != 2*int64(len("bill")) {
+			Test.Errorf("unexpected waste of %d bytes", report.Wasted)
+		}
+	})
+
+	t.Run("PreferenceOverridesRecord", func(Test *testing.T) {
+		//GIVEN
+		root, lib := setup(Test)
+
+		//WHEN
+		report := lib.CollectDuplicates([]string{"copy.pdf"})
+		resolvedCount, err := lib.ResolveDuplicates(report, true)
+
+		//THEN
+		if preferred := report.Groups[0].Copies[0]; preferred != (DuplicateCopy{Kind: UntrackedCopy, AnchoredPath: "copy.pdf"}) {
+			Test.Fatalf("expected untracked copy to be preferred but got %v", preferred)
+		}
+		if err != nil || resolvedCount != 2 {
+			Test.Fatalf("expected two resolutions but got %d (%v)", resolvedCount, err)
+		}
+		if _, statErr := os.Stat(filepath.Join(root, "copy.pdf")); statErr != nil {
+			Test.Error("preferred untracked copy deleted")
+		}
+		for _, name := range []string{"inbox/bill.pdf", "archive/2021_bill.pdf"} {
+			if _, statErr := os.Stat(filepath.Join(root, name)); statErr == nil {
+				Test.Errorf("surplus copy %s not deleted", name)
+			}
+		}
+		if record, _ := lib.GetRecord(1); record.Retired || record.AnchoredPath != "copy.pdf" {
+			Test.Errorf("best ranked record not moved to preferred copy: %+v", record)
+		}
+		if status := lib.GetStatus([]string{filepath.Join(root, "copy.pdf")}); len(status) != 1 || status[0].Status != library.Tracked {
+			Test.Errorf("preferred copy not on record: %+v", status)
+		}
+		if doc, _ := lib.appLib.GetDocumentById(2); !doc.IsObsolete() {
+			Test.Error("record of surplus copy not retired")
+		}
+	})
+
+	t.Run("PreferredPathOnRecordWithOtherContent", func(Test *testing.T) {
+		//GIVEN
+		root, lib := setup(Test)
+		writeTestFile(Test, root, "unique.txt", "bill") //modified, i.e. the path is on record with other content
+
+		//WHEN
+		report := lib.CollectDuplicates([]string{"unique.txt"})
+		resolvedCount, err := lib.ResolveDuplicates(report, true)
+
+		//THEN
+		if err != nil || resolvedCount != 2 {
+			Test.Fatalf("expected two resolutions but got %d (%v)", resolvedCount, err)
+		}
+		if _, statErr := os.Stat(filepath.Join(root, "inbox", "bill.pdf")); statErr != nil {
+			Test.Error("file of best ranked record deleted")
+		}
+		if doc, _ := lib.appLib.GetDocumentById(1); doc.IsObsolete() {
+			Test.Error("best ranked record retired")
+		}
+		if doc, _ := lib.appLib.GetDocumentById(2); !doc.IsObsolete() {
+			Test.Error("record of surplus copy not retired")
+		}
+	})
+
+	t.Run("FilesNotInOrderCounted", func(Test *testing.T) {
+		//GIVEN
+		root, lib := setup(Test)
+		os.Rename(filepath.Join(root, "inbox", "bill.pdf"), filepath.Join(root, "moved.pdf"))
+		writeTestFile(Test, root, "unique.txt", "bill")
+
+		//WHEN
+		report := lib.CollectDuplicates(nil)
+
+		//THEN
+		if len(report.Groups) != 1 {
+			Test.Fatalf("expected one group but got %d", len(report.Groups))
+		}
+		present := 0
+		for _, duplicate := range report.Groups[0].Copies {
+			switch duplicate.AnchoredPath {
+			case "moved.pdf", "unique.txt":
+				if duplicate.Kind != UntrackedCopy {
+					Test.Errorf("expected %s to be an untracked copy but got %v", duplicate.AnchoredPath, duplicate)
+				}
+			}
+			if duplicate.Kind != RetiredCopy {
+				present++
+			}
+		}
+		if present != 4 {
+			Test.Errorf("expected moved and modified files among the copies but got %v", report.Groups[0].Copies)
+		}
+		if report.Wasted != 3*int64(len("bill")) {
+			Test.Errorf("unexpected waste of %d bytes", report.Wasted)
+		}
+	})
+
+	t.Run("Retire", func(Test *testing.T) {
+		//GIVEN
+		root, lib := setup(Test)
+
+		//WHEN
+		resolvedCount, err := lib.ResolveDuplicates(lib.CollectDuplicates(nil), false)
+
+		//THEN
+		if err != nil || resolvedCount != 1 {
+			Test.Fatalf("expected one resolution but got %d (%v)", resolvedCount, err)
+		}
+		if doc, _ := lib.appLib.GetDocumentById(2); !doc.IsObsolete() {
+			Test.Error("longer path not retired")
+		}
+		if doc, _ := lib.appLib.GetDocumentById(1); doc.IsObsolete() {
+			Test.Error("preferred copy retired")
+		}
+		if _, statErr := os.Stat(filepath.Join(root, "copy.pdf")); statErr != nil {
+			Test.Error("untracked copy touched")
+		}
+	})
+
+	t.Run("Delete", func(Test *testing.T) {
+		//GIVEN
+		root, lib := setup(Test)
+
+		//WHEN
+		resolvedCount, err := lib.ResolveDuplicates(lib.CollectDuplicates([]string{"*_bill.pdf"}), true)
+		lib.PersistChanges()
+
+		//THEN
+		if err != nil || resolvedCount != 2 {
+			Test.Fatalf("expected two resolutions but got %d (%v)", resolvedCount, err)
+		}
+		for _, name := range []string{"inbox/bill.pdf", "copy.pdf"} {
+			if _, statErr := os.Stat(filepath.Join(root, name)); statErr == nil {
+				Test.Errorf("surplus copy %s not deleted", name)
+			}
+		}
+		if doc, _ := lib.appLib.GetDocumentById(1); !doc.IsObsolete() {
+			Test.Error("record of deleted file not retired")
+		}
+		if _, statErr := os.Stat(filepath.Join(root, "archive", "2021_bill.pdf")); statErr != nil {
+			Test.Error("preferred copy deleted")
+		}
+	})
+}
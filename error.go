@@ -5,3 +5,4 @@ import (
 )
 
 var recordEmptyContentError = errors.New("content to record is empty")
+var idRangeExhaustedError = errors.New("ID range exhausted, no free ID left (see ID allocation of library identity)")
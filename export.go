@@ -0,0 +1,257 @@
+package doccurator
+
+import (
+	"bytes"
+	checksum "crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/n2code/doccurator/internal"
+	"github.com/n2code/doccurator/internal/document"
+	"github.com/n2code/doccurator/internal/library"
+	out "github.com/n2code/doccurator/internal/output"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExportManifestFileName is the name of the manifest file which Export places in the destination directory.
+const ExportManifestFileName = "doccurator-export.json"
+const exportPartialSuffix = ".partial"
+
+var errDiffersFromRecord = errors.New("file differs from record")
+
+// jsonManifest lists the documents of an export or a pack
+type jsonManifest struct {
+	Library   string
+	Name      string `json:",omitempty"`
+	Created   time.Time
+	Documents []jsonManifestDocument
+}
+
+type jsonManifestDocument struct {
+	Id       string
+	Path     string //relative to the export directory or archive root, slash-separated
+	Source   string //anchored path in the library
+	Size     int64
+	Sha256   string
+	Recorded time.Time
+	Changed  time.Time
+	Modified time.Time
+}
+
+func makeManifestDocument(record Record, path string) jsonManifestDocument {
+	return jsonManifestDocument{
+		Id:       record.Id.String(),
+		Path:     filepath.ToSlash(path),
+		Source:   displayableAnchoredPath(record.AnchoredPath),
+		Size:     record.Size,
+		Sha256:   hex.EncodeToString(record.Sha256[:]),
+		Recorded: record.Recorded.UTC(),
+		Changed:  record.Changed.UTC(),
+		Modified: record.Modified.UTC(),
+	}
+}
+
+func (d *doccurator) Export(destination string, options ExportOptions) (report ExportReport, err error) {
+	absoluteDest := mustAbsFilepath(destination)
+	libraryDirs := []string{d.appLib.GetRoot(), filepath.Dir(d.libFile)}
+	for _, rootDir := range d.appLib.GetNamedRoots() {
+		libraryDirs = append(libraryDirs, rootDir)
+	}
+	for _, rootDir := range libraryDirs {
+		if absoluteDest == rootDir || isChildOf(absoluteDest, rootDir) || isChildOf(rootDir, absoluteDest) {
+			return report, fmt.Errorf("export destination %s overlaps with library directory %s", absoluteDest, rootDir)
+		}
+	}
+	manifestFile := filepath.Join(absoluteDest, ExportManifestFileName)
+	manifest, err := readExportManifest(manifestFile)
+	if err != nil {
+		return report, err
+	}
+	if manifest.Library != "" && !options.Resume {
+		return report, fmt.Errorf("destination %s contains an export already, resume it or choose another destination", absoluteDest)
+	}
+	if manifest.Library != "" && manifest.Library != d.appLib.GetUuid() {
+		return report, fmt.Errorf("export in %s belongs to a different library (%s)", absoluteDest, manifest.Library)
+	}
+	selected := d.selectActiveRecords(options.RecordSelection)
+	if len(selected) == 0 {
+		return report, errors.New("no active records selected for export")
+	}
+	if err = os.MkdirAll(absoluteDest, 0o755); err != nil {
+		return report, fmt.Errorf("export destination not writable: %w", err)
+	}
+
+	report.Destination = absoluteDest
+	report.Failed = make(map[document.Id]error)
+	exported := make(map[string]jsonManifestDocument, len(manifest.Documents))
+	for _, entry := range manifest.Documents {
+		exported[entry.Id] = entry
+	}
+	for _, doc := range selected {
+		record := makeRecord(doc)
+		relativeTarget := exportedPathOf(doc, options.Flat)
+		target := filepath.Join(absoluteDest, relativeTarget)
+		copied, copyErr := d.exportDocument(record, target, options.Resume)
+		if copyErr != nil {
+			report.Failed[record.Id] = copyErr
+			d.Print(out.Error, "  ! failed   [%s] %s: %s\n", record.Id, displayableAnchoredPath(record.AnchoredPath), copyErr)
+			continue
+		}
+		if copied {
+			report.Copied++
+			report.Bytes += record.Size
+			d.Print(out.Normal, "  + exported [%s] %s\n", record.Id, relativeTarget)
+		} else {
+			report.Present++
+			d.Print(out.Verbose, "  = present  [%s] %s\n", record.Id, relativeTarget)
+		}
+		exported[record.Id.String()] = makeManifestDocument(record, relativeTarget)
+	}
+
+	manifest = jsonManifest{Library: d.appLib.GetUuid(), Name: d.appLib.GetName(), Created: time.Now().UTC()}
+	for _, entry := range exported {
+		manifest.Documents = append(manifest.Documents, entry)
+	}
+	sort.Slice(manifest.Documents, func(i, j int) bool { return manifest.Documents[i].Path < manifest.Documents[j].Path })
+	if err = writeExportManifest(manifestFile, manifest); err != nil {
+		return report, fmt.Errorf("export manifest not written: %w", err)
+	}
+	report.Manifest = manifestFile
+	return report, nil
+}
+
+// exportDocument copies the file of the given record to the target and verifies the written copy.
+// If resuming an existing target is kept if its content matches the record.
+func (d *doccurator) exportDocument(record Record, target string, resume bool) (copied bool, err error) {
+	if _, statErr := os.Stat(target); statErr == nil {
+		if !resume {
+			return false, fmt.Errorf("target %s exists already", target)
+		}
+		if sum, hashErr := internal.Sha256OfFile(target); hashErr == nil && sum == record.Sha256 {
+			return false, nil
+		}
+	}
+	if err = os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return false, err
+	}
+	if err = copyVerifiedFile(d.appLib.Absolutize(record.AnchoredPath), target, record); errors.Is(err, errDiffersFromRecord) {
+		return false, fmt.Errorf("%w, update it first", err)
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// copyVerifiedFile streams the source file into a partial file next to the target which is renamed to the target
+// once its content is verified against the record. The copy keeps the permission bits of the source and gets the
+// recorded modification time. The partial file is removed if anything fails.
+func copyVerifiedFile(source string, target string, record Record) (err error) {
+	input, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	stat, err := input.Stat()
+	if err != nil {
+		return err
+	}
+	partial := target + exportPartialSuffix
+	os.Remove(partial) //left over by an interrupted run, possibly read-only
+	output, err := os.OpenFile(partial, os.O_WRONLY|os.O_CREATE|os.O_EXCL, stat.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(partial)
+		}
+	}()
+	hash := checksum.New()
+	size, err := io.Copy(io.MultiWriter(output, hash), input)
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if size != record.Size || !bytes.Equal(hash.Sum(nil), record.Sha256[:]) {
+		return errDiffersFromRecord
+	}
+	if sum, hashErr := internal.Sha256OfFile(partial); hashErr != nil || sum != record.Sha256 {
+		return fmt.Errorf("verification of copy failed (%v)", hashErr)
+	}
+	if err = os.Chmod(partial, stat.Mode().Perm()); err != nil { //not subject to the umask, unlike creation
+		return err
+	}
+	if err = os.Chtimes(partial, record.Modified, record.Modified); err != nil {
+		return err
+	}
+	return os.Rename(partial, target)
+}
+
+// selectActiveRecords yields the active records matching the given selection
+func (d *doccurator) selectActiveRecords(selection RecordSelection) (selected []library.Document) {
+	absoluteSubtree := ""
+	if selection.Subtree != "" {
+		absoluteSubtree = mustAbsFilepath(selection.Subtree)
+	}
+	d.appLib.VisitAllRecords(func(doc library.Document) {
+		if doc.IsObsolete() || !strings.Contains(doc.Id().String(), strings.ToUpper(selection.IdPart)) {
+			return
+		}
+		absolute := d.appLib.Absolutize(doc.AnchoredPath())
+		if absoluteSubtree != "" && absolute != absoluteSubtree && !isChildOf(absolute, absoluteSubtree) {
+			return
+		}
+		selected = append(selected, doc)
+	})
+	return
+}
+
+// exportedPathOf yields the path of the document inside the export directory: either its path relative to its library root
+// (inside a directory named after the root for additional roots) or just its standardized filename
+func exportedPathOf(doc library.Document, flat bool) string {
+	if flat {
+		return doc.StandardizedFilename()
+	}
+	root, relative := document.SplitAnchoredPath(doc.AnchoredPath())
+	return filepath.Join(root, relative)
+}
+
+func readExportManifest(manifestFile string) (manifest jsonManifest, err error) {
+	content, err := os.ReadFile(manifestFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	} else if err != nil {
+		return manifest, fmt.Errorf("export manifest unreadable: %w", err)
+	}
+	if err = json.Unmarshal(content, &manifest); err != nil {
+		return manifest, fmt.Errorf("export manifest (%s) corrupt: %w", manifestFile, err)
+	}
+	return manifest, nil
+}
+
+func writeExportManifest(manifestFile string, manifest jsonManifest) error {
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(manifestFile, content, 0o644)
+}
+
+func (d *doccurator) PrintExportReport(report ExportReport) {
+	d.Print(out.Normal, "\n%d %s exported (%s) to %s, %d already present.\n",
+		report.Copied, out.Plural(report.Copied, "document", "documents"), out.Filesize(report.Bytes), report.Destination, report.Present)
+	if len(report.Failed) > 0 {
+		d.Print(out.Error, "%d %s failed, export can be resumed after fixing the cause.\n", len(report.Failed), out.Plural(len(report.Failed), "document", "documents"))
+	}
+	if report.Manifest != "" {
+		d.Print(out.Verbose, "Manifest: %s\n", report.Manifest)
+	}
+}
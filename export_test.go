@@ -0,0 +1,96 @@
+package doccurator
+
+import (
+	"encoding/json"
+	"github.com/n2code/doccurator/internal/document"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	//GIVEN
+	root, lib := newTestLibrary(t, map[document.Id]string{1: "top", 2: filepath.Join("sub", "nested.txt"), 3: "retired"})
+	lib.RetireByPath(filepath.Join(root, "retired"))
+	lib.PersistChanges()
+	destination := filepath.Join(t.TempDir(), "export")
+
+	//WHEN
+	report, err := lib.Export(destination, ExportOptions{})
+
+	//THEN
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Copied != 2 || len(report.Failed) != 0 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if content, _ := os.ReadFile(filepath.Join(destination, "sub", "nested.txt")); string(content) != filepath.Join("sub", "nested.txt") {
+		t.Error("layout of library not kept")
+	}
+	if _, err := os.Stat(filepath.Join(destination, "retired")); err == nil {
+		t.Error("retired record exported")
+	}
+	var manifest jsonManifest
+	content, _ := os.ReadFile(filepath.Join(destination, ExportManifestFileName))
+	if err := json.Unmarshal(content, &manifest); err != nil || len(manifest.Documents) != 2 || manifest.Library != lib.appLib.GetUuid() {
+		t.Errorf("bad manifest (%v): %s", err, content)
+	}
+
+	t.Run("RepeatedExportRequiresResume", func(Test *testing.T) {
+		if _, err := lib.Export(destination, ExportOptions{}); err == nil {
+			Test.Error("existing export overwritten")
+		}
+	})
+
+	t.Run("ResumeReplacesDamagedCopies", func(Test *testing.T) {
+		os.WriteFile(filepath.Join(destination, "top"), []byte("damaged"), 0o600)
+		report, err := lib.Export(destination, ExportOptions{Resume: true})
+		if err != nil {
+			Test.Fatal(err)
+		}
+		if report.Copied != 1 || report.Present != 1 {
+			Test.Errorf("unexpected report: %+v", report)
+		}
+		if content, _ := os.ReadFile(filepath.Join(destination, "top")); string(content) != "top" {
+			Test.Error("damaged copy not replaced")
+		}
+	})
+
+	t.Run("FlatSubtree", func(Test *testing.T) {
+		flat := Test.TempDir()
+		report, err := lib.Export(flat, ExportOptions{RecordSelection: RecordSelection{Subtree: filepath.Join(root, "sub")}, Flat: true})
+		if err != nil {
+			Test.Fatal(err)
+		}
+		if report.Copied != 1 {
+			Test.Errorf("unexpected report: %+v", report)
+		}
+		doc, _ := lib.appLib.GetDocumentById(2)
+		if _, err := os.Stat(filepath.Join(flat, doc.StandardizedFilename())); err != nil {
+			Test.Error("copy with standardized name missing")
+		}
+	})
+
+	t.Run("PermissionsKept", func(Test *testing.T) {
+		kept := Test.TempDir()
+		os.Chmod(filepath.Join(root, "top"), 0o600)
+		defer os.Chmod(filepath.Join(root, "top"), 0o644)
+		os.WriteFile(filepath.Join(kept, "top"+exportPartialSuffix), []byte("interrupted"), 0o444)
+		if _, err := lib.Export(kept, ExportOptions{}); err != nil {
+			Test.Fatal(err)
+		}
+		if stat, err := os.Stat(filepath.Join(kept, "top")); err != nil || stat.Mode().Perm() != 0o600 {
+			Test.Errorf("permissions of source not kept (%v)", err)
+		}
+		if _, err := os.Stat(filepath.Join(kept, "top"+exportPartialSuffix)); err == nil {
+			Test.Error("partial file left over")
+		}
+	})
+
+	t.Run("DestinationInsideLibrary", func(Test *testing.T) {
+		if _, err := lib.Export(filepath.Join(root, "export"), ExportOptions{}); err == nil {
+			Test.Error("export into library accepted")
+		}
+	})
+}
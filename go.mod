@@ -5,7 +5,6 @@ go 1.20
 require (
 	github.com/disiqueira/gotree/v3 v3.0.2
 	github.com/n2code/ndocid v1.0.1
+	golang.org/x/sys v0.12.0
 	golang.org/x/term v0.12.0
 )
-
-require golang.org/x/sys v0.12.0 // indirect
@@ -17,6 +17,7 @@ type HandleConfig struct {
 	Optimization          OptimizationLevel //performance-vs-thoroughness
 	SuppressTerminalCodes bool              //do use fancy terminal formatting options such as ANSI escape sequences to add color
 	IncludeAllNamesInScan bool              //if set all names are considered in directory scans (i.e. hidden files/folders starting with "." will be included)
+	RegistryFile          string            //registry of known libraries to be updated, the default location in the user configuration directory is used if empty
 }
 
 const (
@@ -30,20 +31,32 @@ const (
 	ThoroughMode                                  //sacrifices performance to avoid any possible oversights
 )
 
+// RootMapping determines how the library root directory is recorded in the library database.
+// The zero value records an absolute path that applies to all hosts.
+type RootMapping struct {
+	RelativeToDatabase bool   //record the root relative to the database file so the library keeps working if both are moved together (e.g. on a removable drive)
+	Host               string //if set the root only applies to the host with this name and overrides the default root there
+}
+
 // New creates a new doccurator library rooted at the given root directory.
 // The library database file does not need to be located inside the root directory.
-// However, its path must not be changed after creation.
-func New(root string, database string, config HandleConfig) (Doccurator, error) {
+// However, its path must not be changed after creation other than by RelocateDatabase.
+// Host-specific root mappings are only supported for existing libraries (see Move).
+func New(root string, database string, mapping RootMapping, config HandleConfig) (Doccurator, error) {
+	if mapping.Host != "" {
+		return nil, fmt.Errorf("library create error: host-specific root requires existing library")
+	}
 	handle := makeDoccurator(config)
 	absoluteRoot := mustAbsFilepath(root)
 
-	err := handle.createLibrary(absoluteRoot, mustAbsFilepath(database))
+	err := handle.createLibrary(absoluteRoot, mustAbsFilepath(database), mapping.RelativeToDatabase)
 	if err != nil {
 		return nil, fmt.Errorf("library create error: %w", err)
 	}
 	handle.Print(output.Normal, "Initialized library with root %s\n", absoluteRoot)
+	handle.register()
 
-	if err := handle.createLocatorFile(absoluteRoot, false); err != nil {
+	if err := handle.createLocatorFile(absoluteRoot, mapping.RelativeToDatabase, false); err != nil {
 		return handle, err //the handle is usable regardless whether locator creation failed
 	}
 
@@ -55,13 +68,11 @@ func New(root string, database string, config HandleConfig) (Doccurator, error)
 func Open(directory string, config HandleConfig) (Doccurator, error) {
 	handle := makeDoccurator(config)
 
-	err := handle.discoverLibraryFile(mustAbsFilepath(directory))
+	err := handle.discoverLibrary(mustAbsFilepath(directory))
 	if err != nil {
 		return nil, fmt.Errorf("library discovery error: %w", err)
 	}
 
-	handle.loadLibrary()
-
 	root := handle.appLib.GetRoot()
 	stat, statErr := os.Stat(root)
 	if statErr != nil {
@@ -69,6 +80,7 @@ func Open(directory string, config HandleConfig) (Doccurator, error) {
 	} else if !stat.Mode().IsDir() {
 		return nil, fmt.Errorf("library open error: root %s is not a directory", root)
 	}
+	handle.register()
 
 	return handle, nil
 }
@@ -76,20 +88,25 @@ func Open(directory string, config HandleConfig) (Doccurator, error) {
 // Move updates the doccurator library root without touching the persisted document paths.
 // (If the entire library root directory has moved no changes will be detected afterward.
 // If the root is set to a parent directory all documents will be considered moved.)
-func Move(newRoot string, database string, config HandleConfig) error {
+// If the mapping names a host the default root is kept and only overridden on that host.
+func Move(newRoot string, database string, mapping RootMapping, config HandleConfig) error {
 	handle := makeDoccurator(config)
 	handle.libFile = mustAbsFilepath(database)
 	handle.loadLibrary()
 
 	absNewRoot := mustAbsFilepath(newRoot)
-	handle.appLib.SetRoot(absNewRoot)
+	handle.appLib.MapRoot(absNewRoot, mapping.RelativeToDatabase, mapping.Host)
 
 	if err := handle.PersistChanges(); err != nil {
 		return err
 	}
-	handle.Print(output.Normal, "Re-Initialized library with root %s\n", absNewRoot)
+	if mapping.Host != "" {
+		handle.Print(output.Normal, "Re-Initialized library with root %s on host %s\n", absNewRoot, mapping.Host)
+	} else {
+		handle.Print(output.Normal, "Re-Initialized library with root %s\n", absNewRoot)
+	}
 
-	if err := handle.createLocatorFile(absNewRoot, true); err != nil {
+	if err := handle.createLocatorFile(absNewRoot, mapping.RelativeToDatabase, true); err != nil {
 		return err
 	}
 
@@ -99,11 +116,14 @@ func Move(newRoot string, database string, config HandleConfig) error {
 type doccurator struct {
 	appLib                library.Api
 	rollbackLog           []rollbackStep //series of steps to be executed in reverse order, errors shall be reported but not stop rollback execution
+	deletionCommitQueue   []func() error //finalizes staged file deletions, executed after the library has been persisted
+	registrationQueue     []*doccurator  //libraries split off by uncommitted changes, registered after the library has been persisted
 	libFile               string         //absolute, system-native path
 	optimizedFsAccess     bool
 	printer               output.Printer
 	fancyTerminalFeatures bool
 	scanAll               bool
+	registryFile          string //absolute, system-native path, resolved on first use if empty
 }
 
 func makeDoccurator(config HandleConfig) (instance *doccurator) {
@@ -121,6 +141,9 @@ func makeDoccurator(config HandleConfig) (instance *doccurator) {
 	instance.printer = output.NewPrinter(classes, !config.SuppressTerminalCodes)
 	instance.optimizedFsAccess = config.Optimization == DefaultOptimizations
 	instance.scanAll = config.IncludeAllNamesInScan
+	if config.RegistryFile != "" {
+		instance.registryFile = mustAbsFilepath(config.RegistryFile)
+	}
 	return
 }
 
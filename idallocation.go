@@ -0,0 +1,115 @@
+package doccurator
+
+import (
+	"fmt"
+	"github.com/n2code/doccurator/internal"
+	"github.com/n2code/doccurator/internal/document"
+	"github.com/n2code/doccurator/internal/library"
+	out "github.com/n2code/doccurator/internal/output"
+	"math/rand"
+	"os"
+)
+
+// idAllocator proposes IDs (without namespace) for a new document in order of preference until told to stop
+type idAllocator interface {
+	// propose calls accept with each candidate until it returns true, the path of the file to be added may be empty
+	propose(path string, accept func(candidate uint32) bool)
+}
+
+// countdownAllocator proposes all IDs from a start downwards
+type countdownAllocator struct {
+	start func(path string) uint32
+}
+
+func (a countdownAllocator) propose(path string, accept func(candidate uint32) bool) {
+	for candidate := a.start(path); candidate > 0; candidate-- {
+		if accept(candidate) {
+			return
+		}
+	}
+}
+
+// rangeAllocator proposes all IDs inside the range exactly once, beginning at the given offset and wrapping around at the end
+type rangeAllocator struct {
+	first, last uint32
+	offset      func(size uint64) uint64
+}
+
+func (a rangeAllocator) propose(_ string, accept func(candidate uint32) bool) {
+	size := uint64(a.last-a.first) + 1
+	offset := a.offset(size)
+	for i := uint64(0); i < size; i++ {
+		if accept(a.first + uint32((offset+i)%size)) {
+			return
+		}
+	}
+}
+
+func currentTime(string) uint32 {
+	return uint32(internal.UnixTimestampNow())
+}
+
+// modificationTime falls back to the current time if the file is inaccessible
+func modificationTime(path string) uint32 {
+	if path != "" {
+		if stat, err := os.Stat(path); err == nil && stat.ModTime().Unix() > 0 {
+			return uint32(stat.ModTime().Unix())
+		}
+	}
+	return currentTime(path)
+}
+
+func makeIdAllocator(allocation library.IdAllocation) idAllocator {
+	switch allocation.Strategy {
+	case library.AllocateByModificationTime:
+		return countdownAllocator{start: modificationTime}
+	case library.AllocateRandomly:
+		return rangeAllocator{first: allocation.First, last: allocation.Last, offset: func(size uint64) uint64 { return uint64(rand.Int63n(int64(size))) }}
+	case library.AllocateFromBlock:
+		return rangeAllocator{first: allocation.First, last: allocation.Last, offset: func(uint64) uint64 { return 0 }}
+	default:
+		return countdownAllocator{start: currentTime}
+	}
+}
+
+func (d *doccurator) GetFreeId() (document.Id, error) {
+	return d.GetFreeIdFor("")
+}
+
+func (d *doccurator) GetFreeIdFor(path string) (document.Id, error) {
+	namespace := d.appLib.GetIdNamespace()
+	free := document.MissingId
+	makeIdAllocator(d.appLib.GetIdAllocation()).propose(path, func(candidate uint32) bool {
+		id := document.Id(candidate).WithNamespace(namespace)
+		if _, exists := d.appLib.GetDocumentById(id); exists {
+			return false
+		}
+		free = id
+		return true
+	})
+	if free == document.MissingId {
+		return free, idRangeExhaustedError
+	}
+	return free, nil
+}
+
+func (d *doccurator) SetIdAllocation(allocation library.IdAllocation) error {
+	if err := d.appLib.SetIdAllocation(allocation); err != nil {
+		return err
+	}
+	d.Print(out.Normal, "ID allocation set to %s\n", describeIdAllocation(allocation))
+	return nil
+}
+
+func describeIdAllocation(allocation library.IdAllocation) string {
+	switch allocation.Strategy {
+	case library.AllocateByModificationTime:
+		return "mtime (counting down from the modification time of the file)"
+	case library.AllocateRandomly:
+		return fmt.Sprintf("random (between %d and %d)", allocation.First, allocation.Last)
+	case library.AllocateFromBlock:
+		return fmt.Sprintf("block (counting up from %d to %d)", allocation.First, allocation.Last)
+	default:
+		return "time (counting down from the current time)"
+	}
+}
@@ -0,0 +1,103 @@
+package doccurator
+
+import (
+	"errors"
+	"github.com/n2code/doccurator/internal/document"
+	"github.com/n2code/doccurator/internal/library"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIdAllocation(t *testing.T) {
+	//GIVEN
+	root, lib := newTestLibrary(t, map[document.Id]string{100: "first", 101: "second", 102: "third"})
+	modified := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	os.WriteFile(filepath.Join(root, "old"), []byte("old"), 0o600)
+	os.Chtimes(filepath.Join(root, "old"), modified, modified)
+
+	t.Run("DefaultIsCurrentTime", func(Test *testing.T) {
+		if id, _ := lib.GetFreeId(); id < document.Id(time.Now().Add(-time.Minute).Unix()) {
+			Test.Errorf("ID %d not derived from current time", id)
+		}
+	})
+
+	t.Run("ModificationTime", func(Test *testing.T) {
+		lib.SetIdAllocation(library.IdAllocation{Strategy: library.AllocateByModificationTime})
+		if id, _ := lib.GetFreeIdFor(filepath.Join(root, "old")); id != document.Id(modified.Unix()) {
+			Test.Errorf("ID %d not derived from modification time", id)
+		}
+		if id, _ := lib.GetFreeIdFor(""); id < document.Id(time.Now().Add(-time.Minute).Unix()) {
+			Test.Errorf("ID %d without file not derived from current time", id)
+		}
+	})
+
+	t.Run("BlockSkipsTakenIds", func(Test *testing.T) {
+		lib.SetIdAllocation(library.IdAllocation{Strategy: library.AllocateFromBlock, First: 100, Last: 103})
+		if id, _ := lib.GetFreeId(); id != 103 {
+			Test.Errorf("expected first free ID of block but got %d", id)
+		}
+		lib.SetIdAllocation(library.IdAllocation{Strategy: library.AllocateFromBlock, First: 100, Last: 102})
+		if id, err := lib.GetFreeId(); !errors.Is(err, idRangeExhaustedError) || id != document.MissingId {
+			Test.Errorf("exhausted block yielded ID %d (%v)", id, err)
+		}
+	})
+
+	t.Run("RandomInsideRange", func(Test *testing.T) {
+		lib.SetIdAllocation(library.IdAllocation{Strategy: library.AllocateRandomly, First: 100, Last: 110})
+		for i := 0; i < 20; i++ {
+			if id, _ := lib.GetFreeId(); id < 103 || id > 110 {
+				Test.Errorf("ID %d outside of range or taken", id)
+			}
+		}
+	})
+
+	t.Run("Namespace", func(Test *testing.T) {
+		lib.SetIdNamespace(7)
+		lib.SetIdAllocation(library.IdAllocation{Strategy: library.AllocateFromBlock, First: 100, Last: 102})
+		if id, _ := lib.GetFreeId(); id != document.Id(100).WithNamespace(7) {
+			Test.Errorf("expected namespaced ID but got %d", id)
+		}
+	})
+
+	t.Run("InvalidRange", func(Test *testing.T) {
+		if err := lib.SetIdAllocation(library.IdAllocation{Strategy: library.AllocateRandomly, First: 5, Last: 4}); err == nil {
+			Test.Error("inverted range accepted")
+		}
+		if err := lib.SetIdAllocation(library.IdAllocation{Strategy: library.AllocateByCurrentTime, First: 5, Last: 6}); err == nil {
+			Test.Error("range accepted for time-based allocation")
+		}
+	})
+}
+
+func TestForeignIdNamespace(t *testing.T) {
+	//GIVEN
+	root, lib := newTestLibrary(t, nil)
+	addTestFile(t, lib, document.Id(5).WithNamespace(3), "own", "own")
+	lib.SetIdNamespace(7)
+	tests := []struct {
+		name    string
+		file    string
+		id      document.Id
+		foreign bool
+	}{
+		{name: "LegacyNamespaceIsNeverForeign", file: "legacy.txt", id: 12345},
+		{name: "NamespaceInUseIsNotForeign", file: "former.txt", id: document.Id(6).WithNamespace(3)},
+		{name: "UnusedNamespaceIsForeign", file: "stranger.txt", id: document.Id(1).WithNamespace(9), foreign: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(Test *testing.T) {
+			path := filepath.Join(root, document.DefaultNameTemplate.Render(tt.file, tt.id, time.Now()))
+			os.WriteFile(path, []byte(tt.file), 0o600)
+
+			//WHEN
+			_, err := lib.AddMultiple([]string{path}, false, false, false, true)
+
+			//THEN
+			if refused := err != nil; refused != tt.foreign {
+				Test.Errorf("expected refusal=%t but got error %v", tt.foreign, err)
+			}
+		})
+	}
+}
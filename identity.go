@@ -0,0 +1,86 @@
+package doccurator
+
+import (
+	"fmt"
+	"github.com/n2code/doccurator/internal/document"
+	"github.com/n2code/doccurator/internal/library"
+	out "github.com/n2code/doccurator/internal/output"
+)
+
+func (d *doccurator) GetIdentity() LibraryIdentity {
+	return LibraryIdentity{
+		Uuid:         d.appLib.GetUuid(),
+		Name:         d.appLib.GetName(),
+		IdNamespace:  d.appLib.GetIdNamespace(),
+		IdAllocation: d.appLib.GetIdAllocation(),
+	}
+}
+
+func (d *doccurator) SetName(name string) {
+	d.appLib.SetName(name)
+	if name == "" {
+		d.Print(out.Normal, "Library name removed\n")
+		return
+	}
+	d.Print(out.Normal, "Library name set to %q\n", name)
+}
+
+func (d *doccurator) SetIdNamespace(namespace uint32) {
+	d.appLib.SetIdNamespace(namespace)
+	d.Print(out.Normal, "ID namespace set to %d\n", namespace)
+}
+
+func (d *doccurator) PrintIdentity() {
+	identity := d.GetIdentity()
+	name := identity.Name
+	if name == "" {
+		name = "(unnamed)"
+	}
+	d.Print(out.Required, "UUID:          %s\n", identity.Uuid)
+	d.Print(out.Required, "Name:          %s\n", name)
+	d.Print(out.Required, "ID namespace:  %d\n", identity.IdNamespace)
+	d.Print(out.Required, "ID allocation: %s\n", describeIdAllocation(identity.IdAllocation))
+}
+
+// describeIdNamespace names the known library (see GetKnownLibraries) which generates IDs in the given namespace, if any
+func (d *doccurator) describeIdNamespace(namespace uint32) string {
+	description := fmt.Sprintf("foreign ID namespace %d", namespace)
+	if known, claimed := d.otherLibraryWithIdNamespace(namespace); claimed {
+		return fmt.Sprintf("%s of library %q", description, displayableLibraryName(known))
+	}
+	return description
+}
+
+// otherLibraryWithIdNamespace looks up a known library (see GetKnownLibraries) other than this one which generates IDs in the given namespace
+func (d *doccurator) otherLibraryWithIdNamespace(namespace uint32) (known KnownLibrary, claimed bool) {
+	if d.registryFile == "" {
+		return
+	}
+	libraries, _ := readRegistry(d.registryFile) //an unreadable registry merely provides no hints
+	for _, known := range libraries {
+		if known.IdNamespace == namespace && known.Uuid != d.appLib.GetUuid() {
+			return known, true
+		}
+	}
+	return
+}
+
+// foreignIdNamespace checks whether the given ID most likely stems from another library: IDs of namespace 0 (generated
+// before a namespace was set) are never foreign, other namespaces are foreign if a different known library generates
+// IDs in them or if no record of this library carries an ID of the namespace yet
+func (d *doccurator) foreignIdNamespace(id document.Id) (namespace uint32, foreign bool) {
+	namespace = id.Namespace()
+	if namespace == 0 || namespace == d.appLib.GetIdNamespace() {
+		return namespace, false
+	}
+	if _, claimed := d.otherLibraryWithIdNamespace(namespace); claimed {
+		return namespace, true
+	}
+	usedHere := false
+	d.appLib.VisitAllRecords(func(doc library.Document) {
+		if doc.Id().Namespace() == namespace {
+			usedHere = true
+		}
+	})
+	return namespace, !usedHere
+}
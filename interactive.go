@@ -7,15 +7,13 @@ import (
 	"fmt"
 	"github.com/n2code/doccurator/internal/library"
 	out "github.com/n2code/doccurator/internal/output"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 )
 
 const choiceAborted = ""
 
-func (d *doccurator) InteractiveTidy(choice RequestChoice, removeWaste bool) (decisionsMade int, foundWaste bool, cancelled bool) {
+func (d *doccurator) InteractiveTidy(choice RequestChoice, removeWaste bool, deduplicate bool) (decisionsMade int, foundWaste bool, cancelled bool) {
 	d.Print(out.Verbose, "Tidying up library...\n")
 
 	// this scan has no skip conditions because consciously added content shall be treated as such
@@ -25,22 +23,20 @@ func (d *doccurator) InteractiveTidy(choice RequestChoice, removeWaste bool) (de
 	buckets := make(map[library.PathStatus][]*library.CheckedPath)
 	wasteSkipEvaluators := d.getScanSkipEvaluators()
 	for i, path := range paths {
-		if path.Status().RepresentsWaste() && library.IsAnyFilterMatching(&wasteSkipEvaluators, filepath.Join(d.appLib.GetRoot(), path.AnchoredPath()), false) {
+		if path.Status().RepresentsWaste() && library.IsAnyFilterMatching(&wasteSkipEvaluators, d.absolutizeAnchored(path.AnchoredPath()), false) {
 			continue
 		}
 		buckets[path.Status()] = append(buckets[path.Status()], &paths[i])
 	}
 
-	var deletionCommitQueue []func() error
-
-	for _, status := range []library.PathStatus{library.Touched, library.Moved, library.Modified, library.Obsolete, library.Duplicate} {
+	for _, status := range []library.PathStatus{library.Touched, library.Moved, library.MovedModified, library.Modified, library.Obsolete, library.Duplicate} {
 		count := len(buckets[status])
 		if count == 0 {
 			continue
 		}
 		var declarationSingle, declarationMultiple, promptMassProcessing, question, subject, pastParticiple string
 		switch status {
-		case library.Touched, library.Moved, library.Modified:
+		case library.Touched, library.Moved, library.MovedModified, library.Modified:
 			declarationSingle = "1 document on record has its file %s.\n"         // touched/moved/modified
 			declarationMultiple = "%d documents on record have their files %s.\n" // <count> + touched/moved/modified
 			promptMassProcessing = "Update %s records?"                           // touched/moved/modified
@@ -49,6 +45,15 @@ func (d *doccurator) InteractiveTidy(choice RequestChoice, removeWaste bool) (de
 			pastParticiple = "updated"
 		case library.Obsolete, library.Duplicate:
 			foundWaste = true
+			if status == library.Duplicate && deduplicate {
+				declarationSingle = "1 file present has %s content.\n"      // duplicate
+				declarationMultiple = "%d files present have %s content.\n" // <count> + duplicate
+				promptMassProcessing = "Replace %s files with links?"       // duplicate
+				question = "Replace file with link?"
+				subject = "file"
+				pastParticiple = "replaced with links"
+				break
+			}
 			if !removeWaste {
 				continue
 			}
@@ -92,7 +97,7 @@ func (d *doccurator) InteractiveTidy(choice RequestChoice, removeWaste bool) (de
 		changeCount := 0
 	NextChange:
 		for _, path := range buckets[status] {
-			absolute := filepath.Join(d.appLib.GetRoot(), path.AnchoredPath())
+			absolute := d.absolutizeAnchored(path.AnchoredPath())
 			displayPath := d.displayablePath(absolute, true, false)
 
 			if decideIndividually {
@@ -127,8 +132,15 @@ func (d *doccurator) InteractiveTidy(choice RequestChoice, removeWaste bool) (de
 							path.ProbeFile(nil, &fileModTime, nil)
 							printProperty("Last modified", fileModTime.Local().Format(time.RFC1123), "file on disk", recordedModTime.Local().Format(time.RFC1123))
 						case library.Moved:
-							displayableOriginalLocation := d.displayablePath(filepath.Join(d.appLib.GetRoot(), referenced.AnchoredPath()), true, false)
+							displayableOriginalLocation := d.displayablePath(d.absolutizeAnchored(referenced.AnchoredPath()), true, false)
 							printProperty("Location", displayPath, "current", displayableOriginalLocation)
+						case library.MovedModified:
+							displayableOriginalLocation := d.displayablePath(d.absolutizeAnchored(referenced.AnchoredPath()), true, false)
+							printProperty("Location", displayPath, "current", displayableOriginalLocation)
+							path.ProbeFile(&fileSize, &fileModTime, &fileChecksum)
+							printProperty("Size", out.Filesize(fileSize), "file on disk", out.Filesize(recordedSize))
+							printProperty("Last modified", fileModTime.Local().Format(time.RFC1123), "file on disk", recordedModTime.Local().Format(time.RFC1123))
+							printProperty("SHA256", hex.EncodeToString(fileChecksum[:]), "file on disk", hex.EncodeToString(recordedChecksum[:]))
 						case library.Modified:
 							path.ProbeFile(&fileSize, &fileModTime, &fileChecksum)
 							printProperty("Size", out.Filesize(fileSize), "file on disk", out.Filesize(recordedSize))
@@ -139,7 +151,8 @@ func (d *doccurator) InteractiveTidy(choice RequestChoice, removeWaste bool) (de
 							if status == library.Obsolete {
 								property = "Obsoleted as"
 							}
-							printProperty(property, "", "", d.printer.Sprintf("%s%s%s%s%s [%s]", libRootScheme, out.BoldIntensity, referenced.AnchoredPath(), out.Reset, out.FaintIntensity, referenced.Id()))
+							scheme, relative := rootSchemeOf(referenced.AnchoredPath())
+							printProperty(property, "", "", d.printer.Sprintf("%s%s%s%s%s [%s]", scheme, out.BoldIntensity, relative, out.Reset, out.FaintIntensity, referenced.Id()))
 							printProperty("SHA256", "", "", hex.EncodeToString(recordedChecksum[:]))
 						}
 						options = []string{"Yes", "No"}
@@ -155,7 +168,7 @@ func (d *doccurator) InteractiveTidy(choice RequestChoice, removeWaste bool) (de
 
 			if doc := path.ReferencedDocument(); doChange {
 				switch status {
-				case library.Moved:
+				case library.Moved, library.MovedModified:
 					err := d.appLib.SetDocumentPath(doc, absolute)
 					if err != nil {
 						d.Print(out.Error, "update failed (%s): %s\n", displayPath, err)
@@ -171,39 +184,19 @@ func (d *doccurator) InteractiveTidy(choice RequestChoice, removeWaste bool) (de
 						d.Print(out.Normal, "%s [%s] - Updated %s.\n", displayPath, lowerStatus, doc.Id())
 					}
 				case library.Obsolete, library.Duplicate:
-					tempDir, err := os.MkdirTemp(filepath.Dir(absolute), ".doccurator-tidy-delete-staging-*")
-					if err != nil {
-						d.Print(out.Error, "deletion preparation failed (%s): %s\n", displayPath, err)
-						continue NextChange
-					}
-					deleteStagingDir := func(stagingDir string) func() error {
-						return func() error {
-							if err := os.RemoveAll(stagingDir); err != nil {
-								return fmt.Errorf("could not delete temporary staging directory (%s): %w", stagingDir, err)
-							}
-							return nil
+					if status == library.Duplicate && deduplicate {
+						linkKind, err := d.stageFileDeduplication(absolute, d.absolutizeAnchored(doc.AnchoredPath()))
+						if err != nil {
+							d.Print(out.Error, "deduplication failed (%s): %s\n", displayPath, err)
+							continue NextChange
 						}
-					}(tempDir)
-
-					backup := filepath.Join(tempDir, filepath.Base(absolute))
-					if err := os.Rename(absolute, backup); err != nil {
+						d.Print(out.Normal, "%s [%s] - Replaced with %s.\n", displayPath, lowerStatus, linkKind)
+						break
+					}
+					if err := d.stageFileDeletion(absolute); err != nil {
 						d.Print(out.Error, "deletion failed (%s): %s\n", displayPath, err)
-						if err := deleteStagingDir(); err != nil {
-							d.Print(out.Error, "%s\n", err)
-						}
 						continue NextChange
 					}
-					deletionCommitQueue = append(deletionCommitQueue, deleteStagingDir)
-
-					d.rollbackLog = append(d.rollbackLog, func(source string, target string, stagingDir string) func() error {
-						return func() error {
-							if err := os.Rename(source, target); err != nil {
-								return err
-							}
-							return os.RemoveAll(stagingDir)
-						}
-					}(backup, absolute, tempDir))
-
 					d.Print(out.Normal, "%s [%s] - Marked for delete.\n", displayPath, lowerStatus)
 				}
 				changeCount++
@@ -216,17 +209,6 @@ func (d *doccurator) InteractiveTidy(choice RequestChoice, removeWaste bool) (de
 
 	d.Print(out.Normal, "\n")
 
-	if len(deletionCommitQueue) > 0 {
-		d.Print(out.Normal, "Committing deletions...\n")
-		for _, commitDelete := range deletionCommitQueue {
-			if err := commitDelete(); err != nil {
-				//errors are reported but do not constitute an overall failure as a rollback would not work and removal from the original directory is already complete by now
-				// => failure is only possible theoretically anyway as the application should be able to remove the staging directory it has just created
-				d.Print(out.Error, "deletion has leftovers: %s\n", err)
-			}
-		}
-	}
-
 	d.Print(out.Verbose, "Tidy operation complete.\n")
 	return
 }
@@ -239,25 +221,37 @@ NextCandidate:
 	for _, checked := range results {
 		switch checked.Status() {
 		case library.Untracked:
-			absolute := filepath.Join(d.appLib.GetRoot(), checked.AnchoredPath())
+			absolute := d.absolutizeAnchored(checked.AnchoredPath())
 			displayPath := d.displayablePath(absolute, true, false)
 
 			usingExtractedId := true
-			extractedId, idErr := ExtractIdFromStandardizedFilename(absolute)
+			extractedId, idErr := d.extractIdFromFilename(absolute)
 			hasExtractedId := idErr == nil
 			newId := extractedId
 			if !hasExtractedId {
-				newId = d.GetFreeId()
+				if newId, idErr = d.GetFreeIdFor(absolute); idErr != nil {
+					d.Print(out.Error, "Skipping (%s): %s\n", displayPath, idErr)
+					continue NextCandidate
+				}
 				usingExtractedId = false
 			}
 
 			for decided := false; !decided; {
 				if usingExtractedId {
-					switch choice(fmt.Sprintf("Add %s using ID from filename? [%s]", displayPath, extractedId), []string{"Yes", "New ID", "Skip"}, true) {
+					question := fmt.Sprintf("Add %s using ID from filename? [%s]", displayPath, extractedId)
+					options := []string{"Yes", "New ID", "Skip"}
+					if namespace, foreign := d.foreignIdNamespace(extractedId); foreign {
+						question = fmt.Sprintf("Add %s using ID from filename although it belongs to %s? [%s]", displayPath, d.describeIdNamespace(namespace), extractedId)
+						options = []string{"New ID", "Yes", "Skip"} //suggest a new ID by default
+					}
+					switch choice(question, options, true) {
 					case "Yes":
 						decided = true
 					case "New ID":
-						newId = d.GetFreeId()
+						if newId, idErr = d.GetFreeIdFor(absolute); idErr != nil {
+							d.Print(out.Error, "Skipping (%s): %s\n", displayPath, idErr)
+							continue NextCandidate
+						}
 						usingExtractedId = false
 					case "Skip":
 						continue NextCandidate
@@ -0,0 +1,53 @@
+package doccurator
+
+import (
+	"github.com/n2code/doccurator/internal/library"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTidyDeduplication(t *testing.T) {
+	//GIVEN
+	root, lib := newTestLibrary(t, nil)
+	original := addTestFile(t, lib, 1, "original", "content")
+	duplicate := writeTestFile(t, root, "duplicate", "content")
+	yesToAll := func(_ string, options []string, _ bool) string { return options[0] }
+	sameFile := func() bool {
+		originalStat, _ := os.Stat(original)
+		duplicateStat, _ := os.Stat(duplicate)
+		return os.SameFile(originalStat, duplicateStat)
+	}
+
+	//WHEN
+	decisionsMade, _, _ := lib.InteractiveTidy(yesToAll, false, true)
+
+	//THEN
+	if decisionsMade != 1 {
+		t.Fatalf("expected one decision but got %d", decisionsMade)
+	}
+	if content, _ := os.ReadFile(duplicate); string(content) != "content" {
+		t.Fatal("duplicate lost its content")
+	}
+	if status := lib.appLib.CheckFilePath(duplicate, false).Status(); status != library.Linked || !sameFile() {
+		t.Errorf("expected hardlink but got status %s after deduplication", status)
+	}
+
+	t.Run("Rollback", func(Test *testing.T) {
+		lib.RollbackAllFilesystemChanges()
+		if content, _ := os.ReadFile(duplicate); string(content) != "content" || sameFile() {
+			Test.Error("duplicate not restored")
+		}
+		if leftovers, _ := filepath.Glob(filepath.Join(root, ".doccurator-tidy-delete-staging-*")); len(leftovers) > 0 {
+			Test.Errorf("staging directory not removed: %s", leftovers[0])
+		}
+	})
+
+	t.Run("NotOfferedAgain", func(Test *testing.T) {
+		lib.InteractiveTidy(yesToAll, false, true)
+		lib.PersistChanges()
+		if decisionsMade, _, _ := lib.InteractiveTidy(yesToAll, false, true); decisionsMade != 0 {
+			Test.Errorf("expected no decision after deduplication but got %d", decisionsMade)
+		}
+	})
+}
@@ -3,10 +3,24 @@ package document
 import (
 	checksum "crypto/sha256"
 	"github.com/n2code/doccurator/internal"
+	"time"
 )
 
 type Id uint64
 
+// idNamespaceShift places the optional namespace of an ID in its upper half, the lower half suffices for timestamp-derived IDs
+const idNamespaceShift = 32
+
+// Namespace yields the ID namespace which the ID belongs to (0 if none)
+func (id Id) Namespace() uint32 {
+	return uint32(id >> idNamespaceShift)
+}
+
+// WithNamespace yields the ID with its upper half replaced by the given namespace
+func (id Id) WithNamespace(namespace uint32) Id {
+	return Id(namespace)<<idNamespaceShift | id&(1<<idNamespaceShift-1)
+}
+
 type TrackedFileStatus rune
 
 const (
@@ -24,17 +38,32 @@ type Api interface {
 	RecordedFileProperties() (size int64, modTime unixTimestamp, sha256 [checksum.Size]byte)
 	IsObsolete() bool
 	DeclareObsolete()
+	Due() unixTimestamp
+	SetDue(due time.Time)
 	AnchoredPath() string
+	Root() string
 	SetPath(anchored string)
-	StandardizedFilename() string
+	StandardizedFilename(template NameTemplate, recognized ...NameTemplate) string
+	OriginalFilename(recognized ...NameTemplate) string
 	UpdateFromFileOnStorage(libraryRoot string) (changed bool, err error)
 	CompareToFileOnStorage(libraryRoot string, skipReadOnSizeMatch bool) TrackedFileStatus
 	MatchesChecksum(sha256 [checksum.Size]byte) bool
+	PerceptualHash() (hash PerceptualHash, isImage bool)
+	CatchUpPerceptualHash(libraryRoot string) (stored bool, err error)
+	CatchUpCertificateExpiry(libraryRoot string) (stored bool, err error)
 	String() string
 }
 
 type Index map[Id]Api
 
+// CopyDocument duplicates the full record of a document (timestamps included) under the given ID and anchored path
+func CopyDocument(original Api, id Id, anchored string) Api {
+	copied := *original.(*document)
+	copied.id = id
+	copied.localStorage.setFromPath(anchored)
+	return &copied
+}
+
 func NewDocument(id Id) Api {
 	now := unixTimestamp(internal.UnixTimestampNow())
 	return &document{
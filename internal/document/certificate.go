@@ -0,0 +1,38 @@
+package document
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"path/filepath"
+	"strings"
+)
+
+var certificateExtensions = map[string]bool{".crt": true, ".cer": true, ".pem": true, ".der": true}
+
+// IsCertificateFilename tells whether the extension of the file suggests a certificate whose expiry can be determined
+func IsCertificateFilename(filename string) bool {
+	return certificateExtensions[strings.ToLower(filepath.Ext(filename))]
+}
+
+// certificateExpiry determines the end of validity (NotAfter) of the first X.509 certificate in a certificate file (PEM or DER)
+func certificateExpiry(filename string, content []byte) (notAfter unixTimestamp, isCertificate bool) {
+	if !IsCertificateFilename(filename) {
+		return 0, false
+	}
+	der := content
+	for rest := content; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			der = block.Bytes
+			break
+		}
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return 0, false
+	}
+	return unixTimestamp(certificate.NotAfter.Unix()), true
+}
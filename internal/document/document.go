@@ -3,12 +3,12 @@ package document
 import (
 	checksum "crypto/sha256"
 	"errors"
-	"fmt"
 	"github.com/n2code/doccurator/internal"
 	"io/fs"
 	"os"
 	"path/filepath"
-	"regexp"
+	"strings"
+	"time"
 )
 
 const IdPattern = string(`[2-9]{5}[23456789ABCDEFHIJKLMNOPQRTUVWXYZ]+`)
@@ -43,18 +43,39 @@ func (doc *document) DeclareObsolete() {
 	}
 }
 
-// AnchoredPath returns a filepath relative to the library root directory ("anchored")
+func (doc *document) Due() unixTimestamp {
+	return doc.due
+}
+
+// SetDue sets the deadline of the document (precision: seconds), the zero time removes it
+func (doc *document) SetDue(due time.Time) {
+	var timestamp unixTimestamp
+	if !due.IsZero() {
+		timestamp = unixTimestamp(due.Unix())
+	}
+	if doc.due != timestamp {
+		doc.due = timestamp
+		doc.updateRecordChangeDate()
+	}
+}
+
+// AnchoredPath returns a filepath relative to the library root directory ("anchored"), qualified with the root name for secondary roots
 func (doc *document) AnchoredPath() string {
-	return doc.localStorage.anchoredFilepath()
+	return QualifyAnchoredPath(doc.localStorage.root, doc.localStorage.anchoredFilepath())
+}
+
+// Root returns the name of the library root the document is located in (empty for the primary root)
+func (doc *document) Root() string {
+	return doc.localStorage.root
 }
 
-//SetPath expects a filepath relative to the library root directory ("anchored")
+//SetPath expects a filepath relative to the library root directory ("anchored"), qualified with the root name for secondary roots
 func (doc *document) SetPath(anchored string) {
 	doc.localStorage.setFromPath(anchored)
 	doc.updateRecordChangeDate()
 }
 
-// UpdateFromFileOnStorage reads and stats the document using the recorded document path and the library root path (can be relative or absolute)
+// UpdateFromFileOnStorage reads and stats the document using the recorded document path and the path of its library root (can be relative or absolute)
 func (doc *document) UpdateFromFileOnStorage(libraryRoot string) (changed bool, err error) {
 	path := filepath.Join(libraryRoot, doc.localStorage.anchoredFilepath()) //result may be relative if the library root is relative
 	statsChanged, err := doc.localStorage.updateFileStats(path)
@@ -66,6 +87,17 @@ func (doc *document) UpdateFromFileOnStorage(libraryRoot string) (changed bool,
 		return false, err
 	}
 	contentChanged := doc.contentMetadata.setFromContent(content)
+	if contentChanged {
+		if notAfter, isCertificate := certificateExpiry(path, content); isCertificate {
+			doc.due = notAfter //certificates are authoritative regarding their expiry
+		}
+		doc.contentMetadata.expiryExamined = true
+	} else if !doc.contentMetadata.expiryExamined { //catching up on content recorded before expiry detection was introduced is no change of the record
+		doc.catchUpExpiry(path, content)
+	}
+	if contentChanged || doc.contentMetadata.perceptualState == perceptualHashUnknown { //catching up on content recorded before hashing was introduced is no change of the record
+		doc.contentMetadata.setPerceptualHash(content)
+	}
 	changed = statsChanged || contentChanged
 	if changed {
 		doc.updateRecordChangeDate()
@@ -73,7 +105,7 @@ func (doc *document) UpdateFromFileOnStorage(libraryRoot string) (changed bool,
 	return
 }
 
-// CompareToFileOnStorage calculates file status using the recorded document path and the library root path (can be relative or absolute)
+// CompareToFileOnStorage calculates file status using the recorded document path and the path of its library root (can be relative or absolute)
 func (doc *document) CompareToFileOnStorage(libraryRoot string, skipReadOnSizeMatch bool) TrackedFileStatus {
 	path := filepath.Join(libraryRoot, doc.localStorage.anchoredFilepath())
 
@@ -90,22 +122,22 @@ func (doc *document) CompareToFileOnStorage(libraryRoot string, skipReadOnSizeMa
 	}
 
 	if !skipReadOnSizeMatch {
-		content, err := os.ReadFile(path)
+		sum, err := internal.Sha256OfFile(path)
 		if err != nil {
 			return FileAccessError
 		}
-		if checksum.Sum256(content) != doc.contentMetadata.sha256Hash {
+		if sum != doc.contentMetadata.sha256Hash {
 			return ModifiedFile
 		}
 	}
 
 	if unixTimestamp(stat.ModTime().Unix()) != doc.localStorage.lastModified {
 		if skipReadOnSizeMatch {
-			content, err := os.ReadFile(path)
+			sum, err := internal.Sha256OfFile(path)
 			if err != nil {
 				return FileAccessError
 			}
-			if checksum.Sum256(content) != doc.contentMetadata.sha256Hash {
+			if sum != doc.contentMetadata.sha256Hash {
 				return ModifiedFile
 			}
 		}
@@ -119,19 +151,68 @@ func (doc *document) MatchesChecksum(sha256 [checksum.Size]byte) bool {
 	return doc.contentMetadata.sha256Hash == sha256
 }
 
-var ndocFileNameRegex = regexp.MustCompile(`^(.*)\.(` + IdPattern + `)\.ndoc(\.[^.]*)?$`)
+func (doc *document) PerceptualHash() (hash PerceptualHash, isImage bool) {
+	return doc.contentMetadata.perceptualHash, doc.contentMetadata.perceptualState == perceptualHashPresent
+}
+
+// CatchUpPerceptualHash examines content recorded before hashing was introduced if the file still matches the record.
+// Neither the change date of the record nor the file is touched. Content which is no decodable image is remembered as such.
+func (doc *document) CatchUpPerceptualHash(libraryRoot string) (stored bool, err error) {
+	if doc.contentMetadata.perceptualState != perceptualHashUnknown {
+		return false, nil
+	}
+	content, err := os.ReadFile(filepath.Join(libraryRoot, doc.localStorage.anchoredFilepath()))
+	if err != nil {
+		return false, err
+	}
+	if checksum.Sum256(content) != doc.contentMetadata.sha256Hash {
+		return false, nil //the hash has to match the content on record
+	}
+	doc.contentMetadata.setPerceptualHash(content)
+	return true, nil
+}
+
+// CatchUpCertificateExpiry examines content recorded before expiry detection was introduced if the file still matches the record.
+// Neither the change date of the record nor the file is touched. A due date which is set already is kept.
+func (doc *document) CatchUpCertificateExpiry(libraryRoot string) (stored bool, err error) {
+	if doc.contentMetadata.expiryExamined {
+		return false, nil
+	}
+	path := filepath.Join(libraryRoot, doc.localStorage.anchoredFilepath())
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	if checksum.Sum256(content) != doc.contentMetadata.sha256Hash {
+		return false, nil //the expiry has to match the content on record
+	}
+	doc.catchUpExpiry(path, content)
+	return true, nil
+}
+
+// catchUpExpiry applies the expiry of a certificate recorded before expiry detection was introduced unless a due date is set
+func (doc *document) catchUpExpiry(path string, content []byte) {
+	if notAfter, isCertificate := certificateExpiry(path, content); isCertificate && doc.due == 0 {
+		doc.due = notAfter
+	}
+	doc.contentMetadata.expiryExamined = true
+}
+
+// StandardizedFilename renders the name of the file according to the template. If the current name already follows the
+// template or one of the recognized (e.g. previously used) templates the original name is recovered first.
+func (doc *document) StandardizedFilename(template NameTemplate, recognized ...NameTemplate) string {
+	original := doc.OriginalFilename(append([]NameTemplate{template}, recognized...)...)
+	return template.Render(original, doc.id, time.Unix(int64(doc.localStorage.lastModified), 0))
+}
 
-func (doc *document) StandardizedFilename() string {
-	var original, extension string
-	//represents file.ext.23456X777.ndoc.ext or file_without_ext.23456X777.ndoc or .ext_only.23456X777.ndoc.ext_only (see tests!)
-	matches := ndocFileNameRegex.FindStringSubmatch(doc.localStorage.name)
-	if matches == nil {
-		original = doc.localStorage.name
-		extension = filepath.Ext(doc.localStorage.name)
-	} else {
-		original, _, extension = matches[1], matches[2], matches[3]
+// OriginalFilename recovers the name of the file before standardization according to the first matching template
+func (doc *document) OriginalFilename(recognized ...NameTemplate) string {
+	for _, known := range recognized {
+		if parsed, matches := known.Parse(doc.localStorage.name); matches {
+			return parsed.OriginalName
+		}
 	}
-	return fmt.Sprintf("%s.%s.ndoc%s", original, doc.id, extension)
+	return doc.localStorage.name
 }
 
 func (doc *document) updateRecordChangeDate() {
@@ -139,11 +220,13 @@ func (doc *document) updateRecordChangeDate() {
 }
 
 func (stored *storedFile) setFromPath(anchored string) {
-	stored.directory = SemanticPathFromNative(filepath.Dir(anchored))
-	stored.name = filepath.Base(anchored)
+	root, relative := SplitAnchoredPath(anchored)
+	stored.root = root
+	stored.directory = SemanticPathFromNative(filepath.Dir(relative))
+	stored.name = filepath.Base(relative)
 }
 
-// anchoredFilepath returns a filepath with system-native separators that is relative to the library root
+// anchoredFilepath returns a filepath with system-native separators that is relative to the library root the file is located in
 func (stored *storedFile) anchoredFilepath() string {
 	return filepath.Join(stored.directory.ToNativeFilepath(), stored.name)
 }
@@ -171,3 +254,27 @@ func (meta *contentMetadata) setFromContent(content []byte) (hasChanged bool) {
 	}
 	return
 }
+
+func (meta *contentMetadata) setPerceptualHash(content []byte) {
+	hash, isImage := perceptualHashOf(content)
+	meta.perceptualHash, meta.perceptualState = hash, perceptualHashAbsent
+	if isImage {
+		meta.perceptualState = perceptualHashPresent
+	}
+}
+
+// QualifyAnchoredPath prefixes a path relative to the named library root with the root qualifier (the primary root has no name and no qualifier)
+func QualifyAnchoredPath(root string, relative string) string {
+	if root == "" {
+		return relative
+	}
+	return root + RootQualifierSeparator + relative
+}
+
+// SplitAnchoredPath separates the root name (empty for the primary root) from the path relative to that root
+func SplitAnchoredPath(anchored string) (root string, relative string) {
+	if root, relative, qualified := strings.Cut(anchored, RootQualifierSeparator); qualified {
+		return root, relative
+	}
+	return "", anchored
+}
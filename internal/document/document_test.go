@@ -3,11 +3,25 @@ package document
 //NOTE: most document functionality is tested in bigger scoped scenario tests, e.g. on library level
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
 	"io/fs"
+	"math"
+	"math/big"
 	"os"
 	"path/filepath"
 	"regexp"
 	"testing"
+	"time"
 )
 
 func TestChangeTimestampUpdating(t *testing.T) {
@@ -204,7 +218,7 @@ func TestStandardizingFilenames(t *testing.T) {
 	assertRepeatedlyStandardizableAndReversible := func(filename string, expectedAfterStandardization string) {
 		cut := NewDocument(someId)
 		cut.SetPath("fake_dir/" + filename)
-		act := cut.StandardizedFilename()
+		act := cut.StandardizedFilename(DefaultNameTemplate)
 
 		//single standardization
 		switch {
@@ -214,7 +228,7 @@ func TestStandardizingFilenames(t *testing.T) {
 		}
 
 		//repeated standardization
-		actRepeated := cut.StandardizedFilename()
+		actRepeated := cut.StandardizedFilename(DefaultNameTemplate)
 		if actRepeated != act {
 			t.Error("repeated standardization of standardized name ", act, "yields", actRepeated, "but expectation is no change")
 		}
@@ -239,7 +253,228 @@ func TestStandardizingFilenames(t *testing.T) {
 	outdatedName := "problematic.ext." + outdatedId.String() + ".ndoc.ext"
 	irregularDoc.SetPath("fake_dir/" + outdatedName)
 	expectedStandardized := "problematic.ext." + correctId.String() + ".ndoc.ext"
-	if standardized := irregularDoc.StandardizedFilename(); standardized != expectedStandardized {
+	if standardized := irregularDoc.StandardizedFilename(DefaultNameTemplate); standardized != expectedStandardized {
 		t.Error("already-standardized filename", outdatedName, "not adapted to correct ID:", expectedStandardized)
 	}
 }
+
+func TestIdNamespace(t *testing.T) {
+	//GIVEN
+	local := Id(1234567890)
+
+	//WHEN
+	namespaced := local.WithNamespace(7)
+
+	//THEN
+	if namespaced.Namespace() != 7 {
+		t.Errorf("namespace of %d is %d instead of 7", namespaced, namespaced.Namespace())
+	}
+	if local.Namespace() != 0 {
+		t.Errorf("ID without namespace yields namespace %d", local.Namespace())
+	}
+	if moved := namespaced.WithNamespace(3); moved.Namespace() != 3 || moved.WithNamespace(0) != local {
+		t.Errorf("changing the namespace does not preserve the lower half: %d", moved)
+	}
+}
+
+func TestCertificateDueDate(t *testing.T) {
+	//GIVEN
+	notAfter := time.Date(2030, 6, 30, 12, 0, 0, 0, time.UTC)
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: notAfter.AddDate(-1, 0, 0), NotAfter: notAfter}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	libRootDir := t.TempDir()
+	os.WriteFile(filepath.Join(libRootDir, "server.crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), fs.ModePerm)
+	os.WriteFile(filepath.Join(libRootDir, "notes.crt"), []byte("not a certificate"), fs.ModePerm)
+	certificate, other := NewDocument(1), NewDocument(2)
+	certificate.SetPath("server.crt")
+	other.SetPath("notes.crt")
+
+	//WHEN
+	certificate.UpdateFromFileOnStorage(libRootDir)
+	other.UpdateFromFileOnStorage(libRootDir)
+
+	//THEN
+	if due := certificate.Due(); due != unixTimestamp(notAfter.Unix()) {
+		t.Errorf("expected due date %s but got %d", notAfter, due)
+	}
+	if other.Due() != 0 {
+		t.Error("due date set for file which is no certificate")
+	}
+
+	t.Run("ManualDueDateKeptUntilContentChanges", func(Test *testing.T) {
+		manual := time.Date(2029, 1, 1, 0, 0, 0, 0, time.UTC)
+		certificate.SetDue(manual)
+		certificate.UpdateFromFileOnStorage(libRootDir)
+		if certificate.Due() != unixTimestamp(manual.Unix()) {
+			Test.Error("manual due date overwritten although certificate unchanged")
+		}
+	})
+
+	t.Run("ClearedDueDateKeptUntilContentChanges", func(Test *testing.T) {
+		certificate.SetDue(time.Time{})
+		certificate.UpdateFromFileOnStorage(libRootDir)
+		if certificate.Due() != 0 {
+			Test.Error("cleared due date restored although certificate unchanged")
+		}
+	})
+
+	t.Run("ExistingRecordCaughtUp", func(Test *testing.T) {
+		recorded := NewDocument(3).(*document)
+		recorded.SetPath("server.crt")
+		recorded.UpdateFromFileOnStorage(libRootDir)
+		recorded.due, recorded.contentMetadata.expiryExamined = 0, false //as recorded before expiry detection was introduced
+		changedBefore := recorded.changed - 1
+		recorded.changed = changedBefore
+		changed, err := recorded.UpdateFromFileOnStorage(libRootDir)
+		if err != nil || changed || recorded.changed != changedBefore {
+			Test.Fatalf("catch-up reported or recorded as change (%v)", err)
+		}
+		if recorded.Due() != unixTimestamp(notAfter.Unix()) {
+			Test.Errorf("due date of existing record not caught up: %d", recorded.Due())
+		}
+	})
+
+	t.Run("CatchUpKeepsDueDate", func(Test *testing.T) {
+		recorded := NewDocument(4).(*document)
+		recorded.SetPath("server.crt")
+		recorded.UpdateFromFileOnStorage(libRootDir)
+		manual := unixTimestamp(time.Date(2029, 1, 1, 0, 0, 0, 0, time.UTC).Unix())
+		recorded.due, recorded.contentMetadata.expiryExamined = manual, false
+		stored, err := recorded.CatchUpCertificateExpiry(libRootDir)
+		if err != nil || !stored {
+			Test.Fatalf("expiry not examined (%v)", err)
+		}
+		if recorded.Due() != manual {
+			Test.Error("due date overwritten by catch-up")
+		}
+		if stored, _ := recorded.CatchUpCertificateExpiry(libRootDir); stored {
+			Test.Error("certificate examined again")
+		}
+	})
+}
+
+func TestNameTemplates(t *testing.T) {
+	//GIVEN
+	someId := Id(123456789)
+	modified := time.Date(2021, 12, 24, 12, 0, 0, 0, time.Local)
+	dated := NameTemplate("{date}_{name}.{id}{ext}")
+	prefixed := NameTemplate("{id}-{name}{ext}")
+
+	assertRoundTrip := func(template NameTemplate, original string, expected string) {
+		rendered := template.Render(original, someId, modified)
+		if rendered != expected {
+			t.Errorf("template %s renders %s as %s instead of %s", template, original, rendered, expected)
+			return
+		}
+		parsed, matches := template.Parse(rendered)
+		if !matches || parsed.OriginalName != original || parsed.IdText != someId.String() {
+			t.Errorf("template %s parses %s as %+v (matching: %t)", template, rendered, parsed, matches)
+		}
+		if id, err := template.ExtractId(rendered); err != nil || id != someId {
+			t.Errorf("ID of %s not extracted: %v", rendered, err)
+		}
+	}
+
+	//WHEN & THEN
+	assertRoundTrip(dated, "report.pdf", "2021-12-24_report."+someId.String()+".pdf")
+	assertRoundTrip(dated, "archive.tar.gz", "2021-12-24_archive.tar."+someId.String()+".gz")
+	assertRoundTrip(dated, "name_only", "2021-12-24_name_only."+someId.String())
+	assertRoundTrip(prefixed, "report.pdf", someId.String()+"-report.pdf")
+	assertRoundTrip(DefaultNameTemplate, "name.ext1.ext2", "name.ext1.ext2."+someId.String()+".ndoc.ext2")
+
+	if _, matches := prefixed.Parse("report.pdf"); matches {
+		t.Error("filename without ID recognized")
+	}
+	for _, invalid := range []NameTemplate{"{name}{ext}", "{id}{ext}", "{id}-{name}", "{id}/{name}{ext}", "{id}-{title}{ext}"} {
+		if invalid.Validate() == nil {
+			t.Errorf("invalid template %s accepted", invalid)
+		}
+	}
+
+	t.Run("Migration", func(Test *testing.T) {
+		doc := NewDocument(someId)
+		doc.SetPath("fake_dir/" + DefaultNameTemplate.Render("report.pdf", someId, modified))
+		if migrated := doc.StandardizedFilename(prefixed, DefaultNameTemplate); migrated != someId.String()+"-report.pdf" {
+			Test.Errorf("migration to new template yields %s", migrated)
+		}
+		if unrecognized := doc.StandardizedFilename(prefixed); unrecognized != someId.String()+"-report.pdf."+someId.String()+".ndoc.pdf" {
+			Test.Errorf("name following unrecognized template yields %s", unrecognized)
+		}
+	})
+}
+
+func TestPerceptualHash(t *testing.T) {
+	//GIVEN
+	pattern := func(width int, height int, mirrored bool) image.Image {
+		img := image.NewRGBA(image.Rect(0, 0, width, height))
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				u, v := float64(x)/float64(width), float64(y)/float64(height)
+				if mirrored {
+					u = 1 - u
+				}
+				brightness := uint8(255 * (0.5 + 0.5*math.Sin(7*u)*math.Cos(5*v)))
+				img.Set(x, y, color.RGBA{R: brightness, G: brightness / 2, B: 255 - brightness, A: 255})
+			}
+		}
+		return img
+	}
+	libRootDir := t.TempDir()
+	write := func(name string, encode func(file *os.File) error) Api {
+		file, _ := os.Create(filepath.Join(libRootDir, name))
+		defer file.Close()
+		if err := encode(file); err != nil {
+			t.Fatal(err)
+		}
+		doc := NewDocument(Id(len(name)))
+		doc.SetPath(name)
+		return doc
+	}
+	original := write("original.png", func(file *os.File) error { return png.Encode(file, pattern(320, 240, false)) })
+	copied := write("smaller_copy.jpg", func(file *os.File) error {
+		return jpeg.Encode(file, pattern(160, 120, false), &jpeg.Options{Quality: 50})
+	})
+	other := write("other.gif", func(file *os.File) error { return gif.Encode(file, pattern(320, 240, true), nil) })
+	text := write("notes.txt", func(file *os.File) error { _, err := file.WriteString("no image"); return err })
+
+	//WHEN
+	for _, doc := range []Api{original, copied, other, text} {
+		doc.UpdateFromFileOnStorage(libRootDir)
+	}
+
+	//THEN
+	originalHash, isImage := original.PerceptualHash()
+	copiedHash, copyIsImage := copied.PerceptualHash()
+	otherHash, otherIsImage := other.PerceptualHash()
+	if !isImage || !copyIsImage || !otherIsImage {
+		t.Fatal("image not hashed")
+	}
+	if _, textIsImage := text.PerceptualHash(); textIsImage {
+		t.Error("text file hashed as image")
+	}
+	if distance := originalHash.Distance(copiedHash); distance > 5 {
+		t.Errorf("re-encoded and resized copy too distant: %d", distance)
+	}
+	if distance := originalHash.Distance(otherHash); distance < 16 {
+		t.Errorf("different image too close: %d", distance)
+	}
+
+	t.Run("Persistence", func(Test *testing.T) {
+		blob, _ := json.Marshal(original)
+		var loaded document
+		loaded.UnmarshalJSON(blob)
+		if hash, loadedIsImage := loaded.PerceptualHash(); !loadedIsImage || hash != originalHash {
+			Test.Errorf("perceptual hash lost on reload, got %s", hash)
+		}
+		blob, _ = json.Marshal(text)
+		var loadedText document
+		loadedText.UnmarshalJSON(blob)
+		if loadedText.contentMetadata.perceptualState != perceptualHashAbsent {
+			Test.Error("examined content without image not remembered as such")
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package document
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/n2code/ndocid"
+)
+
+// NameTemplate describes the format of standardized filenames using the placeholders
+//
+//	{name} for the original filename without extension
+//	{ext}  for the original extension including the dot (empty if none)
+//	{id}   for the document ID
+//	{date} for the modification date of the file (YYYY-MM-DD)
+//
+// e.g. "{date}_{name}.{id}{ext}". Placeholders may be used repeatedly.
+type NameTemplate string
+
+// DefaultNameTemplate represents file.ext.23456X777.ndoc.ext or file_without_ext.23456X777.ndoc or .ext_only.23456X777.ndoc.ext_only
+const DefaultNameTemplate NameTemplate = "{name}{ext}.{id}.ndoc{ext}"
+
+const (
+	namePlaceholder = "{name}"
+	extPlaceholder  = "{ext}"
+	idPlaceholder   = "{id}"
+	datePlaceholder = "{date}"
+)
+
+const nameTemplateDateLayout = "2006-01-02"
+
+var placeholderPatterns = map[string]string{
+	namePlaceholder: `(.*)`,
+	extPlaceholder:  `(\.[^.]*)?`,
+	idPlaceholder:   `(` + IdPattern + `)`,
+	datePlaceholder: `([0-9]{4}-[0-9]{2}-[0-9]{2})`,
+}
+
+var placeholderRegex = regexp.MustCompile(`\{[^{}]*\}`)
+
+// compiledNameTemplate is the parser of a template, placeholders lists the placeholder of each capture group
+type compiledNameTemplate struct {
+	regex        *regexp.Regexp
+	placeholders []string
+}
+
+var compiledNameTemplates sync.Map //by NameTemplate
+
+// Validate checks that the template yields recognizable filenames without directories
+func (t NameTemplate) Validate() error {
+	if strings.ContainsAny(string(t), `/\`) {
+		return fmt.Errorf("name template %s must not contain directory separators", t)
+	}
+	for _, placeholder := range placeholderRegex.FindAllString(string(t), -1) {
+		if _, known := placeholderPatterns[placeholder]; !known {
+			return fmt.Errorf("unknown placeholder %s in name template %s", placeholder, t)
+		}
+	}
+	for _, required := range []string{namePlaceholder, extPlaceholder, idPlaceholder} {
+		if !strings.Contains(string(t), required) {
+			return fmt.Errorf("name template %s lacks placeholder %s", t, required)
+		}
+	}
+	return nil
+}
+
+// Render produces the standardized filename of a file with the given name, ID and modification time
+func (t NameTemplate) Render(originalName string, id Id, modified time.Time) string {
+	extension := filepath.Ext(originalName)
+	return t.render(strings.TrimSuffix(originalName, extension), extension, id.String(), modified.Local().Format(nameTemplateDateLayout))
+}
+
+func (t NameTemplate) render(name string, extension string, id string, date string) string {
+	return strings.NewReplacer(namePlaceholder, name, extPlaceholder, extension, idPlaceholder, id, datePlaceholder, date).Replace(string(t))
+}
+
+func (t NameTemplate) compile() compiledNameTemplate {
+	if compiled, cached := compiledNameTemplates.Load(t); cached {
+		return compiled.(compiledNameTemplate)
+	}
+	var compiled compiledNameTemplate
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	literalStart := 0
+	for _, bounds := range placeholderRegex.FindAllStringIndex(string(t), -1) {
+		placeholder := string(t)[bounds[0]:bounds[1]]
+		pattern.WriteString(regexp.QuoteMeta(string(t)[literalStart:bounds[0]]))
+		pattern.WriteString(placeholderPatterns[placeholder]) //template is validated
+		compiled.placeholders = append(compiled.placeholders, placeholder)
+		literalStart = bounds[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(string(t)[literalStart:]))
+	pattern.WriteString("$")
+	compiled.regex = regexp.MustCompile(pattern.String())
+	compiledNameTemplates.Store(t, compiled)
+	return compiled
+}
+
+// StandardizedName is a filename that matches a NameTemplate
+type StandardizedName struct {
+	IdText       string //ID as found in the filename, not necessarily decodable
+	OriginalName string //filename before standardization
+}
+
+// Parse recognizes filenames that follow the (validated) template and recovers the original name.
+// If the filename cannot be reproduced exactly from the recovered parts the first {name} and last {ext} are assumed.
+func (t NameTemplate) Parse(filename string) (parsed StandardizedName, matches bool) {
+	compiled := t.compile()
+	groups := compiled.regex.FindStringSubmatch(filename)
+	if groups == nil {
+		return parsed, false
+	}
+	var names, extensions []string
+	date := ""
+	for i, placeholder := range compiled.placeholders {
+		value := groups[i+1]
+		switch placeholder {
+		case namePlaceholder:
+			names = append(names, value)
+		case extPlaceholder:
+			extensions = append(extensions, value)
+		case idPlaceholder:
+			if parsed.IdText == "" {
+				parsed.IdText = value
+			}
+		case datePlaceholder:
+			if date == "" {
+				date = value
+			}
+		}
+	}
+	for _, name := range names {
+		for _, extension := range append([]string{""}, extensions...) {
+			candidate := name + extension
+			candidateExtension := filepath.Ext(candidate)
+			if t.render(strings.TrimSuffix(candidate, candidateExtension), candidateExtension, parsed.IdText, date) == filename {
+				parsed.OriginalName = candidate
+				return parsed, true
+			}
+		}
+	}
+	parsed.OriginalName = names[0] + extensions[len(extensions)-1]
+	return parsed, true
+}
+
+// ExtractId discovers and decodes the ID in a filename which follows the template
+func (t NameTemplate) ExtractId(filename string) (Id, error) {
+	parsed, matches := t.Parse(filename)
+	if !matches {
+		example := t.render("notes", ".txt", "23352M4R96Z", "2021-12-24")
+		return 0, fmt.Errorf("ID missing in filename %s (expected format %s, e.g. %s)", filename, t, example)
+	}
+	numId, err, _ := ndocid.Decode(parsed.IdText)
+	if err != nil {
+		return 0, fmt.Errorf(`bad ID in filename %s (%w)`, filename, err)
+	}
+	return Id(numId), nil
+}
@@ -0,0 +1,108 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif" //registers decoder
+	_ "image/jpeg"
+	_ "image/png"
+	"math/bits"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PerceptualHash is a difference hash ("dHash") of an image: Each bit tells whether the brightness of a cell in a grid
+// of 9x8 cells decreases towards its right neighbor. Re-encoded, resized or slightly retouched copies of an image yield
+// (nearly) the same hash, i.e. the number of differing bits is a measure of visual distance.
+type PerceptualHash uint64
+
+const (
+	perceptualGridWidth  = 9 //one more than the hash width because neighbors are compared
+	perceptualGridHeight = 8
+	perceptualMaxSamples = 16 //per cell and dimension, bounds the effort for large images
+)
+
+// PerceptualHashBits is the maximum distance between two perceptual hashes
+const PerceptualHashBits = 64
+
+var perceptualHashExtensions = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true}
+
+// IsImageFilename tells whether the extension of the file suggests content for which a perceptual hash can be computed
+func IsImageFilename(filename string) bool {
+	return perceptualHashExtensions[strings.ToLower(filepath.Ext(filename))]
+}
+
+// Distance yields the number of differing bits of two perceptual hashes (0 = visually identical)
+func (h PerceptualHash) Distance(other PerceptualHash) int {
+	return bits.OnesCount64(uint64(h ^ other))
+}
+
+// perceptualHashOf decodes PNG, JPEG and GIF content (detected by content, not by name) and hashes the image
+func perceptualHashOf(content []byte) (hash PerceptualHash, isImage bool) {
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return 0, false
+	}
+	bounds := img.Bounds()
+	if bounds.Dx() < 1 || bounds.Dy() < 1 {
+		return 0, false
+	}
+
+	var brightness [perceptualGridHeight][perceptualGridWidth]float64
+	for row := 0; row < perceptualGridHeight; row++ {
+		top, bottom := cellBounds(bounds.Min.Y, bounds.Dy(), row, perceptualGridHeight)
+		for column := 0; column < perceptualGridWidth; column++ {
+			left, right := cellBounds(bounds.Min.X, bounds.Dx(), column, perceptualGridWidth)
+			brightness[row][column] = averageLuminance(img, left, right, top, bottom)
+		}
+	}
+
+	for row := 0; row < perceptualGridHeight; row++ {
+		for column := 0; column < perceptualGridWidth-1; column++ {
+			hash <<= 1
+			if brightness[row][column] > brightness[row][column+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash, true
+}
+
+// cellBounds maps a cell of the grid onto the pixel range [start, end), cells of tiny images may share pixels
+func cellBounds(offset int, length int, cell int, cells int) (start int, end int) {
+	start = offset + cell*length/cells
+	end = offset + (cell+1)*length/cells
+	if end <= start {
+		end = start + 1
+	}
+	return
+}
+
+func averageLuminance(img image.Image, left int, right int, top int, bottom int) float64 {
+	stepX := (right-left)/perceptualMaxSamples + 1
+	stepY := (bottom-top)/perceptualMaxSamples + 1
+	sum, samples := 0.0, 0
+	for y := top; y < bottom; y += stepY {
+		for x := left; x < right; x += stepX {
+			r, g, b, _ := img.At(x, y).RGBA()
+			sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			samples++
+		}
+	}
+	return sum / float64(samples)
+}
+
+func (h PerceptualHash) String() string {
+	return fmt.Sprintf("%016x", uint64(h))
+}
+
+// ParsePerceptualHash reads the hexadecimal representation produced by String
+func ParsePerceptualHash(text string) (PerceptualHash, error) {
+	value, err := strconv.ParseUint(text, 16, 64)
+	if err != nil || len(text) != 16 {
+		return 0, fmt.Errorf("bad perceptual hash %q", text)
+	}
+	return PerceptualHash(value), nil
+}
@@ -22,22 +22,33 @@ func (doc *document) String() string {
 	if doc.obsolete {
 		retiredDateLine = fmt.Sprintf("\n  Retired:  %s", formatTime(doc.changed))
 	}
+	perceptualHashLine := ""
+	if doc.contentMetadata.perceptualState == perceptualHashPresent {
+		perceptualHashLine = fmt.Sprintf("\n  PHash:    %s", doc.contentMetadata.perceptualHash)
+	}
+	dueDateLine := ""
+	if doc.due != 0 {
+		dueDateLine = fmt.Sprintf("\n  Due:      %s", formatTime(doc.due))
+	}
 	return fmt.Sprintf(`Document %s
   Path:     %s
   Size:     %s
   SHA256:   %s
   Recorded: %s
-  Modified: %s%s`,
+  Modified: %s%s%s%s`,
 		doc.id,
-		doc.localStorage.anchoredFilepath(),
+		doc.AnchoredPath(),
 		output.Filesize(doc.contentMetadata.size),
 		hex.EncodeToString(doc.contentMetadata.sha256Hash[:]),
 		formatTime(doc.recorded),
 		formatTime(doc.localStorage.lastModified),
+		perceptualHashLine,
+		dueDateLine,
 		retiredDateLine)
 }
 
 type jsonDoc struct {
+	Root         string `json:",omitempty"`
 	Dir          SemanticPath
 	File         string
 	Size         int64
@@ -46,10 +57,16 @@ type jsonDoc struct {
 	Changed      unixTimestamp
 	FileModified unixTimestamp
 	FileObsolete bool
+	Due          unixTimestamp `json:",omitempty"`
+	PHash        string        `json:",omitempty"` //empty if the content has not been examined yet
+	DueChecked   bool          `json:",omitempty"` //set once the content has been examined for a certificate expiry
 }
 
+const noPerceptualHash = "none" //persisted instead of a perceptual hash for content which is no decodable image
+
 func (doc *document) MarshalJSON() ([]byte, error) {
 	persistedDoc := jsonDoc{
+		Root:         doc.localStorage.root,
 		Dir:          doc.localStorage.directory,
 		File:         doc.localStorage.name,
 		Size:         doc.contentMetadata.size,
@@ -58,6 +75,14 @@ func (doc *document) MarshalJSON() ([]byte, error) {
 		Changed:      doc.changed,
 		FileModified: doc.localStorage.lastModified,
 		FileObsolete: doc.obsolete,
+		Due:          doc.due,
+		DueChecked:   doc.contentMetadata.expiryExamined,
+	}
+	switch doc.contentMetadata.perceptualState {
+	case perceptualHashPresent:
+		persistedDoc.PHash = doc.contentMetadata.perceptualHash.String()
+	case perceptualHashAbsent:
+		persistedDoc.PHash = noPerceptualHash
 	}
 	return json.Marshal(persistedDoc)
 }
@@ -85,10 +110,13 @@ func (doc *document) UnmarshalJSON(blob []byte) error {
 		panic(err) //must not occur because persisted library's format is versioned
 	}
 	doc.id = MissingId
+	doc.localStorage.root = loadedDoc.Root
 	doc.localStorage.directory = loadedDoc.Dir
 	doc.localStorage.name = loadedDoc.File
 	doc.localStorage.lastModified = loadedDoc.FileModified
 	doc.obsolete = loadedDoc.FileObsolete
+	doc.due = loadedDoc.Due
+	doc.contentMetadata.expiryExamined = loadedDoc.DueChecked
 	doc.contentMetadata.size = loadedDoc.Size
 	shaBytes, err := hex.DecodeString(loadedDoc.Sha256)
 	if err != nil {
@@ -98,6 +126,18 @@ func (doc *document) UnmarshalJSON(blob []byte) error {
 		panic("persisted hash has bad length") //must not occur because persisted library's format is versioned
 	}
 	copy(doc.contentMetadata.sha256Hash[:], shaBytes)
+	switch loadedDoc.PHash {
+	case "":
+		doc.contentMetadata.perceptualState = perceptualHashUnknown
+	case noPerceptualHash:
+		doc.contentMetadata.perceptualState = perceptualHashAbsent
+	default:
+		hash, err := ParsePerceptualHash(loadedDoc.PHash)
+		if err != nil {
+			panic(err) //must not occur unless the library has been manipulated
+		}
+		doc.contentMetadata.perceptualHash, doc.contentMetadata.perceptualState = hash, perceptualHashPresent
+	}
 	doc.recorded = loadedDoc.Recorded
 	doc.changed = loadedDoc.Changed
 	return nil
@@ -13,10 +13,15 @@ type document struct {
 	localStorage    storedFile      //last known physical location
 	contentMetadata contentMetadata //last known content information
 	obsolete        bool            //tombstone marker to record removal from library
+	due             unixTimestamp   //deadline of the document (e.g. end of a contract or expiry of a certificate), 0 if none
 }
 
 type SemanticPath string //slash-separated regardless of OS
 
+// RootQualifierSeparator separates the root name from the root-relative part of anchored paths in secondary library roots,
+// e.g. "archive//2021/bill.pdf" (cleaned paths never contain it so the qualifier is unambiguous)
+const RootQualifierSeparator = string(filepath.Separator) + string(filepath.Separator)
+
 func (p SemanticPath) ToNativeFilepath() string {
 	return filepath.FromSlash(string(p))
 }
@@ -27,12 +32,25 @@ func SemanticPathFromNative(path string) SemanticPath {
 
 // storedFile is location relative to the storage root
 type storedFile struct {
+	root         string       // root is the name of the library root the file is located in (empty for the primary root)
 	directory    SemanticPath // directory is a semantic path relative to the library's root directory ("anchored")
 	name         string       // name is a pure filename without path information
 	lastModified unixTimestamp
 }
 
 type contentMetadata struct {
-	size       int64
-	sha256Hash [32]byte
+	size            int64
+	sha256Hash      [32]byte
+	perceptualHash  PerceptualHash //only meaningful for perceptualHashPresent
+	perceptualState perceptualHashState
+	expiryExamined  bool //unset for content recorded before certificate expiry detection was introduced
 }
+
+// perceptualHashState tells whether the content has been examined for a perceptual hash
+type perceptualHashState uint8
+
+const (
+	perceptualHashUnknown perceptualHashState = iota //content not examined yet, i.e. recorded before hashing was introduced
+	perceptualHashPresent                            //content is an image
+	perceptualHashAbsent                             //content is no decodable image
+)
@@ -1,7 +1,10 @@
 package internal
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"io"
+	"os"
 	"time"
 )
 
@@ -11,6 +14,21 @@ func AssertNoError(err error, because string) {
 	}
 }
 
+// Sha256OfFile streams the content of the file into the hash function, i.e. the file is never held in memory entirely
+func Sha256OfFile(path string) (sum [sha256.Size]byte, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return
+	}
+	copy(sum[:], hash.Sum(nil))
+	return
+}
+
 var UnixTimestampNow = func() uint64 {
 	return uint64(time.Now().Unix())
 }
@@ -2,6 +2,7 @@ package library
 
 import (
 	"github.com/n2code/doccurator/internal/document"
+	"time"
 )
 
 // Document is a softlink and API in one, if zero-valued it represents absence of a document
@@ -28,18 +29,60 @@ const (
 	//Obsolete signifies either path on record and marked as obsolete with matching content
 	//or path not on record and content is obsolete everywhere => decision required, auto mode may delete file
 	Obsolete PathStatus = 'X'
+	//MovedModified signifies a path not on record whose content is unknown but which is linked to a missing path on record
+	//by ID (attribute or standardized filename) or by the same filename and similar size => decision required, auto mode updates path and content
+	MovedModified PathStatus = '}'
+	//Linked signifies a path not on record which is the same file (hardlink) as a path on record,
+	//i.e. the content is duplicate but occupies no additional space => okay, no action required
+	Linked PathStatus = '&'
 )
 
+type RetentionBasis string
+
+const (
+	RetainSinceRecorded RetentionBasis = "recorded" //retention period starts when the document entered the library
+	RetainSinceModified RetentionBasis = "modified" //retention period starts with the modification time of the file on record
+)
+
+// RetentionRule determines how long the documents below a directory have to be kept
+type RetentionRule struct {
+	Directory string //anchored, i.e. relative to the library root ("." for the root itself), qualified with the root name for additional roots
+	Years     uint
+	Basis     RetentionBasis
+	EndOfYear bool `json:",omitempty"` //retention period starts at the end of the calendar year of the basis date
+}
+
+type IdAllocationStrategy string
+
+const (
+	AllocateByCurrentTime      IdAllocationStrategy = "time"   //counting down from the current time
+	AllocateByModificationTime IdAllocationStrategy = "mtime"  //counting down from the modification time of the file
+	AllocateRandomly           IdAllocationStrategy = "random" //random ID inside a range
+	AllocateFromBlock          IdAllocationStrategy = "block"  //counting up inside a reserved range, e.g. for an offline device
+)
+
+// IdAllocation determines how IDs of new documents are generated (inside the ID namespace of the library, if any)
+type IdAllocation struct {
+	Strategy IdAllocationStrategy
+	First    uint32 `json:",omitempty"` //lower bound of the range (inclusive), only for random and block strategy
+	Last     uint32 `json:",omitempty"` //upper bound of the range (inclusive), only for random and block strategy
+}
+
 type PathSkipEvaluator func(absolutePath string, isDir bool) (skip bool)
 
 // Api expects absolute system-native paths (with respect to the directory separator)
 type Api interface {
 	CreateDocument(document.Id) (Document, error)
+	ImportDocument(source Document, id document.Id) (Document, error) //source may belong to a different library
+	ImportDocumentAt(source Document, absolutePath string) (Document, error)
 	SetDocumentPath(doc Document, absolutePath string) error
 	GetDocumentById(document.Id) (doc Document, exists bool)
 	GetActiveDocumentByPath(absolutePath string) (doc Document, exists bool)
 	UpdateDocumentFromFile(Document) (changed bool, err error)
+	CatchUpPerceptualHash(Document) (stored bool, err error)
+	CatchUpCertificateExpiry(Document) (stored bool, err error)
 	MarkDocumentAsObsolete(Document)
+	SetDocumentDue(doc Document, due time.Time) //zero time removes the due date
 	GetObsoleteDocumentsForPath(absolutePath string) []Document
 	ForgetDocument(Document)
 	CheckFilePath(absolutePath string, skipReadOnSizeMatch bool) CheckedPath
@@ -47,16 +90,45 @@ type Api interface {
 	SaveToLocalFile(path string, overwrite bool) error
 	LoadFromLocalFile(path string)
 	SetRoot(absolutePath string)
+	MapRoot(absolutePath string, relativeToDatabase bool, host string) //empty host sets the default root
 	GetRoot() string
+	IsRootRelativeToDatabase() bool
+	GetUuid() string
+	IsUuidPersisted() bool //false until the library is saved if the database predates library identities
+	GetName() string
+	SetName(name string)
+	GetIdNamespace() uint32
+	SetIdNamespace(namespace uint32)
+	GetIdAllocation() IdAllocation
+	SetIdAllocation(allocation IdAllocation) error
+	GetNameTemplate() document.NameTemplate
+	SetNameTemplate(template document.NameTemplate) error
+	GetRecognizedNameTemplates() []document.NameTemplate
+	ExtractIdFromFilename(filename string) (document.Id, error)
+	GetIdAttributeMode() IdAttributeMode
+	SetIdAttributeMode(mode IdAttributeMode) error
+	ReadIdAttribute(absolutePath string) (attribute IdAttribute, present bool, err error)
+	WriteIdAttribute(doc Document, withHash bool) error
+	SetNamedRoot(name string, absolutePath string) error
+	RemoveNamedRoot(name string) error
+	GetNamedRoots() map[string]string //returns a copy
+	SetRetentionRule(absoluteDirectory string, rule RetentionRule) error
+	RemoveRetentionRule(absoluteDirectory string) error
+	GetRetentionRules() []RetentionRule                                //sorted by directory
+	GetRetentionRuleOf(doc Document) (rule RetentionRule, exists bool) //most specific rule for the location of the document
 	Absolutize(anchoredPath string) string
 	VisitAllRecords(func(Document)) //the list of visited documents is stable and isolated from changes during the visits
 }
 
 func NewLibrary() Api {
 	return &library{
+		uuid:                    newUuid(),
 		documents:               make(map[document.Id]document.Api),
 		activeAnchoredPathIndex: make(map[string]document.Api),
 		rootPath:                "", //to be set later
+		hostRoots:               make(map[string]rootLocation),
+		namedRoots:              make(map[string]string),
+		retentionRules:          make(map[string]RetentionRule),
 		ignoredPaths:            make(map[ignoredLibraryPath]bool),
 	}
 }
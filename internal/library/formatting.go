@@ -8,9 +8,9 @@ func ColorForStatus(status PathStatus) output.SgrModifier {
 		return output.DefaultForeground
 	case Untracked:
 		return output.Cyan //color of progress
-	case Touched, Moved:
+	case Touched, Moved, Linked:
 		return output.Green //color of good news (harmless)
-	case Modified:
+	case Modified, MovedModified:
 		return output.Yellow //color of attention
 	case Duplicate, Obsolete:
 		return output.Magenta //color of waste
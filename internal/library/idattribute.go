@@ -0,0 +1,108 @@
+package library
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/n2code/doccurator/internal/document"
+	"github.com/n2code/ndocid"
+)
+
+// IdAttributeName and HashAttributeName are the extended attributes which link a file to its record independent of its name
+const (
+	IdAttributeName   = "user.doccurator.id"
+	HashAttributeName = "user.doccurator.sha256"
+)
+
+var errFileAttributesUnsupported = errors.New("extended attributes not supported on this platform")
+
+type IdAttributeMode string
+
+const (
+	NoIdAttribute       IdAttributeMode = "none"    //nothing is written
+	IdAttributeOnly     IdAttributeMode = "id"      //the ID is written
+	IdAttributeWithHash IdAttributeMode = "id+hash" //the ID and the checksum of the content on record are written
+)
+
+// IdAttribute is the information found in the extended attributes of a file
+type IdAttribute struct {
+	Id     document.Id
+	Sha256 string //hex-encoded, empty if absent
+}
+
+func (lib *library) GetIdAttributeMode() IdAttributeMode {
+	if lib.idAttributeMode == "" {
+		return NoIdAttribute
+	}
+	return lib.idAttributeMode
+}
+
+func (lib *library) SetIdAttributeMode(mode IdAttributeMode) error {
+	switch mode {
+	case NoIdAttribute:
+	case IdAttributeOnly, IdAttributeWithHash:
+		if !fileAttributesSupported {
+			return errFileAttributesUnsupported
+		}
+	default:
+		return fmt.Errorf("unknown ID attribute mode %q", mode)
+	}
+	lib.idAttributeMode = mode
+	return nil
+}
+
+// ReadIdAttribute yields the ID attribute of the file, if any. Attributes that cannot be decoded are reported as error.
+func (lib *library) ReadIdAttribute(absolutePath string) (attribute IdAttribute, present bool, err error) {
+	textId, present, err := readFileAttribute(absolutePath, IdAttributeName)
+	if err != nil || !present {
+		return attribute, false, err
+	}
+	numId, decodeErr, complete := ndocid.Decode(textId)
+	if decodeErr != nil || !complete {
+		return attribute, false, fmt.Errorf("bad ID %q in attribute %s", textId, IdAttributeName)
+	}
+	attribute.Id = document.Id(numId)
+	attribute.Sha256, _, err = readFileAttribute(absolutePath, HashAttributeName)
+	return attribute, true, err
+}
+
+// WriteIdAttribute links the file of the document to its record, the checksum on record is only written if requested
+// (and removed otherwise because it might be outdated).
+func (lib *library) WriteIdAttribute(ref Document, withHash bool) error {
+	doc := lib.documents[ref.id] //caller error if nil
+	absolute := lib.Absolutize(doc.AnchoredPath())
+	if err := writeFileAttribute(absolute, IdAttributeName, doc.Id().String()); err != nil {
+		return err
+	}
+	if !withHash {
+		return removeFileAttribute(absolute, HashAttributeName)
+	}
+	_, _, sha256 := doc.RecordedFileProperties()
+	return writeFileAttribute(absolute, HashAttributeName, hex.EncodeToString(sha256[:]))
+}
+
+// refreshIdAttribute rewrites an existing ID attribute after the record was updated, failures are ignored because
+// an outdated attribute is detected by checkIdAttribute
+func (lib *library) refreshIdAttribute(ref Document) {
+	absolute := lib.Absolutize(ref.AnchoredPath())
+	if attribute, present, _ := lib.ReadIdAttribute(absolute); present && attribute.Id == ref.id {
+		_ = lib.WriteIdAttribute(ref, attribute.Sha256 != "")
+	}
+}
+
+// checkIdAttribute verifies that the ID attribute of a file on record (if any) matches the record.
+// The checksum is only compared if the content of the file is unchanged.
+func (lib *library) checkIdAttribute(absolutePath string, doc document.Api, contentUnchanged bool) error {
+	attribute, present, err := lib.ReadIdAttribute(absolutePath)
+	if err != nil || !present {
+		return err
+	}
+	if attribute.Id != doc.Id() {
+		return fmt.Errorf("ID attribute %s contradicts record %s", attribute.Id, doc.Id())
+	}
+	_, _, sha256 := doc.RecordedFileProperties()
+	if contentUnchanged && attribute.Sha256 != "" && attribute.Sha256 != hex.EncodeToString(sha256[:]) {
+		return fmt.Errorf("checksum attribute of %s outdated", doc.Id())
+	}
+	return nil
+}
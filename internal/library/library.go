@@ -2,18 +2,22 @@ package library
 
 import (
 	"bufio"
-	"crypto/sha256"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"io/fs"
+	"math"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
 
 	checksum "crypto/sha256"
 	"github.com/n2code/doccurator/internal/document"
+	"github.com/n2code/doccurator/internal/output"
 )
 
 func (lib *library) CreateDocument(id document.Id) (Document, error) {
@@ -27,6 +31,49 @@ func (lib *library) CreateDocument(id document.Id) (Document, error) {
 	return Document{id: id, library: lib}, nil
 }
 
+// ImportDocument copies the record of a document (usually of another library) into the library under the given ID.
+// The root of the record must be known to the library and, unless the record is obsolete, its path must not be in use by another active document.
+func (lib *library) ImportDocument(source Document, id document.Id) (Document, error) {
+	original := source.library.documents[source.id] //caller error if nil
+	if root := original.Root(); root != "" {
+		if _, known := lib.namedRoots[root]; !known {
+			return Document{}, fmt.Errorf("library root %q of document %s unknown", root, original.Id())
+		}
+	}
+	return lib.importDocument(original, id, original.AnchoredPath())
+}
+
+// ImportDocumentAt copies the record of a document of another library into the library, relocated to the given path inside the library.
+// ID and timestamps are kept, the path must not be in use by another active document unless the record is obsolete.
+func (lib *library) ImportDocumentAt(source Document, absolutePath string) (Document, error) {
+	original := source.library.documents[source.id] //caller error if nil
+	anchored, inLibrary := lib.getAnchoredPath(absolutePath)
+	if !inLibrary {
+		return Document{}, fmt.Errorf("path outside library: %s", absolutePath)
+	}
+	return lib.importDocument(original, original.Id(), anchored)
+}
+
+func (lib *library) importDocument(original document.Api, id document.Id, anchored string) (Document, error) {
+	if id == document.MissingId {
+		return Document{}, fmt.Errorf("document ID %s must not be used", id)
+	}
+	if _, exists := lib.documents[id]; exists {
+		return Document{}, fmt.Errorf("document ID %s already exists", id)
+	}
+	if !original.IsObsolete() {
+		if conflicting, pathAlreadyKnown := lib.activeAnchoredPathIndex[anchored]; pathAlreadyKnown {
+			return Document{}, fmt.Errorf("document %s already exists for path %s", conflicting.Id(), anchored)
+		}
+	}
+	imported := document.CopyDocument(original, id, anchored)
+	lib.documents[id] = imported
+	if !imported.IsObsolete() {
+		lib.activeAnchoredPathIndex[anchored] = imported
+	}
+	return Document{id: id, library: lib}, nil
+}
+
 func (lib *library) SetDocumentPath(ref Document, absolutePath string) error {
 	newAnchoredPath, inLibrary := lib.getAnchoredPath(absolutePath)
 	if !inLibrary {
@@ -93,7 +140,23 @@ func (lib *library) getAllObsoleteDocumentsAt(anchored string) (retirees []docum
 
 func (lib *library) UpdateDocumentFromFile(ref Document) (changed bool, err error) {
 	doc := lib.documents[ref.id] //caller error if nil
-	return doc.UpdateFromFileOnStorage(lib.rootPath)
+	changed, err = doc.UpdateFromFileOnStorage(lib.getRootDirectory(doc.Root()))
+	if changed && err == nil {
+		lib.refreshIdAttribute(ref)
+	}
+	return
+}
+
+// CatchUpPerceptualHash records the perceptual hash of a document recorded before hashing was introduced (see document.CatchUpPerceptualHash)
+func (lib *library) CatchUpPerceptualHash(ref Document) (stored bool, err error) {
+	doc := lib.documents[ref.id] //caller error if nil
+	return doc.CatchUpPerceptualHash(lib.getRootDirectory(doc.Root()))
+}
+
+// CatchUpCertificateExpiry records the expiry of a certificate recorded before expiry detection was introduced (see document.CatchUpCertificateExpiry)
+func (lib *library) CatchUpCertificateExpiry(ref Document) (stored bool, err error) {
+	doc := lib.documents[ref.id] //caller error if nil
+	return doc.CatchUpCertificateExpiry(lib.getRootDirectory(doc.Root()))
 }
 
 func (lib *library) MarkDocumentAsObsolete(ref Document) {
@@ -104,6 +167,11 @@ func (lib *library) MarkDocumentAsObsolete(ref Document) {
 	}
 }
 
+func (lib *library) SetDocumentDue(ref Document, due time.Time) {
+	doc := lib.documents[ref.id] //caller error if nil
+	doc.SetDue(due)
+}
+
 func (lib *library) ForgetDocument(ref Document) {
 	doc := lib.documents[ref.id] //caller error if nil
 	if !doc.IsObsolete() {
@@ -115,16 +183,8 @@ func (lib *library) ForgetDocument(ref Document) {
 
 // Absolutize turns an anchored path into an absolute one
 func (lib *library) Absolutize(anchored string) string {
-	return filepath.Join(lib.rootPath, anchored)
-}
-
-func calculateFileChecksum(path string) (sum [sha256.Size]byte, err error) {
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return
-	}
-	sum = sha256.Sum256(content)
-	return
+	root, relative := document.SplitAnchoredPath(anchored)
+	return filepath.Join(lib.getRootDirectory(root), relative)
 }
 
 func (lib *library) loadIgnoreFile(absoluteIgnoreFile string) (err error) {
@@ -174,8 +234,8 @@ func (lib *library) loadIgnoreFile(absoluteIgnoreFile string) (err error) {
 
 			nativeIgnored := filepath.FromSlash(line)
 			absoluteIgnored := filepath.Join(filepath.Dir(absoluteIgnoreFile), nativeIgnored)
-			anchoredIgnored, _ := filepath.Rel(lib.rootPath, absoluteIgnored) //error impossible because both are absolute and ".." does not occur
-			if absoluteIgnored == lib.rootPath {                              //sanity check
+			anchoredIgnored, _ := lib.getAnchoredPath(absoluteIgnored) //always inside because ".." does not occur
+			if lib.isRootDirectory(absoluteIgnored) {                  //sanity check
 				return fmt.Errorf("refers to library root dir")
 			}
 
@@ -197,7 +257,7 @@ func (lib *library) isIgnored(absolutePath string, isDir bool) bool {
 	if filepath.Base(absolutePath) == LocatorFileName {
 		return true
 	}
-	anchoredPath, _ := filepath.Rel(lib.rootPath, absolutePath)
+	anchoredPath, _ := lib.getAnchoredPath(absolutePath)
 	return lib.ignoredPaths[ignoredLibraryPath{anchored: anchoredPath, directory: isDir}]
 }
 
@@ -229,7 +289,7 @@ func (lib *library) Scan(scanFilters []PathSkipEvaluator, resultFilters []PathSk
 
 	visitor := func(absolutePath string, d fs.DirEntry, dirError error) error {
 		if dirError != nil {
-			badDir, _ := filepath.Rel(lib.rootPath, absolutePath)
+			badDir, _ := lib.getAnchoredPath(absolutePath)
 			addError(badDir, fmt.Errorf("directory scan error: %w", dirError))
 			return filepath.SkipDir //attempt to continue
 		}
@@ -241,7 +301,7 @@ func (lib *library) Scan(scanFilters []PathSkipEvaluator, resultFilters []PathSk
 			ignoreFileCandidate := filepath.Join(absolutePath, IgnoreFileName)
 			if _, err := os.Stat(ignoreFileCandidate); err == nil { //ignore file does not have to exist
 				if ignoreErr := lib.loadIgnoreFile(ignoreFileCandidate); ignoreErr != nil {
-					badIgnore, _ := filepath.Rel(lib.rootPath, ignoreFileCandidate)
+					badIgnore, _ := lib.getAnchoredPath(ignoreFileCandidate)
 					addError(badIgnore, fmt.Errorf("ignore file error: %w", ignoreErr))
 				}
 			}
@@ -269,7 +329,9 @@ func (lib *library) Scan(scanFilters []PathSkipEvaluator, resultFilters []PathSk
 
 		return nil
 	}
-	_ = filepath.WalkDir(lib.rootPath, visitor) //errors are communicated as entry in output parameter
+	for _, rootPath := range lib.getAllRootDirectories() {
+		_ = filepath.WalkDir(rootPath, visitor) //errors are communicated as entry in output parameter
+	}
 
 	for _, doc := range lib.documents {
 		if _, alreadyCheckedPath := coveredLibraryPaths[doc.AnchoredPath()]; !alreadyCheckedPath {
@@ -285,13 +347,52 @@ func (lib *library) Scan(scanFilters []PathSkipEvaluator, resultFilters []PathSk
 }
 
 func (lib *library) getAnchoredPath(absolutePath string) (anchored string, insideLibraryDir bool) {
-	anchored, err := filepath.Rel(lib.rootPath, absolutePath)
-	if err != nil || strings.HasPrefix(anchored, "..") {
-		insideLibraryDir = false
-		return
+	for name, rootPath := range lib.namedRoots { //roots do not overlap so at most one matches
+		if relative, inside := getRelativePathInside(rootPath, absolutePath); inside {
+			return document.QualifyAnchoredPath(name, relative), true
+		}
 	}
-	insideLibraryDir = true
-	return
+	return getRelativePathInside(lib.rootPath, absolutePath)
+}
+
+// getRelativePathInside yields the path relative to the given directory and whether it is located inside
+func getRelativePathInside(directory string, absolutePath string) (relative string, inside bool) {
+	relative, err := filepath.Rel(directory, absolutePath)
+	if err != nil || strings.HasPrefix(relative, "..") {
+		return relative, false
+	}
+	return relative, true
+}
+
+// getRootDirectory returns the absolute path of the root with the given name (the primary root has no name)
+func (lib *library) getRootDirectory(name string) string {
+	if name == "" {
+		return lib.rootPath
+	}
+	return lib.namedRoots[name]
+}
+
+// getAllRootDirectories lists the primary root followed by the secondary roots in order of their names
+func (lib *library) getAllRootDirectories() []string {
+	names := make([]string, 0, len(lib.namedRoots))
+	for name := range lib.namedRoots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	directories := []string{lib.rootPath}
+	for _, name := range names {
+		directories = append(directories, lib.namedRoots[name])
+	}
+	return directories
+}
+
+func (lib *library) isRootDirectory(absolutePath string) bool {
+	for _, rootPath := range lib.getAllRootDirectories() {
+		if absolutePath == rootPath {
+			return true
+		}
+	}
+	return false
 }
 
 func (lib *library) activePathExists(anchored string) (exists bool) {
@@ -300,11 +401,144 @@ func (lib *library) activePathExists(anchored string) (exists bool) {
 }
 
 func (lib *library) getAbsolutePathOfDocument(doc document.Api) string {
-	return filepath.Join(lib.rootPath, doc.AnchoredPath())
+	return lib.Absolutize(doc.AnchoredPath())
 }
 
+// SetRoot sets the default primary root which is persisted as absolute path
 func (lib *library) SetRoot(absolutePath string) {
-	lib.rootPath = absolutePath
+	lib.MapRoot(absolutePath, false, "")
+}
+
+// MapRoot sets the primary root, either as default or as override for the given host.
+// If requested the root is persisted relative to the directory of the library database file to support libraries on removable drives.
+func (lib *library) MapRoot(absolutePath string, relativeToDatabase bool, host string) {
+	location := rootLocation{path: absolutePath, relativeToDatabase: relativeToDatabase}
+	if host == "" {
+		lib.defaultRoot = location
+	} else {
+		lib.hostRoots[host] = location
+	}
+	lib.rootPath = lib.getEffectiveRoot().path
+}
+
+// getEffectiveRoot yields the override for the current host if it exists, the default root otherwise
+func (lib *library) getEffectiveRoot() rootLocation {
+	if location, overridden := lib.hostRoots[currentHostname()]; overridden {
+		return location
+	}
+	return lib.defaultRoot
+}
+
+func (lib *library) IsRootRelativeToDatabase() bool {
+	return lib.getEffectiveRoot().relativeToDatabase
+}
+
+func (lib *library) GetUuid() string {
+	return lib.uuid
+}
+
+func (lib *library) IsUuidPersisted() bool {
+	return lib.uuidPersisted
+}
+
+func (lib *library) GetName() string {
+	return lib.name
+}
+
+func (lib *library) SetName(name string) {
+	lib.name = name
+}
+
+func (lib *library) GetIdNamespace() uint32 {
+	return lib.idNamespace
+}
+
+func (lib *library) SetIdNamespace(namespace uint32) {
+	lib.idNamespace = namespace
+}
+
+func (lib *library) GetNameTemplate() document.NameTemplate {
+	if lib.nameTemplate == "" {
+		return document.DefaultNameTemplate
+	}
+	return lib.nameTemplate
+}
+
+// SetNameTemplate remembers the replaced template so that names following it are still recognized
+func (lib *library) SetNameTemplate(template document.NameTemplate) error {
+	if err := template.Validate(); err != nil {
+		return err
+	}
+	previous := lib.GetNameTemplate()
+	if template == previous {
+		return nil
+	}
+	remembered := []document.NameTemplate{previous}
+	for _, known := range lib.previousNameTemplates {
+		if known != template && known != previous {
+			remembered = append(remembered, known)
+		}
+	}
+	lib.nameTemplate = template
+	lib.previousNameTemplates = remembered
+	return nil
+}
+
+// GetRecognizedNameTemplates lists the current template followed by all previous ones (most recent first)
+func (lib *library) GetRecognizedNameTemplates() []document.NameTemplate {
+	return append([]document.NameTemplate{lib.GetNameTemplate()}, lib.previousNameTemplates...)
+}
+
+// ExtractIdFromFilename tries all recognized templates, the error refers to the current one
+func (lib *library) ExtractIdFromFilename(filename string) (id document.Id, err error) {
+	for i, template := range lib.GetRecognizedNameTemplates() {
+		var templateErr error
+		if id, templateErr = template.ExtractId(filename); templateErr == nil {
+			return id, nil
+		} else if i == 0 {
+			err = templateErr
+		}
+	}
+	return 0, err
+}
+
+func (lib *library) GetIdAllocation() IdAllocation {
+	if lib.idAllocation.Strategy == "" {
+		return IdAllocation{Strategy: AllocateByCurrentTime}
+	}
+	return lib.idAllocation
+}
+
+func (lib *library) SetIdAllocation(allocation IdAllocation) error {
+	switch allocation.Strategy {
+	case AllocateByCurrentTime, AllocateByModificationTime:
+		if allocation.First != 0 || allocation.Last != 0 {
+			return fmt.Errorf("ID allocation strategy %q does not use a range", allocation.Strategy)
+		}
+	case AllocateRandomly, AllocateFromBlock:
+		if allocation.First == 0 || allocation.First > allocation.Last {
+			return fmt.Errorf("ID allocation strategy %q requires a range of IDs between 1 and %d", allocation.Strategy, uint32(math.MaxUint32))
+		}
+	default:
+		return fmt.Errorf("unknown ID allocation strategy %q", allocation.Strategy)
+	}
+	lib.idAllocation = allocation
+	return nil
+}
+
+func newUuid() string {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		panic(err) //system source of randomness must not fail
+	}
+	uuid[6] = uuid[6]&0x0f | 0x40 //version 4 (random)
+	uuid[8] = uuid[8]&0x3f | 0x80 //RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
+
+var currentHostname = func() string {
+	name, _ := os.Hostname() //empty name never matches an override
+	return name
 }
 
 // yields absolute path
@@ -312,6 +546,104 @@ func (lib *library) GetRoot() string {
 	return lib.rootPath
 }
 
+var rootNameRegex = regexp.MustCompile(`^[\p{L}\p{N}_-][\p{L}\p{N}_.-]*$`)
+
+// SetNamedRoot adds a secondary root or relocates an existing one, the directory must not overlap with any other root
+func (lib *library) SetNamedRoot(name string, absolutePath string) error {
+	if !rootNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid root name %q (allowed: letters, digits, and any of _-. except at the start)", name)
+	}
+	roots := lib.GetNamedRoots()
+	delete(roots, name)
+	roots[""] = lib.rootPath
+	for otherName, otherPath := range roots {
+		_, otherContainsNew := getRelativePathInside(otherPath, absolutePath)
+		_, newContainsOther := getRelativePathInside(absolutePath, otherPath)
+		if otherContainsNew || newContainsOther {
+			if otherName == "" {
+				return fmt.Errorf("directory %s overlaps with primary library root %s", absolutePath, otherPath)
+			}
+			return fmt.Errorf("directory %s overlaps with library root %q at %s", absolutePath, otherName, otherPath)
+		}
+	}
+	lib.namedRoots[name] = absolutePath
+	return nil
+}
+
+// RemoveNamedRoot removes a secondary root that is not referenced by any record (including retired ones)
+func (lib *library) RemoveNamedRoot(name string) error {
+	if _, exists := lib.namedRoots[name]; !exists {
+		return fmt.Errorf("library root %q does not exist", name)
+	}
+	referencing := 0
+	for _, doc := range lib.documents {
+		if doc.Root() == name {
+			referencing++
+		}
+	}
+	if referencing > 0 {
+		return fmt.Errorf("library root %q is still referenced by %d %s", name, referencing, output.Plural(referencing, "record", "records"))
+	}
+	delete(lib.namedRoots, name)
+	return nil
+}
+
+func (lib *library) SetRetentionRule(absoluteDirectory string, rule RetentionRule) error {
+	anchored, inside := lib.getAnchoredPath(absoluteDirectory)
+	if !inside {
+		return fmt.Errorf("directory %s is outside of the library", absoluteDirectory)
+	}
+	if rule.Years == 0 {
+		return errors.New("retention period must be at least one year")
+	}
+	if rule.Basis != RetainSinceRecorded && rule.Basis != RetainSinceModified {
+		return fmt.Errorf("unknown retention basis %q", rule.Basis)
+	}
+	rule.Directory = anchored
+	lib.retentionRules[anchored] = rule
+	return nil
+}
+
+func (lib *library) RemoveRetentionRule(absoluteDirectory string) error {
+	anchored, _ := lib.getAnchoredPath(absoluteDirectory)
+	if _, exists := lib.retentionRules[anchored]; !exists {
+		return fmt.Errorf("no retention rule for %s", absoluteDirectory)
+	}
+	delete(lib.retentionRules, anchored)
+	return nil
+}
+
+func (lib *library) GetRetentionRules() []RetentionRule {
+	rules := make([]RetentionRule, 0, len(lib.retentionRules))
+	for _, rule := range lib.retentionRules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Directory < rules[j].Directory })
+	return rules
+}
+
+func (lib *library) GetRetentionRuleOf(ref Document) (rule RetentionRule, exists bool) {
+	docRoot, docRelative := document.SplitAnchoredPath(lib.documents[ref.id].AnchoredPath()) //caller error if nil
+	for _, candidate := range lib.retentionRules {
+		root, relative := document.SplitAnchoredPath(candidate.Directory)
+		if root != docRoot || (relative != "." && !strings.HasPrefix(docRelative, relative+string(filepath.Separator))) {
+			continue
+		}
+		if !exists || len(candidate.Directory) > len(rule.Directory) { //deepest directory is most specific
+			rule, exists = candidate, true
+		}
+	}
+	return
+}
+
+func (lib *library) GetNamedRoots() map[string]string {
+	roots := make(map[string]string, len(lib.namedRoots))
+	for name, rootPath := range lib.namedRoots {
+		roots[name] = rootPath
+	}
+	return roots
+}
+
 func (l docsByRecordedAndId) Len() int {
 	return len(l)
 }
@@ -356,30 +688,73 @@ func (libDoc *Document) RecordProperties() (size int64, modTime time.Time, sha25
 	return
 }
 
+func (libDoc *Document) RecordTimestamps() (recorded time.Time, changed time.Time) {
+	doc := libDoc.library.documents[libDoc.id] //caller error if any is nil
+	recorded = time.Unix(int64(doc.Recorded()), 0)
+	changed = time.Unix(int64(doc.Changed()), 0)
+	return
+}
+
+// DueDate yields the deadline of the document if one is set
+func (libDoc *Document) DueDate() (due time.Time, set bool) {
+	doc := libDoc.library.documents[libDoc.id] //caller error if any is nil
+	if doc.Due() == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(doc.Due()), 0), true
+}
+
+// PerceptualHash yields the perceptual hash of the recorded content if it is an image (see document.PerceptualHash)
+func (libDoc *Document) PerceptualHash() (hash document.PerceptualHash, isImage bool) {
+	doc := libDoc.library.documents[libDoc.id] //caller error if any is nil
+	return doc.PerceptualHash()
+}
+
+func (libDoc *Document) StandardizedFilename() string {
+	doc := libDoc.library.documents[libDoc.id] //caller error if any is nil
+	return doc.StandardizedFilename(libDoc.library.GetNameTemplate(), libDoc.library.previousNameTemplates...)
+}
+
 func (libDoc *Document) RenameToStandardNameFormat(dryRun bool) (newNameIfDifferent string, err error, fsRollback func() error) {
+	return libDoc.renameInPlace(libDoc.StandardizedFilename(), "standardized", dryRun)
+}
+
+// OriginalFilename yields the name of the file without the parts added by standardization (the current name if not standardized)
+func (libDoc *Document) OriginalFilename() string {
+	doc := libDoc.library.documents[libDoc.id] //caller error if any is nil
+	return doc.OriginalFilename(libDoc.library.GetRecognizedNameTemplates()...)
+}
+
+// RenameToOriginalName reverts RenameToStandardNameFormat, i.e. strips the ID from the filename
+func (libDoc *Document) RenameToOriginalName(dryRun bool) (newNameIfDifferent string, err error, fsRollback func() error) {
+	return libDoc.renameInPlace(libDoc.OriginalFilename(), "original", dryRun)
+}
+
+// renameInPlace renames the file of the document inside its directory, the kind of name is used in error messages
+func (libDoc *Document) renameInPlace(newName string, kind string, dryRun bool) (newNameIfDifferent string, err error, fsRollback func() error) {
 	fsRollback = func() error { return nil }
 
-	//calculate new name and paths
+	//calculate new paths
 
 	doc := libDoc.library.documents[libDoc.id] //caller error if any is nil
-	standardName := doc.StandardizedFilename()
 	oldPath := doc.AnchoredPath()
-	standardPath := filepath.Join(filepath.Dir(oldPath), standardName)
-	if standardPath == oldPath {
+	root, relativeOldPath := document.SplitAnchoredPath(oldPath)
+	newPath := document.QualifyAnchoredPath(root, filepath.Join(filepath.Dir(relativeOldPath), newName))
+	if newPath == oldPath {
 		return
 	}
-	newNameIfDifferent = standardName
-	absoluteOldPath := filepath.Join(libDoc.library.GetRoot(), oldPath)
-	absoluteNewPath := filepath.Join(libDoc.library.GetRoot(), standardPath)
+	newNameIfDifferent = newName
+	absoluteOldPath := libDoc.library.Absolutize(oldPath)
+	absoluteNewPath := libDoc.library.Absolutize(newPath)
 
 	//check for conflicts
 
-	if libDoc.library.activePathExists(standardPath) {
-		err = fmt.Errorf("standardized path already on record: %s", absoluteNewPath)
+	if libDoc.library.activePathExists(newPath) {
+		err = fmt.Errorf("%s path already on record: %s", kind, absoluteNewPath)
 		return
 	}
 	if _, statErr := os.Stat(absoluteNewPath); statErr == nil {
-		err = fmt.Errorf("file with standardized name already exists: %s", absoluteNewPath)
+		err = fmt.Errorf("file with %s name already exists: %s", kind, absoluteNewPath)
 		return
 	}
 
@@ -406,7 +781,11 @@ func (libDoc *Document) RenameToStandardNameFormat(dryRun bool) (newNameIfDiffer
 }
 
 func (s PathStatus) RepresentsChange() bool {
-	return s != Tracked && s != Removed
+	return s != Tracked && s != Removed && s != Linked
+}
+
+func (s PathStatus) RepresentsOutdatedRecord() bool {
+	return s == Touched || s == Moved || s == MovedModified || s == Modified
 }
 
 func (s PathStatus) RepresentsWaste() bool {
@@ -461,3 +461,104 @@ func TestBlockAddingSystemFiles(t *testing.T) {
 		}
 	})
 }
+
+func TestMultipleRoots(t *testing.T) {
+	//GIVEN
+	primaryDir, lib := setupLibraryInTemp(t)
+	archiveDir := t.TempDir()
+	primaryPath := filepath.Join(primaryDir, "bill.pdf")
+	archivePath := filepath.Join(archiveDir, "2021", "bill.pdf")
+	writeFile(primaryPath, "bill")
+	os.Mkdir(filepath.Join(archiveDir, "2021"), 0o700)
+
+	t.Run("InvalidRootName", func(Test *testing.T) {
+		//WHEN
+		err := lib.SetNamedRoot("a/b", archiveDir)
+		//THEN
+		if err == nil {
+			Test.Fatal("invalid root name accepted")
+		}
+	})
+
+	t.Run("OverlappingRoot", func(Test *testing.T) {
+		//WHEN
+		err := lib.SetNamedRoot("sub", filepath.Join(primaryDir, "sub"))
+		//THEN
+		if err == nil {
+			Test.Fatal("root inside primary root accepted")
+		}
+	})
+
+	//GIVEN
+	if err := lib.SetNamedRoot("archive", archiveDir); err != nil {
+		t.Fatal(err)
+	}
+	doc, _ := lib.CreateDocument(42)
+	lib.SetDocumentPath(doc, primaryPath)
+	lib.UpdateDocumentFromFile(doc)
+
+	t.Run("QualifiedAnchoredPath", func(Test *testing.T) {
+		//WHEN
+		anchored, inLibrary := lib.(*library).getAnchoredPath(archivePath)
+		//THEN
+		if !inLibrary || anchored != document.QualifyAnchoredPath("archive", filepath.Join("2021", "bill.pdf")) {
+			Test.Fatal("unexpected anchored path:", anchored)
+		}
+		if lib.Absolutize(anchored) != archivePath {
+			Test.Fatal("anchored path does not resolve to original path")
+		}
+	})
+
+	//GIVEN
+	os.Rename(primaryPath, archivePath)
+
+	t.Run("MoveAcrossRoots", func(Test *testing.T) {
+		//WHEN
+		results, ok := lib.Scan(nil, nil, false)
+		//THEN
+		if !ok || len(results) != 2 {
+			Test.Fatal("unexpected scan results:", results)
+		}
+		for _, result := range results {
+			switch result.AnchoredPath() {
+			case "bill.pdf":
+				if result.Status() != Missing {
+					Test.Error("original location not missing")
+				}
+			case document.QualifyAnchoredPath("archive", filepath.Join("2021", "bill.pdf")):
+				if result.Status() != Moved || result.ReferencedDocument() != doc {
+					Test.Error("new location not detected as moved")
+				}
+			default:
+				Test.Error("unexpected path", result.AnchoredPath())
+			}
+		}
+	})
+
+	//GIVEN
+	lib.SetDocumentPath(doc, archivePath)
+
+	t.Run("RemoveReferencedRoot", func(Test *testing.T) {
+		//WHEN
+		err := lib.RemoveNamedRoot("archive")
+		//THEN
+		if err == nil {
+			Test.Fatal("root removed although a record refers to it")
+		}
+	})
+
+	//GIVEN
+	lib.ForgetDocument(doc)
+
+	t.Run("RemoveUnreferencedRoot", func(Test *testing.T) {
+		//WHEN
+		err := lib.RemoveNamedRoot("archive")
+		//THEN
+		if err != nil {
+			Test.Fatal(err)
+		}
+		if len(lib.GetNamedRoots()) != 0 {
+			Test.Fatal("root still listed")
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	checksum "crypto/sha256"
 	"errors"
 	"fmt"
+	"github.com/n2code/doccurator/internal"
 	"github.com/n2code/doccurator/internal/document"
 	"io/fs"
 	"os"
@@ -16,6 +17,7 @@ type CheckedPath struct {
 	status       PathStatus
 	referencing  Document
 	err          error
+	attributeErr error //ID attribute of the file contradicts its record
 }
 
 // CheckFilePath deals with all combinations of the given path being on record and/or [not] existing in reality.
@@ -37,7 +39,7 @@ func (lib *library) CheckFilePath(absolutePath string, skipReadOnSizeMatch bool)
 
 	if doc, isOnActiveRecord := lib.activeAnchoredPathIndex[result.anchoredPath]; isOnActiveRecord {
 		result.referencing = Document{id: doc.Id(), library: lib}
-		switch status := doc.CompareToFileOnStorage(lib.rootPath, skipReadOnSizeMatch); status {
+		switch status := doc.CompareToFileOnStorage(lib.getRootDirectory(doc.Root()), skipReadOnSizeMatch); status {
 		case document.UnmodifiedFile:
 			result.status = Tracked
 		case document.TouchedFile:
@@ -49,6 +51,9 @@ func (lib *library) CheckFilePath(absolutePath string, skipReadOnSizeMatch bool)
 		case document.FileAccessError:
 			result.err = fmt.Errorf("could not access last known location (%s) of document %s", doc.AnchoredPath(), doc.Id())
 		}
+		if result.status == Tracked || result.status == Touched || result.status == Modified {
+			result.attributeErr = lib.checkIdAttribute(absolutePath, doc, result.status != Modified)
+		}
 		return
 	}
 
@@ -70,7 +75,7 @@ func (lib *library) CheckFilePath(absolutePath string, skipReadOnSizeMatch bool)
 		result.err = fmt.Errorf("path is not a file: %s", absolutePath)
 		return
 	}
-	fileChecksum, checksumErr := calculateFileChecksum(absolutePath)
+	fileChecksum, checksumErr := internal.Sha256OfFile(absolutePath)
 	if checksumErr != nil {
 		result.err = checksumErr
 		return
@@ -79,10 +84,11 @@ func (lib *library) CheckFilePath(absolutePath string, skipReadOnSizeMatch bool)
 	//file exists that is not on active record, match to known contents by checksum
 
 	foundMatchingNonEmptyActive := false
+	foundLinkedActive := false
 	foundMissingActive := false
 	foundMatchingNonEmptyObsolete := false
 
-	var anyMatchingNonEmptyActive, anyMissingMatchingActive, anyMatchingNonEmptyObsolete document.Api
+	var anyMatchingNonEmptyActive, anyMissingMatchingActive, anyMatchingNonEmptyObsolete, anyLinkedActive document.Api
 	for _, doc := range lib.documents {
 		if doc.MatchesChecksum(fileChecksum) {
 			size, _, _ := doc.RecordedFileProperties()
@@ -94,12 +100,17 @@ func (lib *library) CheckFilePath(absolutePath string, skipReadOnSizeMatch bool)
 				}
 				continue
 			}
-			statusOfContentMatch := doc.CompareToFileOnStorage(lib.rootPath, skipReadOnSizeMatch)
+			statusOfContentMatch := doc.CompareToFileOnStorage(lib.getRootDirectory(doc.Root()), skipReadOnSizeMatch)
 			switch statusOfContentMatch {
 			case document.UnmodifiedFile, document.TouchedFile:
 				if size > 0 {
 					foundMatchingNonEmptyActive = true
 					anyMatchingNonEmptyActive = doc
+					//a hardlink of the file on record is not another copy
+					if recordedStat, err := os.Stat(lib.Absolutize(doc.AnchoredPath())); err == nil && os.SameFile(stat, recordedStat) {
+						foundLinkedActive = true
+						anyLinkedActive = doc
+					}
 				}
 			case document.ModifiedFile:
 				//content has changed so a matching record is moot
@@ -113,22 +124,92 @@ func (lib *library) CheckFilePath(absolutePath string, skipReadOnSizeMatch bool)
 		}
 	}
 
+	//an ID (attribute or standardized filename) may link the file to a missing record even if the content differs
+	var missingLinkedById document.Api
+	if !foundMissingActive {
+		missingLinkedById = lib.findMissingRecordById(absolutePath, skipReadOnSizeMatch)
+	}
+
 	result.status = Untracked
 	switch { //the order of cases is significant because it reflects status priority!
 	case foundMissingActive:
 		result.status = Moved
 		result.referencing = Document{id: anyMissingMatchingActive.Id(), library: lib}
+	case missingLinkedById != nil:
+		result.status = MovedModified
+		result.referencing = Document{id: missingLinkedById.Id(), library: lib}
+	case foundLinkedActive:
+		result.status = Linked
+		result.referencing = Document{id: anyLinkedActive.Id(), library: lib}
 	case foundMatchingNonEmptyObsolete:
 		result.status = Obsolete
 		result.referencing = Document{id: anyMatchingNonEmptyObsolete.Id(), library: lib}
 	case foundMatchingNonEmptyActive:
 		result.status = Duplicate
 		result.referencing = Document{id: anyMatchingNonEmptyActive.Id(), library: lib}
+	default:
+		//as a last resort a missing record with the same filename and similar size is assumed to be the origin
+		if doc := lib.findMissingRecordBySimilarity(absolutePath, stat.Size(), skipReadOnSizeMatch); doc != nil {
+			result.status = MovedModified
+			result.referencing = Document{id: doc.Id(), library: lib}
+		}
 	}
 
 	return
 }
 
+// similarSizeTolerance is the maximum size difference relative to the larger size for files to be considered similar
+const similarSizeTolerance = 0.2
+
+// findMissingRecordById yields the active record whose file is missing and whose ID is stored in the ID attribute or the
+// standardized filename of the given file (in that order of precedence), nil if there is none
+func (lib *library) findMissingRecordById(absolutePath string, skipReadOnSizeMatch bool) document.Api {
+	var candidates []document.Id
+	if attribute, present, _ := lib.ReadIdAttribute(absolutePath); present {
+		candidates = append(candidates, attribute.Id)
+	}
+	if id, err := lib.ExtractIdFromFilename(filepath.Base(absolutePath)); err == nil {
+		candidates = append(candidates, id)
+	}
+	for _, id := range candidates {
+		if doc, exists := lib.documents[id]; exists && !doc.IsObsolete() &&
+			doc.CompareToFileOnStorage(lib.getRootDirectory(doc.Root()), skipReadOnSizeMatch) == document.NoFileFound {
+			return doc
+		}
+	}
+	return nil
+}
+
+// findMissingRecordBySimilarity yields the only active record whose file is missing and whose filename matches the
+// given file while the recorded size is similar, nil if there is none or the match is ambiguous
+func (lib *library) findMissingRecordBySimilarity(absolutePath string, size int64, skipReadOnSizeMatch bool) document.Api {
+	name := filepath.Base(absolutePath)
+	var match document.Api
+	for _, doc := range lib.documents {
+		if doc.IsObsolete() || filepath.Base(doc.AnchoredPath()) != name {
+			continue
+		}
+		recordedSize, _, _ := doc.RecordedFileProperties()
+		if !isSimilarSize(size, recordedSize) ||
+			doc.CompareToFileOnStorage(lib.getRootDirectory(doc.Root()), skipReadOnSizeMatch) != document.NoFileFound {
+			continue
+		}
+		if match != nil {
+			return nil //ambiguous
+		}
+		match = doc
+	}
+	return match
+}
+
+func isSimilarSize(a int64, b int64) bool {
+	larger, difference := a, a-b
+	if b > a {
+		larger, difference = b, b-a
+	}
+	return float64(difference) <= similarSizeTolerance*float64(larger)
+}
+
 func (p CheckedPath) Status() PathStatus {
 	return p.status
 }
@@ -141,12 +222,17 @@ func (p CheckedPath) GetError() error {
 	return p.err
 }
 
+// AttributeConflict reports an ID attribute of the file which contradicts its record (see WriteIdAttribute)
+func (p CheckedPath) AttributeConflict() error {
+	return p.attributeErr
+}
+
 func (p CheckedPath) ReferencedDocument() Document {
 	return p.referencing
 }
 
 func (p CheckedPath) ProbeFile(size *int64, modTime *time.Time, sha256 *[checksum.Size]byte) error {
-	absolute := filepath.Join(p.referencing.library.rootPath, p.anchoredPath)
+	absolute := p.referencing.library.Absolutize(p.anchoredPath)
 
 	if size != nil || modTime != nil {
 		stat, err := os.Stat(absolute)
@@ -162,11 +248,11 @@ func (p CheckedPath) ProbeFile(size *int64, modTime *time.Time, sha256 *[checksu
 	}
 
 	if sha256 != nil {
-		content, err := os.ReadFile(absolute)
+		sum, err := internal.Sha256OfFile(absolute)
 		if err != nil {
 			return err
 		}
-		*sha256 = checksum.Sum256(content)
+		*sha256 = sum
 	}
 
 	return nil
@@ -266,3 +266,152 @@ func TestPathChecking(t *testing.T) {
 		f{path: "X", contentOnRecord: "42", fileContent: emptyFile, expectedStatus: Modified, expectedRefToDocOf: 4})
 
 }
+
+func TestIdAttributeLinksMovedAndEditedFile(t *testing.T) {
+	//GIVEN
+	libRootDir, lib := setupLibraryInTemp(t)
+	original, moved := filepath.Join(libRootDir, "original"), filepath.Join(libRootDir, "renamed")
+	writeFile(original, "draft")
+	doc, _ := lib.CreateDocument(42)
+	lib.SetDocumentPath(doc, original)
+	lib.UpdateDocumentFromFile(doc)
+	if err := lib.WriteIdAttribute(doc, true); err != nil {
+		t.Skip("extended attributes unavailable:", err)
+	}
+	os.Rename(original, moved)
+	os.WriteFile(moved, []byte("final"), 0o600) //keeps attributes
+
+	//WHEN
+	check := lib.CheckFilePath(moved, false)
+
+	//THEN
+	if referenced := check.ReferencedDocument(); check.Status() != MovedModified || referenced.Id() != 42 {
+		t.Errorf("edited file not linked to its record, got status %s", check.Status())
+	}
+
+	t.Run("Consistency", func(Test *testing.T) {
+		os.Rename(moved, original)
+		lib.UpdateDocumentFromFile(doc) //refreshes the checksum attribute
+		if conflict := lib.CheckFilePath(original, false).AttributeConflict(); conflict != nil {
+			Test.Errorf("attribute not refreshed on update: %s", conflict)
+		}
+		other, _ := lib.CreateDocument(43)
+		copied := filepath.Join(libRootDir, "copy")
+		writeFile(copied, "copy")
+		lib.SetDocumentPath(other, copied)
+		lib.UpdateDocumentFromFile(other)
+		lib.WriteIdAttribute(doc, false)
+		attribute, _, _ := lib.ReadIdAttribute(original)
+		writeFileAttribute(copied, IdAttributeName, attribute.Id.String())
+		if lib.CheckFilePath(copied, false).AttributeConflict() == nil {
+			Test.Error("attribute of other record not reported")
+		}
+	})
+}
+
+func TestMovedAndModifiedDetection(t *testing.T) {
+	setup := func(t *testing.T, records map[document.Id]string) (libRootDir string, lib Api) {
+		libRootDir, lib = setupLibraryInTemp(t)
+		for id, anchored := range records {
+			path := filepath.Join(libRootDir, filepath.FromSlash(anchored))
+			os.MkdirAll(filepath.Dir(path), 0o700)
+			writeFile(path, "first draft of "+anchored)
+			doc, _ := lib.CreateDocument(id)
+			lib.SetDocumentPath(doc, path)
+			lib.UpdateDocumentFromFile(doc)
+			os.Remove(path)
+		}
+		return
+	}
+	place := func(libRootDir string, anchored string, content string) string {
+		path := filepath.Join(libRootDir, filepath.FromSlash(anchored))
+		os.MkdirAll(filepath.Dir(path), 0o700)
+		writeFile(path, content)
+		return path
+	}
+
+	t.Run("SameNameAndSimilarSize", func(Test *testing.T) {
+		//GIVEN
+		libRootDir, lib := setup(Test, map[document.Id]string{1: "inbox/letter.txt", 2: "inbox/other.txt"})
+		edited := place(libRootDir, "archive/letter.txt", "final draft of inbox/letter.txt")
+
+		//WHEN
+		check := lib.CheckFilePath(edited, false)
+
+		//THEN
+		if referenced := check.ReferencedDocument(); check.Status() != MovedModified || referenced.Id() != 1 {
+			Test.Errorf("edited file not linked to its missing record, got status %s", check.Status())
+		}
+	})
+
+	t.Run("SameNameButDissimilarSize", func(Test *testing.T) {
+		//GIVEN
+		libRootDir, lib := setup(Test, map[document.Id]string{1: "inbox/letter.txt"})
+		unrelated := place(libRootDir, "archive/letter.txt", "a completely different letter which is much longer than the draft")
+
+		//WHEN
+		status := lib.CheckFilePath(unrelated, false).Status()
+
+		//THEN
+		if status != Untracked {
+			Test.Errorf("unrelated file linked to record, got status %s", status)
+		}
+	})
+
+	t.Run("AmbiguousMatch", func(Test *testing.T) {
+		//GIVEN
+		libRootDir, lib := setup(Test, map[document.Id]string{1: "a/letter.txt", 2: "b/letter.txt"})
+		edited := place(libRootDir, "c/letter.txt", "final draft of a/letter.txt")
+
+		//WHEN
+		status := lib.CheckFilePath(edited, false).Status()
+
+		//THEN
+		if status != Untracked {
+			Test.Errorf("file linked to one of several candidates, got status %s", status)
+		}
+	})
+
+	t.Run("IdInStandardizedFilename", func(Test *testing.T) {
+		//GIVEN
+		libRootDir, lib := setup(Test, map[document.Id]string{1: "a/letter.txt", 2: "b/letter.txt"})
+		renamed := place(libRootDir, document.DefaultNameTemplate.Render("reply.txt", 2, time.Now()), "entirely rewritten")
+
+		//WHEN
+		check := lib.CheckFilePath(renamed, false)
+
+		//THEN
+		if referenced := check.ReferencedDocument(); check.Status() != MovedModified || referenced.Id() != 2 {
+			Test.Errorf("file not linked to the record of the ID in its name, got status %s", check.Status())
+		}
+	})
+}
+
+func TestHardlinkIsNotDuplicate(t *testing.T) {
+	//GIVEN
+	libRootDir, lib := setupLibraryInTemp(t)
+	original := filepath.Join(libRootDir, "original")
+	writeFile(original, "content")
+	doc, _ := lib.CreateDocument(1)
+	lib.SetDocumentPath(doc, original)
+	lib.UpdateDocumentFromFile(doc)
+	linked, copied := filepath.Join(libRootDir, "linked"), filepath.Join(libRootDir, "copied")
+	if err := os.Link(original, linked); err != nil {
+		t.Skip("hardlinks unavailable:", err)
+	}
+	writeFile(copied, "content")
+
+	//WHEN
+	linkedCheck, copiedCheck := lib.CheckFilePath(linked, false), lib.CheckFilePath(copied, false)
+
+	//THEN
+	if referenced := linkedCheck.ReferencedDocument(); linkedCheck.Status() != Linked || referenced.Id() != 1 {
+		t.Errorf("hardlink not recognized, got status %s", linkedCheck.Status())
+	}
+	if copiedCheck.Status() != Duplicate {
+		t.Errorf("copy not recognized as duplicate, got status %s", copiedCheck.Status())
+	}
+	if Linked.RepresentsWaste() || Linked.RepresentsChange() {
+		t.Error("hardlink considered waste or change")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -14,9 +15,13 @@ import (
 )
 
 const workInProgressFileSuffix = ".wip"
+
+// DatabaseCompanionSuffixes lists the suffixes of files which may accompany a library database file (appended to its path)
+var DatabaseCompanionSuffixes = []string{workInProgressFileSuffix}
+
 const databaseContentOpener = "LIBRARY>>>"
 const databaseContentTerminator = "<<<LIBRARY"
-const databaseSemanticVersion = "0.3.0"
+const databaseSemanticVersion = "0.13.0"
 const semVerPattern = `^(?P<major>0|[1-9]\d*)\.(?P<minor>0|[1-9]\d*)\.(?P<patch>0|[1-9]\d*)(?:-(?P<prerelease>(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?(?:\+(?P<buildmetadata>[0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`
 
 var semanticVersionRegex = regexp.MustCompile(semVerPattern)
@@ -37,6 +42,7 @@ func (lib *library) SaveToLocalFile(path string, overwrite bool) (err error) {
 		}
 	}
 
+	lib.databaseDirectory = filepath.Dir(path) //relative roots refer to it
 	tempPath := path + workInProgressFileSuffix
 
 	file, err := os.OpenFile(tempPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600|os.ModeExclusive)
@@ -91,6 +97,7 @@ func (lib *library) SaveToLocalFile(path string, overwrite bool) (err error) {
 	if err != nil {
 		return fmt.Errorf("replacing library file (%s) with temporary working copy (%s) failed: %w", path, tempPath, err)
 	}
+	lib.uuidPersisted = true
 
 	return nil
 }
@@ -146,6 +153,7 @@ func (lib *library) LoadFromLocalFile(path string) {
 	decoder := json.NewDecoder(decompressor)
 	decoder.DisallowUnknownFields()
 
+	lib.databaseDirectory = filepath.Dir(path) //relative roots refer to it
 	lib.documents = make(map[document.Id]document.Api)
 	lib.activeAnchoredPathIndex = make(map[string]document.Api)
 	lib.ignoredPaths = make(map[ignoredLibraryPath]bool)
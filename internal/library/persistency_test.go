@@ -77,3 +77,95 @@ func TestLibrarySaveAndReload(t *testing.T) {
 		t.Fatalf("library not reloaded correctly\nexpected:\n%s\ngot:\n%s", originalLibRecords.String(), loadedLibRecords.String())
 	}
 }
+
+func TestPortableRootPersistence(t *testing.T) {
+	//GIVEN
+	driveDir := t.TempDir()
+	rootDir := filepath.Join(driveDir, "documents")
+	libraryFilePath := filepath.Join(driveDir, "test.lib")
+	otherHostRootDir := t.TempDir()
+	lib := NewLibrary()
+	lib.MapRoot(rootDir, true, "")
+	lib.MapRoot(otherHostRootDir, false, "other-host")
+	lib.SaveToLocalFile(libraryFilePath, false)
+
+	//WHEN
+	remountedDir := t.TempDir()
+	os.Rename(libraryFilePath, filepath.Join(remountedDir, "test.lib"))
+	loadedLib := NewLibrary()
+	loadedLib.LoadFromLocalFile(filepath.Join(remountedDir, "test.lib"))
+
+	//THEN
+	if loadedLib.GetRoot() != filepath.Join(remountedDir, "documents") {
+		t.Fatal("relative root not resolved with respect to database location:", loadedLib.GetRoot())
+	}
+	if !loadedLib.IsRootRelativeToDatabase() {
+		t.Fatal("relative root not recognized as such after reload")
+	}
+
+	t.Run("HostOverride", func(Test *testing.T) {
+		//GIVEN
+		defer func(original func() string) { currentHostname = original }(currentHostname)
+		currentHostname = func() string { return "other-host" }
+		hostLib := NewLibrary()
+		//WHEN
+		hostLib.LoadFromLocalFile(filepath.Join(remountedDir, "test.lib"))
+		//THEN
+		if hostLib.GetRoot() != otherHostRootDir || hostLib.IsRootRelativeToDatabase() {
+			Test.Fatal("host-specific root not applied:", hostLib.GetRoot())
+		}
+	})
+}
+
+func TestIdentityPersistence(t *testing.T) {
+	//GIVEN
+	tmpDir := t.TempDir()
+	libraryFilePath := filepath.Join(tmpDir, "test.lib")
+	Lib := NewLibrary()
+	Lib.SetRoot(tmpDir)
+	Lib.SetName("Archive")
+	Lib.SetIdNamespace(42)
+	Lib.SetIdAllocation(IdAllocation{Strategy: AllocateFromBlock, First: 1000, Last: 1999})
+
+	//WHEN
+	Lib.SaveToLocalFile(libraryFilePath, false)
+	LoadedLib := NewLibrary()
+	LoadedLib.LoadFromLocalFile(libraryFilePath)
+
+	//THEN
+	if LoadedLib.GetUuid() != Lib.GetUuid() {
+		t.Errorf("UUID changed from %s to %s", Lib.GetUuid(), LoadedLib.GetUuid())
+	}
+	if LoadedLib.GetName() != "Archive" {
+		t.Errorf("name not reloaded, got %q", LoadedLib.GetName())
+	}
+	if LoadedLib.GetIdNamespace() != 42 {
+		t.Errorf("ID namespace not reloaded, got %d", LoadedLib.GetIdNamespace())
+	}
+	if allocation := LoadedLib.GetIdAllocation(); allocation != (IdAllocation{Strategy: AllocateFromBlock, First: 1000, Last: 1999}) {
+		t.Errorf("ID allocation not reloaded, got %+v", allocation)
+	}
+}
+
+func TestRetentionRulePersistence(t *testing.T) {
+	//GIVEN
+	tmpDir := t.TempDir()
+	libraryFilePath := filepath.Join(tmpDir, "test.lib")
+	Lib := NewLibrary()
+	Lib.SetRoot(tmpDir)
+	rule := RetentionRule{Years: 10, Basis: RetainSinceModified, EndOfYear: true}
+	if err := Lib.SetRetentionRule(filepath.Join(tmpDir, "invoices"), rule); err != nil {
+		t.Fatal(err)
+	}
+
+	//WHEN
+	Lib.SaveToLocalFile(libraryFilePath, false)
+	LoadedLib := NewLibrary()
+	LoadedLib.LoadFromLocalFile(libraryFilePath)
+
+	//THEN
+	rule.Directory = "invoices"
+	if rules := LoadedLib.GetRetentionRules(); len(rules) != 1 || rules[0] != rule {
+		t.Errorf("retention rules not reloaded, got %+v", rules)
+	}
+}
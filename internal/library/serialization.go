@@ -2,30 +2,101 @@ package library
 
 import (
 	"encoding/json"
+	"path/filepath"
 
 	"github.com/n2code/doccurator/internal/document"
 )
 
 type jsonLib struct {
-	LocalRoot string
-	Documents document.Index
+	Uuid                  string
+	Name                  string                  `json:",omitempty"`
+	IdNamespace           uint32                  `json:",omitempty"`
+	IdAllocation          *IdAllocation           `json:",omitempty"` //absent for the default allocation
+	NameTemplate          document.NameTemplate   `json:",omitempty"`
+	PreviousNameTemplates []document.NameTemplate `json:",omitempty"`
+	IdAttributes          IdAttributeMode         `json:",omitempty"`
+	LocalRoot             string                  //absolute or relative to the directory of the database file
+	HostRoots             map[string]string       `json:",omitempty"` //overrides of LocalRoot by hostname
+	NamedRoots            map[string]string       `json:",omitempty"`
+	RetentionRules        []RetentionRule         `json:",omitempty"`
+	Documents             document.Index
 }
 
 func (lib *library) MarshalJSON() ([]byte, error) {
 	root := jsonLib{
-		LocalRoot: lib.rootPath,
-		Documents: lib.documents,
+		Uuid:                  lib.uuid,
+		Name:                  lib.name,
+		IdNamespace:           lib.idNamespace,
+		LocalRoot:             lib.persistableRoot(lib.defaultRoot),
+		NamedRoots:            lib.namedRoots,
+		RetentionRules:        lib.GetRetentionRules(),
+		Documents:             lib.documents,
+		NameTemplate:          lib.nameTemplate,
+		PreviousNameTemplates: lib.previousNameTemplates,
+		IdAttributes:          lib.idAttributeMode,
+	}
+	if lib.idAllocation.Strategy != "" && lib.idAllocation.Strategy != AllocateByCurrentTime {
+		allocation := lib.idAllocation
+		root.IdAllocation = &allocation
+	}
+	if len(lib.hostRoots) > 0 {
+		root.HostRoots = make(map[string]string, len(lib.hostRoots))
+		for host, location := range lib.hostRoots {
+			root.HostRoots[host] = lib.persistableRoot(location)
+		}
 	}
 	return json.Marshal(root)
 }
 
+func (lib *library) persistableRoot(location rootLocation) string {
+	if location.relativeToDatabase && lib.databaseDirectory != "" {
+		if relative, err := filepath.Rel(lib.databaseDirectory, location.path); err == nil {
+			return relative
+		} //else fall back to absolute path, e.g. because the volumes differ
+	}
+	return location.path
+}
+
+func (lib *library) loadRoot(persisted string) rootLocation {
+	if filepath.IsAbs(persisted) {
+		return rootLocation{path: persisted}
+	}
+	return rootLocation{path: filepath.Join(lib.databaseDirectory, persisted), relativeToDatabase: true}
+}
+
 func (lib *library) UnmarshalJSON(blob []byte) error {
 	var loadedLib jsonLib
 	err := json.Unmarshal(blob, &loadedLib)
 	if err != nil {
 		panic(err) //must not occur because persisted library's format is versioned
 	}
-	lib.rootPath = loadedLib.LocalRoot
+	if loadedLib.Uuid != "" { //else keep the fresh one which is persisted with the next save
+		lib.uuid = loadedLib.Uuid
+	}
+	lib.uuidPersisted = loadedLib.Uuid != ""
+	lib.name = loadedLib.Name
+	lib.idNamespace = loadedLib.IdNamespace
+	lib.nameTemplate = loadedLib.NameTemplate
+	lib.previousNameTemplates = loadedLib.PreviousNameTemplates
+	lib.idAttributeMode = loadedLib.IdAttributes
+	lib.idAllocation = IdAllocation{}
+	if loadedLib.IdAllocation != nil {
+		lib.idAllocation = *loadedLib.IdAllocation
+	}
+	lib.defaultRoot = lib.loadRoot(loadedLib.LocalRoot)
+	lib.hostRoots = make(map[string]rootLocation, len(loadedLib.HostRoots))
+	for host, persisted := range loadedLib.HostRoots {
+		lib.hostRoots[host] = lib.loadRoot(persisted)
+	}
+	lib.rootPath = lib.getEffectiveRoot().path
+	lib.namedRoots = loadedLib.NamedRoots
+	if lib.namedRoots == nil {
+		lib.namedRoots = make(map[string]string)
+	}
+	lib.retentionRules = make(map[string]RetentionRule, len(loadedLib.RetentionRules))
+	for _, rule := range loadedLib.RetentionRules {
+		lib.retentionRules[rule.Directory] = rule
+	}
 	lib.documents = loadedLib.Documents
 	for _, doc := range lib.documents {
 		if !doc.IsObsolete() {
@@ -36,16 +107,18 @@ func (lib *library) UnmarshalJSON(blob []byte) error {
 }
 
 var pathStatusText = map[PathStatus]string{
-	Error:     "Error",
-	Untracked: "Untracked",
-	Tracked:   "Tracked",
-	Touched:   "Touched",
-	Modified:  "Modified",
-	Moved:     "Moved",
-	Removed:   "Removed",
-	Missing:   "Missing",
-	Duplicate: "Duplicate",
-	Obsolete:  "Obsolete",
+	Error:         "Error",
+	Untracked:     "Untracked",
+	Tracked:       "Tracked",
+	Touched:       "Touched",
+	Modified:      "Modified",
+	Moved:         "Moved",
+	MovedModified: "Moved+modified",
+	Linked:        "Linked",
+	Removed:       "Removed",
+	Missing:       "Missing",
+	Duplicate:     "Duplicate",
+	Obsolete:      "Obsolete",
 }
 
 func (s PathStatus) String() string {
@@ -5,15 +5,33 @@ import (
 )
 
 type ignoredLibraryPath struct {
-	anchored  string //relative to library root, system-native path
+	anchored  string //relative to library root (qualified for secondary roots), system-native path
 	directory bool   //match directories iff set else match only files
 }
 
+type rootLocation struct {
+	path               string //absolute, system-native path
+	relativeToDatabase bool   //persisted relative to the directory of the library database file
+}
+
 type library struct {
+	uuid                    string //unique identity of the library, random (version 4)
+	uuidPersisted           bool   //false for a fresh library and for databases created before libraries had an identity
+	name                    string //human-readable, optional
+	idNamespace             uint32 //namespace of newly generated document IDs, 0 if none
+	idAllocation            IdAllocation
+	nameTemplate            document.NameTemplate   //format of standardized filenames, empty for the default
+	previousNameTemplates   []document.NameTemplate //formerly used templates whose names are still recognized, most recent first
+	idAttributeMode         IdAttributeMode         //extended attributes written when files are added, empty for none
 	documents               map[document.Id]document.Api
 	activeAnchoredPathIndex map[string]document.Api     //active paths represent non-obsolete documents
-	rootPath                string                      //absolute, system-native path
+	rootPath                string                      //absolute, system-native path of the primary root (effective on this host)
+	defaultRoot             rootLocation                //primary root unless overridden for this host
+	hostRoots               map[string]rootLocation     //primary root overrides by hostname
+	namedRoots              map[string]string           //absolute, system-native paths of secondary roots by name
+	retentionRules          map[string]RetentionRule    //by anchored directory
 	ignoredPaths            map[ignoredLibraryPath]bool //true for all keys
+	databaseDirectory       string                      //absolute, system-native path, known once the library has been saved or loaded
 }
 
 type orderedDocuments []document.Api
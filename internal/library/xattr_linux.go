@@ -0,0 +1,40 @@
+//go:build linux
+
+package library
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+const fileAttributesSupported = true
+
+// readFileAttribute yields the value of an extended attribute, filesystems without support report the attribute as absent
+func readFileAttribute(path string, name string) (value string, present bool, err error) {
+	size, err := unix.Getxattr(path, name, nil)
+	for err == nil {
+		buffer := make([]byte, size)
+		var read int
+		if read, err = unix.Getxattr(path, name, buffer); err == nil {
+			return string(buffer[:read]), true, nil
+		} else if errors.Is(err, unix.ERANGE) { //grown meanwhile
+			size, err = unix.Getxattr(path, name, nil)
+		}
+	}
+	if errors.Is(err, unix.ENODATA) || errors.Is(err, unix.ENOTSUP) {
+		return "", false, nil
+	}
+	return "", false, err
+}
+
+func writeFileAttribute(path string, name string, value string) error {
+	return unix.Setxattr(path, name, []byte(value), 0)
+}
+
+func removeFileAttribute(path string, name string) error {
+	if err := unix.Removexattr(path, name); err != nil && !errors.Is(err, unix.ENODATA) {
+		return err
+	}
+	return nil
+}
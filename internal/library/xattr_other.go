@@ -0,0 +1,17 @@
+//go:build !linux
+
+package library
+
+const fileAttributesSupported = false
+
+func readFileAttribute(string, string) (value string, present bool, err error) {
+	return "", false, nil
+}
+
+func writeFileAttribute(string, string, string) error {
+	return errFileAttributesUnsupported
+}
+
+func removeFileAttribute(string, string) error {
+	return nil
+}
@@ -0,0 +1,210 @@
+package doccurator
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/n2code/doccurator/internal/library"
+	out "github.com/n2code/doccurator/internal/output"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const libraryLocatorPermissions = 0o440 //owner and group can read
+
+// locatorFormatHeader is the first line of versioned locator files.
+// Locators without it consist of a single file URL pointing to the database (legacy format).
+const locatorFormatHeader = "doccurator-locator 2"
+const locatorLibraryKey = "library"
+const locatorDatabaseKey = "database"
+
+// libraryLocator is the content of a locator file which points to the database of the library whose root directory contains it
+type libraryLocator struct {
+	libraryUuid string             //empty for legacy locators which do not carry it
+	candidates  []locatorCandidate //in order of priority
+}
+
+type locatorCandidate struct {
+	libFile  string //absolute, system-native path
+	relative bool   //referenced relative to the directory of the locator
+}
+
+func (l libraryLocator) refersTo(libFile string) bool {
+	for _, candidate := range l.candidates {
+		if candidate.libFile == libFile {
+			return true
+		}
+	}
+	return false
+}
+
+// createLocatorFile places a library locator in the given directory which lists the database file both relative to the directory and absolute.
+// The relative reference takes precedence if requested.
+func (d *doccurator) createLocatorFile(directory string, preferRelative bool, deleteExisting bool) error {
+	path := filepath.Join(directory, library.LocatorFileName)
+
+	if deleteExisting {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("updating library locator (%s) failed: %w", path, err)
+		}
+	}
+
+	absoluteUri := url.URL{Scheme: "file", Path: filepath.ToSlash(d.libFile)}
+	uris := []string{absoluteUri.String()}
+	if relativeLibFile, err := filepath.Rel(directory, d.libFile); err == nil { //else only absolute path, e.g. because the volumes differ
+		escapedPath := (&url.URL{Path: filepath.ToSlash(relativeLibFile)}).EscapedPath()
+		relativeUri := url.URL{Scheme: "file", Opaque: escapedPath} //i.e. "file:../doccurator.db"
+		if preferRelative {
+			uris = append([]string{relativeUri.String()}, uris...)
+		} else {
+			uris = append(uris, relativeUri.String())
+		}
+	}
+
+	var content strings.Builder
+	fmt.Fprintf(&content, "%s\n%s %s\n", locatorFormatHeader, locatorLibraryKey, d.appLib.GetUuid())
+	for _, uri := range uris {
+		fmt.Fprintf(&content, "%s %s\n", locatorDatabaseKey, uri)
+	}
+	if err := os.WriteFile(path, []byte(content.String()), libraryLocatorPermissions); err != nil {
+		return fmt.Errorf("writing library locator (%s) failed: %w", path, err)
+	}
+	d.Print(out.Verbose, "Created library locator %s\n", path)
+	return nil
+}
+
+// readLocatorFile parses both the versioned and the legacy locator format
+func readLocatorFile(path string) (locator libraryLocator, err error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("bad library locator file (%s): %w", path, err)
+		}
+	}()
+
+	lines := bufio.NewScanner(strings.NewReader(string(contents)))
+	if !lines.Scan() {
+		err = errors.New("empty")
+		return
+	}
+	if header := lines.Text(); header != locatorFormatHeader {
+		if strings.HasPrefix(header, strings.Fields(locatorFormatHeader)[0]) {
+			err = fmt.Errorf(`unsupported format "%s"`, header)
+			return
+		}
+		candidate, uriErr := parseLocatorUri(header, filepath.Dir(path)) //legacy format
+		if uriErr != nil {
+			err = uriErr
+			return
+		}
+		locator.candidates = append(locator.candidates, candidate)
+		return
+	}
+
+	for lines.Scan() {
+		key, value, _ := strings.Cut(strings.TrimSpace(lines.Text()), " ")
+		switch key {
+		case locatorLibraryKey:
+			locator.libraryUuid = value
+		case locatorDatabaseKey:
+			candidate, uriErr := parseLocatorUri(value, filepath.Dir(path))
+			if uriErr != nil {
+				err = uriErr
+				return
+			}
+			locator.candidates = append(locator.candidates, candidate)
+		} //unknown keys are skipped to allow for compatible extensions
+	}
+	if len(locator.candidates) == 0 {
+		err = errors.New("no database location given")
+	}
+	return
+}
+
+func parseLocatorUri(text string, locatorDirectory string) (candidate locatorCandidate, err error) {
+	uri, err := url.Parse(text)
+	if err != nil {
+		return
+	}
+	if uri.Scheme != "file" {
+		err = fmt.Errorf(`scheme of URL missing or unsupported: "%s"`, uri.Scheme)
+		return
+	}
+	if uri.Opaque != "" { //relative to the directory of the locator
+		relativeLibFile, unescapeErr := url.PathUnescape(uri.Opaque)
+		if unescapeErr != nil {
+			err = unescapeErr
+			return
+		}
+		return locatorCandidate{libFile: filepath.Join(locatorDirectory, filepath.FromSlash(relativeLibFile)), relative: true}, nil
+	}
+	if !filepath.IsAbs(filepath.FromSlash(uri.Path)) {
+		err = fmt.Errorf(`no absolute path: "%s"`, uri.Path)
+		return
+	}
+	return locatorCandidate{libFile: filepath.FromSlash(uri.Path)}, nil
+}
+
+// discoverLibrary searches the given directory and its parents for a library locator and loads the library it points to.
+// Database candidates are tried in order of priority, the first one that exists and belongs to the library of the locator is used.
+func (d *doccurator) discoverLibrary(startingDirectoryAbsolute string) (err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("library not found: %w", err)
+		}
+	}()
+	currentDir := startingDirectoryAbsolute
+	for {
+		locatorPath := filepath.Join(currentDir, library.LocatorFileName)
+		stat, statErr := os.Stat(locatorPath)
+		if statErr == nil && stat.Mode().IsRegular() {
+			return d.loadLibraryViaLocator(locatorPath)
+		} else if errors.Is(statErr, os.ErrNotExist) {
+			if currentDir == "/" {
+				return errors.New("stopping at filesystem root")
+			}
+			currentDir = filepath.Dir(currentDir)
+		} else {
+			return statErr
+		}
+	}
+}
+
+func (d *doccurator) loadLibraryViaLocator(locatorPath string) error {
+	locator, err := readLocatorFile(locatorPath)
+	if err != nil {
+		return err
+	}
+	var problems []string
+	tried := make(map[string]bool)
+	for _, candidate := range locator.candidates {
+		if tried[candidate.libFile] { //relative and absolute reference may resolve to the same file
+			continue
+		}
+		tried[candidate.libFile] = true
+		if _, statErr := os.Stat(candidate.libFile); statErr != nil {
+			problems = append(problems, statErr.Error())
+			continue
+		}
+		loaded, loadErr := loadForeignLibrary(candidate.libFile) //a broken candidate must not prevent the fallback to the next one
+		if loadErr != nil {
+			problems = append(problems, loadErr.Error())
+			continue
+		}
+		if locator.libraryUuid != "" && loaded.GetUuid() != locator.libraryUuid && loaded.IsUuidPersisted() { //a database without identity is recognized by its path
+			problems = append(problems, fmt.Sprintf("%s belongs to a different library (%s instead of %s)", candidate.libFile, loaded.GetUuid(), locator.libraryUuid))
+			continue
+		}
+		d.appLib, d.libFile = loaded, candidate.libFile
+		d.Print(out.Verbose, "Loaded library rooted at %s from %s\n", d.appLib.GetRoot(), d.libFile)
+		d.Print(out.Verbose, "Used library locator %s\n", locatorPath)
+		return nil
+	}
+	d.appLib, d.libFile = nil, ""
+	return fmt.Errorf("no usable database listed in library locator %s: %s", locatorPath, strings.Join(problems, "; "))
+}
@@ -0,0 +1,92 @@
+//go:build !windows
+
+package doccurator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadLocatorFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    libraryLocator
+		wantErr bool
+	}{
+		{name: "Legacy", content: "file:///my/lib.db", want: libraryLocator{candidates: []locatorCandidate{{libFile: "/my/lib.db"}}}},
+		{name: "LegacyGarbage", content: "::garbage", wantErr: true},
+		{name: "Versioned", content: "doccurator-locator 2\nlibrary 1234\ndatabase file:../lib%20file.db\ndatabase file:///my/lib.db\n",
+			want: libraryLocator{libraryUuid: "1234", candidates: []locatorCandidate{{libFile: "/my/lib file.db", relative: true}, {libFile: "/my/lib.db"}}}},
+		{name: "VersionedWithUnknownKey", content: "doccurator-locator 2\nfuture stuff\ndatabase file:///my/lib.db\n",
+			want: libraryLocator{candidates: []locatorCandidate{{libFile: "/my/lib.db"}}}},
+		{name: "VersionedWithoutDatabase", content: "doccurator-locator 2\nlibrary 1234\n", wantErr: true},
+		{name: "UnsupportedVersion", content: "doccurator-locator 99\n", wantErr: true},
+		{name: "BadScheme", content: "http://example.com/lib.db", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(Test *testing.T) {
+			//GIVEN
+			locatorDir := filepath.Join(Test.TempDir(), "my", "root")
+			os.MkdirAll(locatorDir, 0o700)
+			locatorPath := filepath.Join(locatorDir, ".doccurator")
+			os.WriteFile(locatorPath, []byte(tt.content), 0o600)
+			for i := range tt.want.candidates {
+				if tt.want.candidates[i].relative {
+					tt.want.candidates[i].libFile = filepath.Join(filepath.Dir(locatorDir), filepath.Base(tt.want.candidates[i].libFile))
+				}
+			}
+			//WHEN
+			got, err := readLocatorFile(locatorPath)
+			//THEN
+			if (err != nil) != tt.wantErr {
+				Test.Fatalf("readLocatorFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.libraryUuid != tt.want.libraryUuid || len(got.candidates) != len(tt.want.candidates) {
+				Test.Fatalf("readLocatorFile() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got.candidates {
+				if got.candidates[i] != tt.want.candidates[i] {
+					Test.Errorf("candidate %d = %+v, want %+v", i, got.candidates[i], tt.want.candidates[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLocatorFallbackPastBrokenDatabase(t *testing.T) {
+	//GIVEN
+	config := testConfig(t)
+	root := t.TempDir()
+	valid := filepath.Join(t.TempDir(), "valid.db")
+	if _, err := New(root, valid, RootMapping{}, config); err != nil {
+		t.Fatal(err)
+	}
+	corrupt := filepath.Join(t.TempDir(), "corrupt.db")
+	os.WriteFile(corrupt, []byte("garbage"), 0o600)
+	os.Remove(filepath.Join(root, ".doccurator")) //locator is read-only
+	os.WriteFile(filepath.Join(root, ".doccurator"), []byte("doccurator-locator 2\ndatabase file://"+corrupt+"\ndatabase file://"+valid+"\n"), 0o600)
+
+	//WHEN
+	handle, err := Open(root, config)
+
+	//THEN
+	if err != nil {
+		t.Fatalf("fallback to second candidate failed: %s", err)
+	}
+	if libFile := handle.(*doccurator).libFile; libFile != valid {
+		t.Errorf("expected database %s but got %s", valid, libFile)
+	}
+
+	t.Run("NoUsableCandidate", func(Test *testing.T) {
+		os.Remove(filepath.Join(root, ".doccurator"))
+		os.WriteFile(filepath.Join(root, ".doccurator"), []byte("doccurator-locator 2\ndatabase file://"+corrupt+"\n"), 0o600)
+		if _, err := Open(root, config); err == nil {
+			Test.Error("corrupt database accepted")
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package doccurator
+
+import (
+	"errors"
+	"fmt"
+	"github.com/n2code/doccurator/internal/document"
+	"github.com/n2code/doccurator/internal/library"
+	out "github.com/n2code/doccurator/internal/output"
+	"sort"
+)
+
+func (d *doccurator) MergeFrom(otherDatabase string, strategy MergeStrategy) (changeCount int, err error) {
+	if strategy.PathCollision == Renumber || strategy.Divergence == Renumber {
+		return 0, errors.New("renumbering only resolves ID collisions")
+	}
+	absoluteOther := mustAbsFilepath(otherDatabase)
+	if absoluteOther == d.libFile {
+		return 0, fmt.Errorf("library database %s cannot be merged into itself", absoluteOther)
+	}
+	other, err := loadForeignLibrary(absoluteOther)
+	if err != nil {
+		return 0, err
+	}
+	d.Print(out.Normal, "Merging records of library [%s] from %s\n", other.GetUuid(), absoluteOther)
+
+	var theirs []library.Document
+	other.VisitAllRecords(func(doc library.Document) {
+		theirs = append(theirs, doc)
+	})
+	sort.Slice(theirs, func(i, j int) bool {
+		return theirs[i].AnchoredPath() < theirs[j].AnchoredPath()
+	})
+
+	m := merger{d: d, strategy: strategy}
+	for _, doc := range theirs {
+		if err = m.mergeRecord(doc); err != nil {
+			return m.changes, fmt.Errorf("merge of record %s failed: %w", doc.Id(), err)
+		}
+	}
+	d.Print(out.Normal, "%d %s to merge, %d %s kept, %d %s already in sync.\n",
+		m.changes, out.Plural(m.changes, "change", "changes"),
+		m.kept, out.Plural(m.kept, "conflicting record", "conflicting records"),
+		m.unchanged, out.Plural(m.unchanged, "record", "records"))
+	return m.changes, nil
+}
+
+// merger applies the records of another library to the library of the handle one by one
+type merger struct {
+	d         *doccurator
+	strategy  MergeStrategy
+	changes   int
+	kept      int
+	unchanged int
+}
+
+func (m *merger) mergeRecord(theirs library.Document) error {
+	theirRecord := makeRecord(theirs)
+	if root, _ := document.SplitAnchoredPath(theirRecord.AnchoredPath); root != "" {
+		if _, known := m.d.appLib.GetNamedRoots()[root]; !known {
+			return fmt.Errorf("library root %q unknown, add it first (%s)", root, displayableAnchoredPath(theirRecord.AnchoredPath))
+		}
+	}
+
+	ours, exists := m.d.appLib.GetDocumentById(theirRecord.Id)
+	if !exists {
+		return m.importRecord(theirs, theirRecord.Id, library.Document{}, "new")
+	}
+
+	ourRecord := makeRecord(ours)
+	if ourRecord.AnchoredPath == theirRecord.AnchoredPath && ourRecord.Sha256 == theirRecord.Sha256 && ourRecord.Retired == theirRecord.Retired && ourRecord.Modified.Equal(theirRecord.Modified) {
+		m.unchanged++
+		return nil
+	}
+
+	//copies of the same record share the time of recording and usually either path or content (IDs derived from the time may collide nevertheless)
+	conflict, resolution := "ID collision", m.strategy.IdCollision
+	if ourRecord.Recorded.Equal(theirRecord.Recorded) && (ourRecord.AnchoredPath == theirRecord.AnchoredPath || ourRecord.Sha256 == theirRecord.Sha256) {
+		conflict, resolution = "diverged", m.strategy.Divergence
+	}
+	switch resolution {
+	case KeepOurs:
+		m.keep(ourRecord, conflict)
+		return nil
+	case TakeNewer:
+		if !theirRecord.Changed.After(ourRecord.Changed) {
+			m.keep(ourRecord, conflict+", ours newer")
+			return nil
+		}
+		return m.importRecord(theirs, theirRecord.Id, ours, conflict+", theirs newer")
+	case TakeTheirs:
+		return m.importRecord(theirs, theirRecord.Id, ours, conflict)
+	case Renumber:
+		id, err := m.d.GetFreeId()
+		if err != nil {
+			return fmt.Errorf("%s not renumbered: %w", theirRecord.Id, err)
+		}
+		return m.importRecord(theirs, id, library.Document{}, conflict+", renumbered")
+	}
+	return fmt.Errorf("unknown resolution %d", resolution)
+}
+
+// importRecord copies the given record under the given ID, replacing the given record of this library if set.
+// Path collisions with other active records are resolved according to the strategy.
+func (m *merger) importRecord(theirs library.Document, id document.Id, replaced library.Document, reason string) error {
+	theirRecord := makeRecord(theirs)
+	if !theirRecord.Retired {
+		occupant, occupied := m.d.appLib.GetActiveDocumentByPath(m.d.appLib.Absolutize(theirRecord.AnchoredPath))
+		if occupied && (replaced == library.Document{} || occupant.Id() != replaced.Id()) {
+			occupantRecord := makeRecord(occupant)
+			conflict := fmt.Sprintf("path collision with %s", theirRecord.Id)
+			switch m.strategy.PathCollision {
+			case KeepOurs:
+				m.keep(occupantRecord, conflict)
+				return nil
+			case TakeNewer:
+				if !theirRecord.Changed.After(occupantRecord.Changed) {
+					m.keep(occupantRecord, conflict+", ours newer")
+					return nil
+				}
+			}
+			m.d.appLib.MarkDocumentAsObsolete(occupant)
+			m.changes++
+			m.d.Print(out.Normal, "  - retire  [%s] %s (%s)\n", occupantRecord.Id, displayableAnchoredPath(occupantRecord.AnchoredPath), conflict)
+		}
+	}
+
+	action := "import "
+	if replaced != (library.Document{}) {
+		m.d.appLib.ForgetDocument(replaced)
+		action = "replace"
+	}
+	if _, err := m.d.appLib.ImportDocument(theirs, id); err != nil {
+		return err
+	}
+	m.changes++
+	details := reason
+	if theirRecord.Retired {
+		details += ", retired"
+	}
+	if id != theirRecord.Id {
+		details += fmt.Sprintf(", was %s", theirRecord.Id)
+	}
+	m.d.Print(out.Normal, "  + %s [%s] %s (%s)\n", action, id, displayableAnchoredPath(theirRecord.AnchoredPath), details)
+	return nil
+}
+
+func (m *merger) keep(ours Record, conflict string) {
+	m.kept++
+	m.d.Print(out.Normal, "  = keep    [%s] %s (%s)\n", ours.Id, displayableAnchoredPath(ours.AnchoredPath), conflict)
+}
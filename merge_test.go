@@ -0,0 +1,111 @@
+package doccurator
+
+import (
+	"errors"
+	"github.com/n2code/doccurator/internal/library"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeFrom(t *testing.T) {
+	// setup creates a library and a diverged copy of its database:
+	// "shared" is modified in the copy, "collision" exists in both with the same ID but unrelated content and path,
+	// "new" exists only in the copy, and "occupied" is a different document at the same path in both
+	setup := func(Test *testing.T) (ours *doccurator, theirDatabase string, root string) {
+		root, ours = newTestLibrary(Test, nil)
+		addTestFile(Test, ours, 1, "shared", "original")
+		ours.PersistChanges()
+
+		theirDatabase = filepath.Join(Test.TempDir(), "theirs.db")
+		theirs := makeDoccurator(testConfig(Test))
+		theirs.libFile = theirDatabase
+		theirs.appLib = ours.appLib
+		theirs.PersistChanges() //copy of the current state
+		theirs.loadLibrary()
+
+		addTestFile(Test, ours, 2, "ours_only", "ours")
+		addTestFile(Test, ours, 4, "occupied", "occupied by us")
+		ours.PersistChanges()
+
+		theirs.UpdateByPath(writeTestFile(Test, root, "shared", "changed by them"))
+		addTestFile(Test, theirs, 2, "theirs_only", "theirs")
+		addTestFile(Test, theirs, 3, "new", "new")
+		addTestFile(Test, theirs, 5, "occupied", "occupied by them")
+		if err := theirs.PersistChanges(); err != nil {
+			Test.Fatal(err)
+		}
+		return
+	}
+
+	t.Run("KeepOurs", func(Test *testing.T) {
+		//GIVEN
+		ours, theirDatabase, _ := setup(Test)
+
+		//WHEN
+		changes, err := ours.MergeFrom(theirDatabase, MergeStrategy{})
+
+		//THEN
+		if err != nil {
+			Test.Fatal(err)
+		}
+		if changes != 1 {
+			Test.Errorf("expected only the new record to be imported but got %d changes", changes)
+		}
+		if record, exists := ours.GetRecord(3); !exists || record.AnchoredPath != "new" {
+			Test.Error("new record not imported")
+		}
+		if record, _ := ours.GetRecord(2); record.AnchoredPath != "ours_only" {
+			Test.Error("colliding record replaced")
+		}
+		if _, exists := ours.GetRecord(5); exists {
+			Test.Error("record at occupied path imported")
+		}
+	})
+
+	t.Run("TakeTheirsAndRenumber", func(Test *testing.T) {
+		//GIVEN
+		ours, theirDatabase, _ := setup(Test)
+		strategy := MergeStrategy{IdCollision: Renumber, PathCollision: TakeTheirs, Divergence: TakeTheirs}
+
+		//WHEN
+		_, err := ours.MergeFrom(theirDatabase, strategy)
+
+		//THEN
+		if err != nil {
+			Test.Fatal(err)
+		}
+		if shared, _ := ours.GetRecord(1); shared.Size != int64(len("changed by them")) {
+			Test.Error("diverged record not replaced")
+		}
+		if record, _ := ours.GetRecord(2); record.AnchoredPath != "ours_only" {
+			Test.Error("colliding record replaced instead of renumbering the other")
+		}
+		renumbered := false
+		ours.appLib.VisitAllRecords(func(doc library.Document) {
+			renumbered = renumbered || (doc.AnchoredPath() == "theirs_only" && doc.Id() != 2)
+		})
+		if !renumbered {
+			Test.Error("colliding record not renumbered")
+		}
+		if occupant, _ := ours.GetRecord(4); !occupant.Retired {
+			Test.Error("record at path taken by theirs not retired")
+		}
+		if record, exists := ours.GetRecord(5); !exists || record.Retired {
+			Test.Error("record at occupied path not imported")
+		}
+	})
+
+	t.Run("RenumberWithoutFreeId", func(Test *testing.T) {
+		//GIVEN
+		ours, theirDatabase, _ := setup(Test)
+		ours.SetIdAllocation(library.IdAllocation{Strategy: library.AllocateFromBlock, First: 1, Last: 2})
+
+		//WHEN
+		_, err := ours.MergeFrom(theirDatabase, MergeStrategy{IdCollision: Renumber})
+
+		//THEN
+		if !errors.Is(err, idRangeExhaustedError) {
+			Test.Errorf("expected exhausted ID range to be reported but got %v", err)
+		}
+	})
+}
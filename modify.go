@@ -11,7 +11,7 @@ import (
 func (d *doccurator) UpdateByPath(filePath string) error {
 	absoluteFilePath := mustAbsFilepath(filePath)
 	switch check := d.appLib.CheckFilePath(absoluteFilePath, false); check.Status() { //check on update must be accurate hence no performance optimization
-	case library.Moved:
+	case library.Moved, library.MovedModified:
 		err := d.appLib.SetDocumentPath(check.ReferencedDocument(), absoluteFilePath)
 		internal.AssertNoError(err, "path already checked to be inside library and moved implies no conflicting record")
 		fallthrough
@@ -26,7 +26,7 @@ func (d *doccurator) UpdateByPath(filePath string) error {
 		return fmt.Errorf("no file found: %s", filePath)
 	case library.Missing:
 		return fmt.Errorf("use retire to accept missing file: %s", filePath)
-	case library.Untracked, library.Duplicate:
+	case library.Untracked, library.Duplicate, library.Linked:
 		return fmt.Errorf("path not on record: %s", filePath)
 	case library.Obsolete:
 		return fmt.Errorf("path already retired: %s", filePath)
@@ -50,6 +50,22 @@ func (d *doccurator) RetireByPath(path string) error {
 	return nil
 }
 
+func (d *doccurator) RemoveWasteByPath(path string) error {
+	absPath := mustAbsFilepath(path)
+	switch check := d.appLib.CheckFilePath(absPath, false); check.Status() { //deletion must be based on accurate content comparison hence no performance optimization
+	case library.Duplicate, library.Obsolete:
+		if err := d.stageFileDeletion(absPath); err != nil {
+			return fmt.Errorf("deletion failed: %s: %w", path, err)
+		}
+		d.Print(out.Normal, "Marked for delete: %s\n", d.displayablePath(absPath, true, false))
+	case library.Error:
+		return fmt.Errorf("path access failed: %s: %w", path, check.GetError())
+	default:
+		return fmt.Errorf("path has neither duplicate nor obsolete content: %s", path)
+	}
+	return nil
+}
+
 func (d *doccurator) ForgetAllObsolete() {
 	d.appLib.VisitAllRecords(func(doc library.Document) {
 		if doc.IsObsolete() {
@@ -0,0 +1,89 @@
+package doccurator
+
+import (
+	"errors"
+	"fmt"
+	"github.com/n2code/doccurator/internal"
+	"github.com/n2code/doccurator/internal/document"
+	"github.com/n2code/doccurator/internal/library"
+	out "github.com/n2code/doccurator/internal/output"
+	"path/filepath"
+	"time"
+)
+
+func (d *doccurator) SetNameTemplate(template string) error {
+	if err := d.appLib.SetNameTemplate(document.NameTemplate(template)); err != nil {
+		return err
+	}
+	d.Print(out.Normal, "Name template set to %s\n", template)
+	return nil
+}
+
+func (d *doccurator) PrintNameTemplates() {
+	templates := d.appLib.GetRecognizedNameTemplates()
+	d.Print(out.Required, "Name template: %s\n", templates[0])
+	d.Print(out.Normal, "Example:       %s\n", templates[0].Render("notes.txt", document.Id(internal.UnixTimestampNow()), time.Now()))
+	for _, previous := range templates[1:] {
+		d.Print(out.Required, "Recognized:    %s\n", previous)
+	}
+	d.Print(out.Required, "ID attribute:  %s\n", d.appLib.GetIdAttributeMode())
+}
+
+func (d *doccurator) SetIdAttributeMode(mode library.IdAttributeMode) error {
+	if err := d.appLib.SetIdAttributeMode(mode); err != nil {
+		return err
+	}
+	d.Print(out.Normal, "ID attribute mode set to %s\n", mode)
+	return nil
+}
+
+func (d *doccurator) WriteIdAttributes() (writtenCount int, err error) {
+	mode := d.appLib.GetIdAttributeMode()
+	if mode == library.NoIdAttribute {
+		return 0, errors.New("no ID attributes to write because attribute mode is " + string(mode))
+	}
+	failed, skipped := 0, 0
+	d.appLib.VisitAllRecords(func(doc library.Document) {
+		if doc.IsObsolete() {
+			return
+		}
+		absolute := d.appLib.Absolutize(doc.AnchoredPath())
+		if status := d.appLib.CheckFilePath(absolute, d.optimizedFsAccess).Status(); status != library.Tracked && status != library.Touched {
+			skipped++
+			d.Print(out.Verbose, "  ? skipped   [%s] %s (%s)\n", doc.Id(), displayableAnchoredPath(doc.AnchoredPath()), status)
+			return
+		}
+		if writeErr := d.appLib.WriteIdAttribute(doc, mode == library.IdAttributeWithHash); writeErr != nil {
+			failed++
+			d.Print(out.Error, "%s\n", writeErr)
+			return
+		}
+		writtenCount++
+	})
+	d.Print(out.Normal, "ID attributes written to %d %s, %d skipped because not in order.\n", writtenCount, out.Plural(writtenCount, "file", "files"), skipped)
+	if failed > 0 {
+		return writtenCount, fmt.Errorf("%d %s not written", failed, out.Plural(failed, "attribute", "attributes"))
+	}
+	return writtenCount, nil
+}
+
+func (d *doccurator) MigrateFilenames(dryRun bool) (renamedCount int, err error) {
+	templates := d.appLib.GetRecognizedNameTemplates()
+	var candidates []library.Document
+	d.appLib.VisitAllRecords(func(doc library.Document) {
+		if doc.IsObsolete() {
+			return
+		}
+		for _, template := range templates {
+			if _, matches := template.Parse(filepath.Base(doc.AnchoredPath())); matches {
+				candidates = append(candidates, doc)
+				return
+			}
+		}
+	})
+	plan, err := d.renameAll(candidates, false, dryRun)
+	if err != nil {
+		return 0, err
+	}
+	return len(plan.Renames), nil
+}
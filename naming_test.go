@@ -0,0 +1,54 @@
+package doccurator
+
+import (
+	"github.com/n2code/doccurator/internal/document"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateFilenames(t *testing.T) {
+	//GIVEN
+	root, lib := newTestLibrary(t, map[document.Id]string{1: "standardized.txt", 2: "plain.txt"})
+	lib.StandardizeLocation(1)
+	legacyName := "standardized.txt." + document.Id(1).String() + ".ndoc.txt"
+	newName := document.Id(1).String() + "-standardized.txt"
+	lib.PersistChanges()
+
+	//WHEN
+	templateErr := lib.SetNameTemplate("{id}-{name}{ext}")
+	renamed, err := lib.MigrateFilenames(false)
+
+	//THEN
+	if templateErr != nil || err != nil {
+		t.Fatal(templateErr, err)
+	}
+	if renamed != 1 {
+		t.Errorf("expected exactly one rename but got %d", renamed)
+	}
+	if _, err := os.Stat(filepath.Join(root, newName)); err != nil {
+		t.Error("standardized file not migrated")
+	}
+	if _, err := os.Stat(filepath.Join(root, "plain.txt")); err != nil {
+		t.Error("file without standardized name renamed")
+	}
+	lib.PersistChanges()
+
+	t.Run("PreviousTemplateRecognized", func(Test *testing.T) {
+		reopened, err := Open(root, testConfig(Test))
+		if err != nil {
+			Test.Fatal(err)
+		}
+		for _, name := range []string{legacyName, newName} {
+			if id, err := reopened.(*doccurator).extractIdFromFilename(name); err != nil || id != 1 {
+				Test.Errorf("ID not extracted from %s: %v", name, err)
+			}
+		}
+	})
+
+	t.Run("InvalidTemplate", func(Test *testing.T) {
+		if err := lib.SetNameTemplate("{name}{ext}"); err == nil {
+			Test.Error("template without ID accepted")
+		}
+	})
+}
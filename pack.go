@@ -0,0 +1,392 @@
+package doccurator
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	checksum "crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/n2code/doccurator/internal/document"
+	out "github.com/n2code/doccurator/internal/output"
+	"github.com/n2code/ndocid"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PackManifestFileName is the name of the manifest file which Pack places at the top of the archive.
+const PackManifestFileName = "doccurator-pack.json"
+
+func (d *doccurator) Pack(archive string, selection RecordSelection, flat bool) (packedCount int, err error) {
+	absoluteArchive := mustAbsFilepath(archive)
+	if _, statErr := os.Stat(absoluteArchive); statErr == nil {
+		return 0, fmt.Errorf("archive %s exists already", absoluteArchive)
+	}
+	selected := d.selectActiveRecords(selection)
+	if len(selected) == 0 {
+		return 0, errors.New("no active records selected for packing")
+	}
+
+	manifest := jsonManifest{Library: d.appLib.GetUuid(), Name: d.appLib.GetName(), Created: time.Now().UTC()}
+	sources := make([]string, 0, len(selected))
+	for _, doc := range selected {
+		record := makeRecord(doc)
+		sources = append(sources, d.appLib.Absolutize(record.AnchoredPath))
+		manifest.Documents = append(manifest.Documents, makeManifestDocument(record, exportedPathOf(doc, flat)))
+	}
+	manifestContent, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+
+	file, err := os.OpenFile(absoluteArchive, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	writer, err := newArchiveWriter(absoluteArchive, file)
+	if err == nil {
+		err = writer.add(PackManifestFileName, bytes.NewReader(manifestContent), int64(len(manifestContent)), manifest.Created)
+		for i, entry := range manifest.Documents {
+			if err != nil {
+				break
+			}
+			err = packDocument(writer, sources[i], entry)
+			d.Print(out.Verbose, "  + packed [%s] %s\n", entry.Id, entry.Path)
+		}
+		if closeErr := writer.close(); err == nil {
+			err = closeErr
+		}
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(absoluteArchive)
+		return 0, fmt.Errorf("archive %s not written: %w", absoluteArchive, err)
+	}
+	d.Print(out.Normal, "Packed %d %s into %s\n", len(selected), out.Plural(selected, "document", "documents"), absoluteArchive)
+	return len(selected), nil
+}
+
+// packDocument streams the file into the archive and checks on the way that it still matches its record
+func packDocument(writer archiveWriter, source string, entry jsonManifestDocument) error {
+	file, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("record %s not packable: %w", entry.Id, err)
+	}
+	defer file.Close()
+	hash := checksum.New()
+	if err := writer.add(entry.Path, io.TeeReader(file, hash), entry.Size, entry.Modified); err != nil {
+		return fmt.Errorf("record %s not packable: %w", entry.Id, err)
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != entry.Sha256 {
+		return fmt.Errorf("record %s not packable: file differs from record, update it first", entry.Id)
+	}
+	return nil
+}
+
+// VerifyPack checks the integrity of an archive created by Pack against the manifest it contains. No library is required.
+func VerifyPack(archive string) (verification PackVerification, err error) {
+	verification, _, err = readPack(archive)
+	return
+}
+
+// PrintPackVerification outputs the result of VerifyPack.
+func PrintPackVerification(verification PackVerification, config HandleConfig) {
+	printer := makeDoccurator(config)
+	name := verification.Name
+	if name == "" {
+		name = "(unnamed)"
+	}
+	printer.Print(out.Normal, "Archive %s\n  packed from library %s [%s] on %s\n\n", verification.Archive, name, verification.Library, verification.Packed.Local().Format(time.RFC1123))
+	for _, group := range []struct {
+		title   string
+		entries []string
+	}{{"Missing", verification.Missing}, {"Corrupted", verification.Corrupted}, {"Unlisted", verification.Extra}} {
+		if len(group.entries) == 0 {
+			continue
+		}
+		printer.Print(out.Normal, " %s (%d %s)\n", group.title, len(group.entries), out.Plural(group.entries, "entry", "entries"))
+		for _, entry := range group.entries {
+			printer.Print(out.Required, "  %s\n", entry)
+		}
+		printer.Print(out.Normal, "\n")
+	}
+	printer.Print(out.Normal, " %d %s intact.\n", verification.Intact, out.Plural(verification.Intact, "document", "documents"))
+}
+
+func (d *doccurator) Unpack(archive string, directory string) (addedCount int, err error) {
+	verification, manifest, err := readPack(archive)
+	if err != nil {
+		return 0, err
+	}
+	if !verification.Complete() {
+		return 0, fmt.Errorf("archive %s damaged, verify it for details", verification.Archive)
+	}
+	absoluteDir := mustAbsFilepath(directory)
+	if _, rootDir := d.getRootOf(absoluteDir); absoluteDir != rootDir && !isChildOf(absoluteDir, rootDir) {
+		return 0, fmt.Errorf("directory to unpack to must be located inside the library: %s", absoluteDir)
+	}
+	pending := make(map[string]jsonManifestDocument, len(manifest.Documents)) //by path inside archive
+	for _, entry := range manifest.Documents {
+		target := filepath.Join(absoluteDir, filepath.FromSlash(entry.Path))
+		if !isChildOf(target, absoluteDir) {
+			return 0, fmt.Errorf("archive entry %s points outside of the target directory", entry.Path)
+		}
+		pending[entry.Path] = entry
+	}
+
+	err = visitArchive(verification.Archive, func(name string, content io.Reader) error {
+		entry, listed := pending[name]
+		if !listed { //manifest, unlisted entries, and repeated entries
+			return nil
+		}
+		delete(pending, name)
+		target := filepath.Join(absoluteDir, filepath.FromSlash(entry.Path))
+		if _, statErr := os.Stat(target); statErr == nil {
+			return fmt.Errorf("file %s exists already", target)
+		}
+		if err := d.createMissingDirectories(filepath.Dir(target)); err != nil {
+			return err
+		}
+		if err := unpackDocument(content, target, entry); err != nil {
+			return err
+		}
+		d.rollbackLog = append(d.rollbackLog, func() error {
+			return os.Remove(target)
+		})
+		if err := os.Chtimes(target, entry.Modified, entry.Modified); err != nil {
+			return err
+		}
+
+		id := document.MissingId
+		if numId, decodeErr, complete := ndocid.Decode(entry.Id); decodeErr == nil && complete {
+			id = document.Id(numId)
+		}
+		if _, taken := d.appLib.GetDocumentById(id); taken || id == document.MissingId {
+			var idErr error
+			if id, idErr = d.GetFreeId(); idErr != nil {
+				return fmt.Errorf("ID %s of %s taken: %w", entry.Id, entry.Path, idErr)
+			}
+			d.Print(out.Normal, "ID %s of %s taken, assigning new ID\n", entry.Id, entry.Path)
+		}
+		if _, err := d.addSingle(id, target, false, entry.Size == 0); err != nil {
+			return err
+		}
+		addedCount++
+		return nil
+	})
+	if err == nil && len(pending) > 0 {
+		err = fmt.Errorf("archive %s changed while unpacking", verification.Archive)
+	}
+	return addedCount, err
+}
+
+// unpackDocument streams an archive entry into a new file which is removed again if its content does not match the manifest
+func unpackDocument(content io.Reader, target string, entry jsonManifestDocument) error {
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	hash := checksum.New()
+	_, err = io.Copy(io.MultiWriter(file, hash), content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && hex.EncodeToString(hash.Sum(nil)) != entry.Sha256 {
+		err = fmt.Errorf("archive entry %s changed while unpacking", entry.Path)
+	}
+	if err != nil {
+		os.Remove(target)
+	}
+	return err
+}
+
+// readPack verifies the archive entry by entry, only the manifest is read into memory
+func readPack(archive string) (verification PackVerification, manifest jsonManifest, err error) {
+	verification.Archive = mustAbsFilepath(archive)
+	type digest struct {
+		size   int64
+		sha256 string
+	}
+	digests := make(map[string]digest) //by path inside archive
+	var manifestContent []byte
+	err = visitArchive(verification.Archive, func(name string, content io.Reader) (visitErr error) {
+		if name == PackManifestFileName {
+			manifestContent, visitErr = io.ReadAll(content)
+			return
+		}
+		hash := checksum.New()
+		size, visitErr := io.Copy(hash, content)
+		digests[name] = digest{size: size, sha256: hex.EncodeToString(hash.Sum(nil))}
+		return
+	})
+	if err != nil {
+		return verification, manifest, fmt.Errorf("archive %s unreadable: %w", verification.Archive, err)
+	}
+	if manifestContent == nil {
+		return verification, manifest, fmt.Errorf("archive %s contains no manifest (%s)", verification.Archive, PackManifestFileName)
+	}
+	if err = json.Unmarshal(manifestContent, &manifest); err != nil {
+		return verification, manifest, fmt.Errorf("manifest of archive %s corrupt: %w", verification.Archive, err)
+	}
+	verification.Library, verification.Name, verification.Packed = manifest.Library, manifest.Name, manifest.Created
+
+	listed := make(map[string]bool, len(manifest.Documents))
+	for _, entry := range manifest.Documents {
+		listed[entry.Path] = true
+		contained, exists := digests[entry.Path]
+		if !exists {
+			verification.Missing = append(verification.Missing, entry.Path)
+			continue
+		}
+		if contained.size != entry.Size || contained.sha256 != entry.Sha256 {
+			verification.Corrupted = append(verification.Corrupted, entry.Path)
+			continue
+		}
+		verification.Intact++
+	}
+	for name := range digests {
+		if !listed[name] {
+			verification.Extra = append(verification.Extra, name)
+		}
+	}
+	sort.Strings(verification.Missing)
+	sort.Strings(verification.Corrupted)
+	sort.Strings(verification.Extra)
+	return verification, manifest, nil
+}
+
+type archiveWriter interface {
+	add(name string, content io.Reader, size int64, modTime time.Time) error
+	close() error
+}
+
+type zipArchiveWriter struct{ *zip.Writer }
+
+func (w zipArchiveWriter) add(name string, content io.Reader, size int64, modTime time.Time) error {
+	entry, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime})
+	if err != nil {
+		return err
+	}
+	written, err := io.Copy(entry, content)
+	if err == nil && written != size {
+		err = fmt.Errorf("size of %s changed while packing", name)
+	}
+	return err
+}
+
+func (w zipArchiveWriter) close() error {
+	return w.Close()
+}
+
+type tarGzArchiveWriter struct {
+	compressor *gzip.Writer
+	archive    *tar.Writer
+}
+
+func (w tarGzArchiveWriter) add(name string, content io.Reader, size int64, modTime time.Time) error {
+	header := &tar.Header{Typeflag: tar.TypeReg, Name: name, Size: size, Mode: 0o644, ModTime: modTime}
+	if err := w.archive.WriteHeader(header); err != nil {
+		return err
+	}
+	written, err := io.Copy(w.archive, content)
+	if err == nil && written != size {
+		err = fmt.Errorf("size of %s changed while packing", name)
+	}
+	return err
+}
+
+func (w tarGzArchiveWriter) close() error {
+	if err := w.archive.Close(); err != nil {
+		return err
+	}
+	return w.compressor.Close()
+}
+
+func isZipArchive(name string) (zipFormat bool, err error) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return true, nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return false, nil
+	}
+	return false, fmt.Errorf("archive format of %s unsupported (supported extensions: .zip, .tar.gz, .tgz)", filepath.Base(name))
+}
+
+func newArchiveWriter(name string, target io.Writer) (archiveWriter, error) {
+	zipFormat, err := isZipArchive(name)
+	if err != nil {
+		return nil, err
+	}
+	if zipFormat {
+		return zipArchiveWriter{zip.NewWriter(target)}, nil
+	}
+	compressor := gzip.NewWriter(target)
+	return tarGzArchiveWriter{compressor: compressor, archive: tar.NewWriter(compressor)}, nil
+}
+
+// visitArchive calls the visitor for every regular file in the archive with its cleaned, slash-separated path,
+// the content can only be read during the visit and the first error of a visitor ends the traversal
+func visitArchive(name string, visitor func(name string, content io.Reader) error) error {
+	zipFormat, err := isZipArchive(name)
+	if err != nil {
+		return err
+	}
+	if zipFormat {
+		reader, err := zip.OpenReader(name)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		for _, entry := range reader.File {
+			if entry.FileInfo().IsDir() {
+				continue
+			}
+			file, err := entry.Open()
+			if err != nil {
+				return err
+			}
+			err = visitor(path.Clean(entry.Name), file)
+			file.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	decompressor, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	reader := tar.NewReader(decompressor)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := visitor(path.Clean(header.Name), reader); err != nil {
+			return err
+		}
+	}
+}
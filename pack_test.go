@@ -0,0 +1,106 @@
+package doccurator
+
+import (
+	"bytes"
+	"github.com/n2code/doccurator/internal/document"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPack(t *testing.T) {
+	//GIVEN
+	root, lib := newTestLibrary(t, map[document.Id]string{1: "top", 2: filepath.Join("sub", "nested")})
+
+	for _, extension := range []string{".zip", ".tar.gz"} {
+		t.Run("RoundTrip"+extension, func(Test *testing.T) {
+			archive := filepath.Join(Test.TempDir(), "bundle"+extension)
+
+			//WHEN
+			packed, err := lib.Pack(archive, RecordSelection{}, false)
+			verification, verifyErr := VerifyPack(archive)
+
+			//THEN
+			if err != nil || packed != 2 {
+				Test.Fatalf("expected 2 packed documents but got %d (%v)", packed, err)
+			}
+			if verifyErr != nil || !verification.Complete() || verification.Intact != 2 || verification.Library != lib.appLib.GetUuid() {
+				Test.Errorf("bad verification (%v): %+v", verifyErr, verification)
+			}
+
+			target := Test.TempDir()
+			unpacking, err := New(target, filepath.Join(target, "library.db"), RootMapping{}, testConfig(Test))
+			if err != nil {
+				Test.Fatal(err)
+			}
+			added, err := unpacking.Unpack(archive, filepath.Join(target, "inbox"))
+			if err != nil || added != 2 {
+				Test.Fatalf("expected 2 added documents but got %d (%v)", added, err)
+			}
+			record, exists := unpacking.GetRecord(2)
+			if !exists || record.AnchoredPath != filepath.Join("inbox", "sub", "nested") {
+				Test.Errorf("document not added with original ID: %+v", record)
+			}
+			original, _ := lib.GetRecord(2)
+			if !record.Modified.Equal(original.Modified) || record.Sha256 != original.Sha256 {
+				Test.Error("content or modification time not restored")
+			}
+		})
+	}
+
+	t.Run("DamagedArchive", func(Test *testing.T) {
+		archive := filepath.Join(Test.TempDir(), "bundle.zip")
+		lib.Pack(archive, RecordSelection{IdPart: document.Id(1).String()}, true)
+		verification, manifest, _ := readPack(archive)
+		if verification.Intact != 1 {
+			Test.Fatalf("selection not applied: %+v", verification)
+		}
+		tampered := filepath.Join(Test.TempDir(), "tampered.zip")
+		file, _ := os.Create(tampered)
+		writer, _ := newArchiveWriter(tampered, file)
+		for _, entry := range manifest.Documents {
+			writer.add(entry.Path, strings.NewReader("tampered"), int64(len("tampered")), time.Now())
+		}
+		original, _ := visitArchiveEntry(archive, PackManifestFileName)
+		writer.add(PackManifestFileName, bytes.NewReader(original), int64(len(original)), time.Now())
+		writer.add("unlisted", strings.NewReader("unlisted"), int64(len("unlisted")), time.Now())
+		writer.close()
+		file.Close()
+
+		verification, err := VerifyPack(tampered)
+		if err != nil {
+			Test.Fatal(err)
+		}
+		if verification.Complete() || len(verification.Corrupted) != 1 || len(verification.Extra) != 1 {
+			Test.Errorf("damage not detected: %+v", verification)
+		}
+		if _, err := lib.Unpack(tampered, root); err == nil {
+			Test.Error("damaged archive unpacked")
+		}
+	})
+
+	t.Run("ModifiedFile", func(Test *testing.T) {
+		archive := filepath.Join(Test.TempDir(), "bundle.tar.gz")
+		writeTestFile(Test, root, "top", "pot")
+
+		if _, err := lib.Pack(archive, RecordSelection{}, false); err == nil {
+			Test.Error("file differing from its record packed")
+		}
+		if _, err := os.Stat(archive); err == nil {
+			Test.Error("incomplete archive left behind")
+		}
+	})
+}
+
+func visitArchiveEntry(archive string, wanted string) (content []byte, err error) {
+	err = visitArchive(archive, func(name string, entryContent io.Reader) (readErr error) {
+		if name == wanted {
+			content, readErr = io.ReadAll(entryContent)
+		}
+		return
+	})
+	return
+}
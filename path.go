@@ -1,41 +1,36 @@
 package doccurator
 
 import (
-	"fmt"
 	"github.com/n2code/doccurator/internal"
 	"github.com/n2code/doccurator/internal/document"
 	out "github.com/n2code/doccurator/internal/output"
-	"github.com/n2code/ndocid"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 )
 
-//represents file.23456X777.ndoc.ext or file_without_ext.23456X777.ndoc or .23456X777.ndoc.ext_only
-var ndocFileNameRegex = regexp.MustCompile(`^.*\.(` + document.IdPattern + `)\.ndoc(?:\.[^.]*)?$`)
-
-// ExtractIdFromStandardizedFilename attempts to discover and extract a valid ID from the given filename or path.
+// ExtractIdFromStandardizedFilename attempts to discover and extract a valid ID from the given filename or path (in the default format).
 func ExtractIdFromStandardizedFilename(path string) (document.Id, error) {
-	filename := filepath.Base(path)
-	matches := ndocFileNameRegex.FindStringSubmatch(filename)
-	if matches == nil {
-		return 0, fmt.Errorf("ID missing in filename %s (expected format <name>.<ID>.ndoc.<ext>, e.g. notes.txt.23352M4R96Z.ndoc.txt)", filename)
-	}
-	textId := matches[1]
-	numId, err, _ := ndocid.Decode(textId)
-	if err != nil {
-		return 0, fmt.Errorf(`bad ID in filename %s (%w)`, filename, err)
-	}
-	return document.Id(numId), nil
+	return document.DefaultNameTemplate.ExtractId(filepath.Base(path))
+}
+
+// extractIdFromFilename is like ExtractIdFromStandardizedFilename but recognizes all name templates of the library.
+func (d *doccurator) extractIdFromFilename(path string) (document.Id, error) {
+	return d.appLib.ExtractIdFromFilename(filepath.Base(path))
 }
 
 const libRootScheme = "lib:" + string(filepath.Separator) + string(filepath.Separator)
 
 func (d *doccurator) displayablePath(absolutePath string, shortenLibraryRoot bool, omitDotSlash bool) string {
-	pleasant := pleasantPath(filepath.Clean(absolutePath), d.appLib.GetRoot(), mustGetwd(), shortenLibraryRoot, omitDotSlash)
+	absolutePath = filepath.Clean(absolutePath)
+	rootName, rootDirectory := d.getRootOf(absolutePath)
+	pleasant := pleasantPath(absolutePath, rootDirectory, mustGetwd(), shortenLibraryRoot, omitDotSlash)
 	if strings.HasPrefix(pleasant, libRootScheme) {
-		pleasant = strings.Replace(pleasant, libRootScheme, d.printer.Sprintf("%s%s%s", out.FaintIntensity, libRootScheme, out.NormalIntensity), 1)
+		scheme := libRootScheme
+		if rootName != "" {
+			scheme = namedRootScheme(rootName)
+		}
+		pleasant = strings.Replace(pleasant, libRootScheme, d.printer.Sprintf("%s%s%s", out.FaintIntensity, scheme, out.NormalIntensity), 1)
 	}
 	return pleasant
 }
@@ -2,11 +2,11 @@ package doccurator
 
 import (
 	"fmt"
-	"github.com/n2code/doccurator/internal"
 	"github.com/n2code/doccurator/internal/document"
 	"github.com/n2code/doccurator/internal/library"
 	out "github.com/n2code/doccurator/internal/output"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -36,31 +36,53 @@ func (d *doccurator) PrintAllRecords(excludeRetired bool) {
 
 func (d *doccurator) PrintTree(excludeUnchanged bool, onlyWorkingDir bool) error {
 	displayFilters := make([]library.PathSkipEvaluator, 0, 2)
-	libRoot := d.appLib.GetRoot()
 
-	label := libRoot + " [library root]"
+	//one tree per library root, the primary one (which has no name) comes first
+	namedRoots := d.appLib.GetNamedRoots()
+	rootNames := make([]string, 0, len(namedRoots)+1)
+	for name := range namedRoots {
+		rootNames = append(rootNames, name)
+	}
+	sort.Strings(rootNames)
+	rootNames = append([]string{""}, rootNames...)
+	labels := map[string]string{"": d.appLib.GetRoot() + " [library root]"}
+	for name, directory := range namedRoots {
+		labels[name] = fmt.Sprintf("%s [library root %q]", directory, name)
+	}
+
 	trimPrefix := ""
 
 	if onlyWorkingDir {
 		wd := mustGetwd()
-		if isChildOf(wd, libRoot) {
-			label = wd + " [working directory]"
+		rootName, rootDirectory := d.getRootOf(wd)
+		if isChildOf(wd, rootDirectory) {
+			labels[rootName] = wd + " [working directory]"
 
-			trimPrefix, _ = filepath.Rel(libRoot, wd)
+			trimPrefix, _ = filepath.Rel(rootDirectory, wd)
 			trimPrefix += dirSeparator
-
-			displayFilters = append(displayFilters, func(absolute string, dir bool) bool {
-				//do not skip if...
-				return !(dir && isChildOf(wd, absolute) || //directory above (walk-into required!)
-					dir && absolute == wd || //or working directory itself
-					isChildOf(absolute, wd)) //or file/directory inside working directory
-			})
-		} else if wd != libRoot {
+		} else if wd != rootDirectory {
 			return fmt.Errorf("working directory is not inside library")
-		} //else wd == root which shall not behave differently
+		} //else wd == root which shall only restrict output to the tree of that root
+
+		rootNames = []string{rootName}
+		displayFilters = append(displayFilters, func(absolute string, dir bool) bool {
+			//do not skip if...
+			return !(dir && isChildOf(wd, absolute) || //directory above (walk-into required!)
+				dir && absolute == wd || //or working directory itself
+				isChildOf(absolute, wd)) //or file/directory inside working directory
+		})
 	}
 
-	tree := out.NewVisualFileTree(label)
+	trees := make(map[string]out.VisualFileTree, len(rootNames))
+	for _, name := range rootNames {
+		trees[name] = out.NewVisualFileTree(labels[name])
+	}
+	insertIntoTree := func(anchored string, nodePrefix string, nodeSuffix string) {
+		root, relative := document.SplitAnchoredPath(anchored)
+		if tree, shown := trees[root]; shown {
+			tree.InsertPath(strings.TrimPrefix(relative, trimPrefix), nodePrefix, nodeSuffix)
+		}
+	}
 	nodeSuffix := d.printer.Sprintf("%s", out.Reset)
 
 	var pathsWithErrors []*library.CheckedPath
@@ -74,14 +96,14 @@ func (d *doccurator) PrintTree(excludeUnchanged bool, onlyWorkingDir bool) error
 		switch status {
 		case library.Tracked:
 			symbol = "" // to reduce clutter for the majority of entries
-		case library.Moved:
+		case library.Moved, library.MovedModified:
 			referenced := node.ReferencedDocument()
 			movedIdsInScope[referenced.Id()] = true
 		case library.Error:
 			pathsWithErrors = append(pathsWithErrors, node)
 		}
 		nodePrefix := d.printer.Sprintf("%s%s", library.ColorForStatus(status), symbol)
-		tree.InsertPath(strings.TrimPrefix(node.AnchoredPath(), trimPrefix), nodePrefix, nodeSuffix)
+		insertIntoTree(node.AnchoredPath(), nodePrefix, nodeSuffix)
 	}
 
 	for index, checkedPath := range paths {
@@ -100,36 +122,57 @@ func (d *doccurator) PrintTree(excludeUnchanged bool, onlyWorkingDir bool) error
 		lost := missing.ReferencedDocument()
 		if _, wasMoved := movedIdsInScope[lost.Id()]; wasMoved {
 			nodePrefix := d.printer.Sprintf("%s[<] ", out.FaintIntensity)
-			tree.InsertPath(strings.TrimPrefix(missing.AnchoredPath(), trimPrefix), nodePrefix, nodeSuffix)
+			insertIntoTree(missing.AnchoredPath(), nodePrefix, nodeSuffix)
 			continue
 		}
 		addPathToTree(missing)
 	}
 	errorCount := len(pathsWithErrors)
 
-	d.Print(out.Required, "%s", tree.Render())
+	for _, name := range rootNames {
+		d.Print(out.Required, "%s", trees[name].Render())
+	}
 
 	if !ok {
 		d.Print(out.Error, "%d scanning %s occurred:\n", errorCount, out.Plural(errorCount, "error", "errors"))
 		for _, errorPath := range pathsWithErrors {
-			d.Print(out.Error, "%s@%s: %s%s\n", library.ColorForStatus(library.Error), d.displayablePath(filepath.Join(libRoot, errorPath.AnchoredPath()), false, false), errorPath.GetError(), out.Reset)
+			d.Print(out.Error, "%s@%s: %s%s\n", library.ColorForStatus(library.Error), d.displayablePath(d.absolutizeAnchored(errorPath.AnchoredPath()), false, false), errorPath.GetError(), out.Reset)
 		}
 	}
 	return nil
 }
 
-func (d *doccurator) PrintStatus(paths []string) {
-	buckets := make(map[library.PathStatus][]library.CheckedPath)
+// statusPresentationOrder lists all statuses in the order in which they are presented to the user
+var statusPresentationOrder = []library.PathStatus{
+	library.Tracked, // first present what is merely for acknowledgement -> not actionable
+	library.Removed, // same for this status -> not actionable
+	library.Linked,  // and for this one which looks like waste but occupies no additional space
 
-	if len(paths) > 0 {
-		d.Print(out.Verbose, "Status of %d specified %s:\n", len(paths), out.Plural(paths, "path", "paths"))
-	}
-	d.Print(out.Normal, "\n")
+	library.Obsolete,  // then present waste to encourage clean up
+	library.Duplicate, // (yet another type of waste)
+
+	library.Untracked, // then present an easy decision that is unlikely to be postponed (new content is likely to be committed straight away)
+	library.Touched,   // yet another easy decision, very likely to be accepted
+	library.Moved,     //and the final most likely easy decision, also anticipated to be accepted
+
+	library.MovedModified, //then present troubling findings which are probably accepted after careful inspection...
+	library.Modified,      //...such as plain modifications
+
+	library.Missing, //finally, present serious issues that require manual intervention such as recovery...
+	library.Error,   //...or permission adjustment
+}
 
-	hasChanges := false
+// collectStatus compares the given files to the library records and groups the results by status.
+// If no paths are given the full library is scanned and unchanged tracked files are omitted.
+// Files whose ID attribute contradicts their record are listed separately regardless of their status.
+func (d *doccurator) collectStatus(paths []string) (buckets map[library.PathStatus][]library.CheckedPath, hasChanges bool, inconsistent []library.CheckedPath) {
+	buckets = make(map[library.PathStatus][]library.CheckedPath)
 	explicitQueryForPaths := len(paths) > 0
 
-	processResult := func(result library.CheckedPath, relativePath string) {
+	processResult := func(result library.CheckedPath) {
+		if result.AttributeConflict() != nil {
+			inconsistent = append(inconsistent, result)
+		}
 		status := result.Status()
 		if !status.RepresentsChange() && !explicitQueryForPaths {
 			return //to hide unchanged files [when no explicit paths are queried]
@@ -142,13 +185,12 @@ func (d *doccurator) PrintStatus(paths []string) {
 
 	if explicitQueryForPaths {
 		for _, path := range paths {
-			result := d.appLib.CheckFilePath(mustAbsFilepath(path), false) //explicit status query must not sacrifice correctness for performance
-			processResult(result, path)
+			processResult(d.appLib.CheckFilePath(mustAbsFilepath(path), false)) //explicit status query must not sacrifice correctness for performance
 		}
 	} else {
 		results, _ := d.appLib.Scan(d.getScanSkipEvaluators(), nil, d.optimizedFsAccess) //full scan may optimize performance if allowed to
 		for _, result := range results {
-			processResult(result, mustRelFilepathToWorkingDir(filepath.Join(d.appLib.GetRoot(), result.AnchoredPath())))
+			processResult(result)
 		}
 	}
 
@@ -157,7 +199,7 @@ func (d *doccurator) PrintStatus(paths []string) {
 	if missingCount := len(buckets[library.Missing]); missingCount > 0 {
 		filteredMissing := make([]library.CheckedPath, 0, missingCount)
 		movedIds := make(map[document.Id]bool)
-		for _, moved := range buckets[library.Moved] {
+		for _, moved := range append(buckets[library.Moved], buckets[library.MovedModified]...) {
 			originalRecord := moved.ReferencedDocument()
 			movedIds[originalRecord.Id()] = true
 		}
@@ -169,29 +211,35 @@ func (d *doccurator) PrintStatus(paths []string) {
 		}
 		buckets[library.Missing] = filteredMissing
 	}
+	return
+}
 
-	//present grouped entries of each status in a deliberate order to optimize the workflow
-	for _, status := range []library.PathStatus{
-		library.Tracked, // first present what is merely for acknowledgement -> not actionable
-		library.Removed, // same for this status -> not actionable
-
-		library.Obsolete,  // then present waste to encourage clean up
-		library.Duplicate, // (yet another type of waste)
+func (d *doccurator) PrintStatus(paths []string) {
+	d.printStatus(paths)
+}
 
-		library.Untracked, // then present an easy decision that is unlikely to be postponed (new content is likely to be committed straight away)
-		library.Touched,   // yet another easy decision, very likely to be accepted
-		library.Moved,     //and the final most likely easy decision, also anticipated to be accepted
+// printStatus implements PrintStatus and yields the number of changed paths and errors encountered
+func (d *doccurator) printStatus(paths []string) (changeCount int, errorCount int) {
+	if len(paths) > 0 {
+		d.Print(out.Verbose, "Status of %d specified %s:\n", len(paths), out.Plural(paths, "path", "paths"))
+	}
+	d.Print(out.Normal, "\n")
 
-		library.Modified, //then present troubling findings which are probably accepted after careful inspection
+	buckets, hasChanges, inconsistent := d.collectStatus(paths)
 
-		library.Missing, //finally, present serious issues that require manual intervention such as recovery...
-		library.Error,   //...or permission adjustment
-	} {
+	//present grouped entries of each status in a deliberate order to optimize the workflow
+	for _, status := range statusPresentationOrder {
 		bucket := buckets[status]
 		if len(bucket) == 0 {
 			continue //to hide empty buckets
 		}
 
+		if status == library.Error {
+			errorCount += len(bucket)
+		} else if status.RepresentsChange() {
+			changeCount += len(bucket)
+		}
+
 		//bucket header
 		if status == library.Error {
 			d.Print(out.Normal, " %s occurred:\n", out.Plural(bucket, "Error", "Errors"))
@@ -204,12 +252,15 @@ func (d *doccurator) PrintStatus(paths []string) {
 			d.Print(out.Normal, "  ")
 			d.Print(out.Required, "%s[%c] %s%s\n", library.ColorForStatus(status), rune(status), d.displayablePath(d.appLib.Absolutize(result.AnchoredPath()), status != library.Error, true), out.Reset)
 			switch status {
-			case library.Moved:
+			case library.Moved, library.MovedModified:
 				originalRecord := result.ReferencedDocument()
 				d.Print(out.Normal, "      previous: %s\n", d.displayablePath(d.appLib.Absolutize(originalRecord.AnchoredPath()), true, false))
 			case library.Duplicate:
 				identicalRecord := result.ReferencedDocument()
 				d.Print(out.Normal, "      identical: %s\n", d.displayablePath(d.appLib.Absolutize(identicalRecord.AnchoredPath()), true, false))
+			case library.Linked:
+				linkedRecord := result.ReferencedDocument()
+				d.Print(out.Normal, "      same file as: %s\n", d.displayablePath(d.appLib.Absolutize(linkedRecord.AnchoredPath()), true, false))
 			case library.Error:
 				d.Print(out.Normal, "      ")
 				d.Print(out.Error, "%s%s%s%s%s\n", library.ColorForStatus(library.Error), out.Invert, result.GetError(), out.Invert, out.Reset)
@@ -217,16 +268,99 @@ func (d *doccurator) PrintStatus(paths []string) {
 		}
 		d.Print(out.Normal, "\n")
 	}
-	if hasChanges == false && len(paths) == 0 {
+	if len(inconsistent) > 0 {
+		errorCount += len(inconsistent)
+		d.Print(out.Normal, " Inconsistent ID attributes (%d %s)\n", len(inconsistent), out.Plural(inconsistent, "file", "files"))
+		for _, result := range inconsistent {
+			d.Print(out.Normal, "  ")
+			d.Print(out.Required, "%s%s%s\n", library.ColorForStatus(library.Error), d.displayablePath(d.appLib.Absolutize(result.AnchoredPath()), true, true), out.Reset)
+			d.Print(out.Normal, "      %s\n", result.AttributeConflict())
+		}
+		d.Print(out.Normal, "\n")
+	}
+	if hasChanges == false && len(paths) == 0 && len(inconsistent) == 0 {
 		d.Print(out.Normal, " Library files in sync with all records.\n\n")
 	}
+	return
+}
+
+func (d *doccurator) GetAllPaths(excludeUnchanged bool) (results []CheckResult) {
+	paths, _ := d.appLib.Scan(d.getScanSkipEvaluators(), nil, d.optimizedFsAccess) //full scan may optimize performance if allowed to
+	for _, checked := range paths {
+		if excludeUnchanged && !checked.Status().RepresentsChange() && checked.AttributeConflict() == nil {
+			continue
+		}
+		results = append(results, makeCheckResult(checked))
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].AnchoredPath < results[j].AnchoredPath
+	})
+	return
+}
+
+func (d *doccurator) GetStatus(paths []string) (results []CheckResult) {
+	buckets, _, inconsistent := d.collectStatus(paths)
+	for _, status := range statusPresentationOrder {
+		for _, checked := range buckets[status] {
+			results = append(results, makeCheckResult(checked))
+		}
+	}
+	if len(paths) == 0 { //unchanged files are not part of any bucket then
+		for _, checked := range inconsistent {
+			if !checked.Status().RepresentsChange() {
+				results = append(results, makeCheckResult(checked))
+			}
+		}
+	}
+	return
+}
+
+func makeCheckResult(checked library.CheckedPath) CheckResult {
+	status := checked.Status()
+	result := CheckResult{
+		AnchoredPath:      checked.AnchoredPath(),
+		Status:            status,
+		IsChange:          status.RepresentsChange(),
+		IsOutdated:        status.RepresentsOutdatedRecord(),
+		IsWaste:           status.RepresentsWaste(),
+		Reference:         document.MissingId,
+		Problem:           checked.GetError(),
+		AttributeConflict: checked.AttributeConflict(),
+	}
+	if referenced := checked.ReferencedDocument(); referenced != (library.Document{}) {
+		result.Reference = referenced.Id()
+		result.ReferencePath = referenced.AnchoredPath()
+	}
+	return result
+}
+
+func (d *doccurator) GetRecord(id document.Id) (record Record, exists bool) {
+	doc, exists := d.appLib.GetDocumentById(id)
+	if !exists {
+		return
+	}
+	return makeRecord(doc), true
+}
+
+func makeRecord(doc library.Document) (record Record) {
+	record.Id = doc.Id()
+	record.AnchoredPath = doc.AnchoredPath()
+	record.Size, record.Modified, record.Sha256 = doc.RecordProperties()
+	record.Recorded, record.Changed = doc.RecordTimestamps()
+	record.Retired = doc.IsObsolete()
+	record.Due, _ = doc.DueDate()
+	return
+}
+
+func (d *doccurator) Absolutize(anchoredPath string) string {
+	return d.appLib.Absolutize(anchoredPath)
 }
 
 func (d *doccurator) SearchByIdPart(part string) (results []SearchResult) {
 	partInUpper := strings.ToUpper(part)
 	d.appLib.VisitAllRecords(func(doc library.Document) {
 		if id := doc.Id(); strings.Contains(id.String(), partInUpper) {
-			absolute := filepath.Join(d.appLib.GetRoot(), doc.AnchoredPath())
+			absolute := d.absolutizeAnchored(doc.AnchoredPath())
 			results = append(results, SearchResult{
 				Id:         id,
 				Path:       mustRelFilepathToWorkingDir(absolute),
@@ -236,18 +370,6 @@ func (d *doccurator) SearchByIdPart(part string) (results []SearchResult) {
 	return
 }
 
-func (d *doccurator) GetFreeId() document.Id {
-	candidate := internal.UnixTimestampNow()
-	for candidate > 0 {
-		id := document.Id(candidate)
-		if _, exists := d.appLib.GetDocumentById(id); !exists {
-			return id
-		}
-		candidate--
-	}
-	return document.MissingId
-}
-
 func (d *doccurator) getScanSkipEvaluators() []library.PathSkipEvaluator {
 	if d.scanAll {
 		return []library.PathSkipEvaluator{}
@@ -0,0 +1,265 @@
+package doccurator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/n2code/doccurator/internal/library"
+	out "github.com/n2code/doccurator/internal/output"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const registryDirectoryName = "doccurator"
+const registryFileName = "libraries.json"
+const registryPermissions = 0o600 //owner can read and write
+
+// KnownLibrary is an entry in the registry of known libraries which is updated whenever a library is created, opened, or changed.
+// Opening a library does not rewrite the registry if the entry is up to date already.
+type KnownLibrary struct {
+	Uuid        string
+	Name        string    `json:",omitempty"`
+	IdNamespace uint32    `json:",omitempty"`
+	Database    string    //absolute, system-native path
+	Root        string    //absolute, system-native path of the primary root on the registering host
+	Records     int       //number of active records when the library was last registered
+	LastCommit  time.Time //modification time of the database file when the library was last registered
+}
+
+type jsonRegistry struct {
+	Libraries []KnownLibrary
+}
+
+// DefaultRegistryFile determines the location of the registry of known libraries inside the user configuration directory (e.g. $XDG_CONFIG_HOME).
+func DefaultRegistryFile() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("location of library registry unknown: %w", err)
+	}
+	return filepath.Join(configDir, registryDirectoryName, registryFileName), nil
+}
+
+func resolveRegistryFile(config HandleConfig) (string, error) {
+	if config.RegistryFile != "" {
+		return mustAbsFilepath(config.RegistryFile), nil
+	}
+	return DefaultRegistryFile()
+}
+
+// GetKnownLibraries reads the registry of known libraries, sorted by name and database path.
+// A registry that does not exist yet is considered empty.
+func GetKnownLibraries(config HandleConfig) ([]KnownLibrary, error) {
+	registryFile, err := resolveRegistryFile(config)
+	if err != nil {
+		return nil, err
+	}
+	return readRegistry(registryFile)
+}
+
+func readRegistry(registryFile string) ([]KnownLibrary, error) {
+	content, err := os.ReadFile(registryFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("library registry unreadable: %w", err)
+	}
+	var registry jsonRegistry
+	if err := json.Unmarshal(content, &registry); err != nil {
+		return nil, fmt.Errorf("library registry (%s) corrupt: %w", registryFile, err)
+	}
+	sort.SliceStable(registry.Libraries, func(i, j int) bool {
+		a, b := registry.Libraries[i], registry.Libraries[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Database < b.Database
+	})
+	return registry.Libraries, nil
+}
+
+func writeRegistry(registryFile string, libraries []KnownLibrary) error {
+	content, err := json.MarshalIndent(jsonRegistry{Libraries: libraries}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(registryFile), 0o700); err != nil {
+		return err
+	}
+	//write-and-rename so that concurrent readers never see partial content
+	temporary := registryFile + ".tmp"
+	if err := os.WriteFile(temporary, content, registryPermissions); err != nil {
+		return err
+	}
+	return os.Rename(temporary, registryFile)
+}
+
+// register records the current state of the library in the registry of known libraries.
+// Libraries whose database predates library identities are registered once their identity has been saved.
+// Failure is reported but does not affect the library itself.
+func (d *doccurator) register() {
+	if err := d.updateRegistry(); err != nil {
+		d.Print(out.Error, "registry of known libraries not updated: %s\n", err)
+	}
+}
+
+func (d *doccurator) updateRegistry() error {
+	if d.registryFile == "" {
+		registryFile, err := DefaultRegistryFile()
+		if err != nil {
+			return err
+		}
+		d.registryFile = registryFile
+	}
+	if !d.appLib.IsUuidPersisted() { //the UUID would change with every load until then
+		d.Print(out.Verbose, "Registration of library postponed until its identity is saved\n")
+		return nil
+	}
+	libraries, err := readRegistry(d.registryFile)
+	if err != nil {
+		return err
+	}
+
+	entry := KnownLibrary{
+		Uuid:        d.appLib.GetUuid(),
+		Name:        d.appLib.GetName(),
+		IdNamespace: d.appLib.GetIdNamespace(),
+		Database:    d.libFile,
+		Root:        d.appLib.GetRoot(),
+	}
+	d.appLib.VisitAllRecords(func(doc library.Document) {
+		if !doc.IsObsolete() {
+			entry.Records++
+		}
+	})
+	if stat, err := os.Stat(d.libFile); err == nil {
+		entry.LastCommit = stat.ModTime()
+	}
+
+	updated := []KnownLibrary{entry}
+	upToDate := false
+	for _, known := range libraries {
+		if known.Uuid == entry.Uuid || known.Database == entry.Database { //the database may have been replaced by a different library
+			upToDate = upToDate || isSameRegistration(known, entry)
+			continue
+		}
+		updated = append(updated, known)
+	}
+	if upToDate && len(updated) == len(libraries) {
+		return nil
+	}
+	if err := writeRegistry(d.registryFile, updated); err != nil {
+		return err
+	}
+	d.Print(out.Verbose, "Registered library in %s\n", d.registryFile)
+	return nil
+}
+
+func isSameRegistration(a KnownLibrary, b KnownLibrary) bool {
+	if !a.LastCommit.Equal(b.LastCommit) { //location and monotonic clock reading do not survive the registry file
+		return false
+	}
+	a.LastCommit, b.LastCommit = time.Time{}, time.Time{}
+	return a == b
+}
+
+// PrintKnownLibraries lists all libraries in the registry of known libraries as of their last registration.
+func PrintKnownLibraries(config HandleConfig) error {
+	printer := makeDoccurator(config)
+	libraries, err := GetKnownLibraries(config)
+	if err != nil {
+		return err
+	}
+	if len(libraries) == 0 {
+		printer.Print(out.Normal, "No libraries known yet.\n")
+		return nil
+	}
+	for _, known := range libraries {
+		database := known.Database
+		if _, statErr := os.Stat(known.Database); statErr != nil {
+			database += " (unavailable)"
+		}
+		printer.Print(out.Required, "\n%s%s%s [%s]\n", out.BoldIntensity, displayableLibraryName(known), out.NormalIntensity, known.Uuid)
+		printer.Print(out.Required, "  Root:        %s\n", known.Root)
+		printer.Print(out.Required, "  Database:    %s\n", database)
+		printer.Print(out.Required, "  Records:     %d\n", known.Records)
+		printer.Print(out.Required, "  Last commit: %s\n", known.LastCommit.Local().Format(time.RFC1123))
+	}
+	printer.Print(out.Normal, "\n%d %s known\n", len(libraries), out.Plural(libraries, "library", "libraries"))
+	return nil
+}
+
+// PrintStatusOfKnownLibraries calls PrintStatus for each library in the registry of known libraries and concludes with a combined summary.
+// Libraries that cannot be opened are skipped and reported in the summary as well as by the returned error.
+func PrintStatusOfKnownLibraries(config HandleConfig) error {
+	printer := makeDoccurator(config)
+	libraries, err := GetKnownLibraries(config)
+	if err != nil {
+		return err
+	}
+	if len(libraries) == 0 {
+		return errors.New("no libraries known yet")
+	}
+
+	summaries := make([]string, 0, len(libraries))
+	failures := 0
+	for _, known := range libraries {
+		name := displayableLibraryName(known)
+		printer.Print(out.Required, "\n%s=== %s (%s) ===%s\n", out.BoldIntensity, name, known.Root, out.NormalIntensity)
+		handle, openErr := openKnownLibrary(known, config)
+		if openErr != nil {
+			printer.Print(out.Error, "%s\n", openErr)
+			summaries = append(summaries, fmt.Sprintf("%s: unavailable", name))
+			failures++
+			continue
+		}
+		changes, problems := handle.printStatus(nil)
+		summary := fmt.Sprintf("%s: %d %s", name, changes, out.Plural(changes, "change", "changes"))
+		if problems > 0 {
+			summary += fmt.Sprintf(", %d %s", problems, out.Plural(problems, "error", "errors"))
+		}
+		summaries = append(summaries, summary)
+	}
+
+	printer.Print(out.Required, "\nSummary of %d %s:\n", len(libraries), out.Plural(libraries, "library", "libraries"))
+	for _, summary := range summaries {
+		printer.Print(out.Required, "  %s\n", summary)
+	}
+	if failures > 0 {
+		return fmt.Errorf("%d of %d %s could not be opened", failures, len(libraries), out.Plural(libraries, "library", "libraries"))
+	}
+	return nil
+}
+
+// openKnownLibrary loads the database of a registered library directly, i.e. without library locator
+func openKnownLibrary(known KnownLibrary, config HandleConfig) (handle *doccurator, err error) {
+	handle = makeDoccurator(config)
+	defer func() {
+		if loadErr := recover(); loadErr != nil {
+			err = fmt.Errorf("library cannot be loaded: %v", loadErr)
+		}
+	}()
+	if _, statErr := os.Stat(known.Database); statErr != nil {
+		return nil, fmt.Errorf("library database inaccessible: %w", statErr)
+	}
+	handle.libFile = known.Database
+	handle.loadLibrary()
+	if uuid := handle.appLib.GetUuid(); uuid != known.Uuid && handle.appLib.IsUuidPersisted() { //a database without identity is recognized by its path
+		return nil, fmt.Errorf("library database %s belongs to a different library (%s instead of %s)", known.Database, uuid, known.Uuid)
+	}
+	if stat, statErr := os.Stat(handle.appLib.GetRoot()); statErr != nil {
+		return nil, fmt.Errorf("library root inaccessible: %w", statErr)
+	} else if !stat.IsDir() {
+		return nil, fmt.Errorf("library root %s is not a directory", handle.appLib.GetRoot())
+	}
+	handle.register()
+	return handle, nil
+}
+
+func displayableLibraryName(known KnownLibrary) string {
+	if known.Name != "" {
+		return known.Name
+	}
+	return filepath.Base(known.Root)
+}
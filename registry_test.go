@@ -0,0 +1,132 @@
+package doccurator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLibraryRegistry(t *testing.T) {
+	//GIVEN
+	registryFile := filepath.Join(t.TempDir(), "config", "libraries.json")
+	config := HandleConfig{Verbosity: QuietMode, RegistryFile: registryFile}
+	rootA, rootB := t.TempDir(), t.TempDir()
+	databaseA, databaseB := filepath.Join(rootA, "a.db"), filepath.Join(rootB, "b.db")
+
+	//WHEN
+	libA, errA := New(rootA, databaseA, RootMapping{}, config)
+	_, errB := New(rootB, databaseB, RootMapping{}, config)
+
+	//THEN
+	if errA != nil || errB != nil {
+		t.Fatal(errA, errB)
+	}
+	known, err := GetKnownLibraries(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(known) != 2 {
+		t.Fatalf("expected 2 known libraries but got %d", len(known))
+	}
+
+	t.Run("CommitUpdatesEntry", func(Test *testing.T) {
+		//GIVEN
+		os.WriteFile(filepath.Join(rootA, "file"), []byte("content"), 0o600)
+		libA.AddMultiple([]string{filepath.Join(rootA, "file")}, false, false, true, true)
+		libA.SetName("Alpha")
+
+		//WHEN
+		if err := libA.PersistChanges(); err != nil {
+			Test.Fatal(err)
+		}
+
+		//THEN
+		known, _ := GetKnownLibraries(config)
+		if len(known) != 2 {
+			Test.Fatalf("expected 2 known libraries but got %d", len(known))
+		}
+		var entry KnownLibrary
+		for _, library := range known {
+			if library.Database == databaseA {
+				entry = library
+			}
+		}
+		if entry.Name != "Alpha" || entry.Records != 1 || entry.Root != rootA || entry.Uuid != libA.GetIdentity().Uuid {
+			Test.Fatalf("registry entry not updated: %+v", entry)
+		}
+	})
+
+	t.Run("ReplacedDatabaseSupersedesEntry", func(Test *testing.T) {
+		//GIVEN
+		os.Remove(databaseB)
+		os.Remove(filepath.Join(rootB, ".doccurator"))
+
+		//WHEN
+		replacement, err := New(rootB, databaseB, RootMapping{}, config)
+
+		//THEN
+		if err != nil {
+			Test.Fatal(err)
+		}
+		known, _ := GetKnownLibraries(config)
+		if len(known) != 2 {
+			Test.Fatalf("expected 2 known libraries but got %d", len(known))
+		}
+		for _, library := range known {
+			if library.Database == databaseB && library.Uuid != replacement.GetIdentity().Uuid {
+				Test.Fatal("entry of replaced library still present")
+			}
+		}
+	})
+
+	t.Run("OpenKeepsUpToDateRegistry", func(Test *testing.T) {
+		//GIVEN
+		before, _ := os.Stat(registryFile)
+
+		//WHEN
+		_, err := Open(rootA, config)
+
+		//THEN
+		if err != nil {
+			Test.Fatal(err)
+		}
+		if after, _ := os.Stat(registryFile); !os.SameFile(before, after) {
+			Test.Error("registry rewritten although unchanged")
+		}
+	})
+
+	t.Run("DatabaseWithoutIdentity", func(Test *testing.T) {
+		//GIVEN
+		stripFromDatabase(Test, databaseA, `\s*"Uuid": "[^"]*",`) //as written before libraries had an identity
+		known, _ := GetKnownLibraries(config)
+		before, _ := os.Stat(registryFile)
+
+		//WHEN
+		_, openErr := Open(rootA, config)
+		var entry KnownLibrary
+		for _, library := range known {
+			if library.Database == databaseA {
+				entry = library
+			}
+		}
+		_, knownErr := openKnownLibrary(entry, config)
+
+		//THEN
+		if openErr != nil || knownErr != nil {
+			Test.Fatal(openErr, knownErr)
+		}
+		if after, _ := os.Stat(registryFile); !os.SameFile(before, after) {
+			Test.Error("library registered with unsaved identity")
+		}
+	})
+
+	t.Run("StatusOfAllLibraries", func(Test *testing.T) {
+		//WHEN
+		err := PrintStatusOfKnownLibraries(config)
+
+		//THEN
+		if err != nil {
+			Test.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package doccurator
+
+import (
+	"fmt"
+	"github.com/n2code/doccurator/internal/library"
+	out "github.com/n2code/doccurator/internal/output"
+	"path/filepath"
+	"sort"
+)
+
+func (d *doccurator) RenameRecords(selection RecordSelection, reverse bool, dryRun bool) (RenamePlan, error) {
+	return d.renameAll(d.selectActiveRecords(selection), reverse, dryRun)
+}
+
+// renameAll plans the renames of the given documents to their standardized (or original) names and prints the plan.
+// The plan is only applied if it is free of conflicts. A failure during the renames leaves all steps in the rollback log.
+func (d *doccurator) renameAll(docs []library.Document, reverse bool, dryRun bool) (plan RenamePlan, err error) {
+	sort.Slice(docs, func(i, j int) bool { return docs[i].AnchoredPath() < docs[j].AnchoredPath() })
+	renameOf := func(doc library.Document) func(dryRun bool) (string, error, func() error) {
+		if reverse {
+			return doc.RenameToOriginalName
+		}
+		return doc.RenameToStandardNameFormat
+	}
+
+	var planned []library.Document
+	targets := make(map[string]Record) //by absolute path after rename
+	for _, doc := range docs {
+		record := makeRecord(doc)
+		absolute := d.appLib.Absolutize(record.AnchoredPath)
+		if status := d.appLib.CheckFilePath(absolute, d.optimizedFsAccess).Status(); status != library.Tracked && status != library.Touched {
+			plan.Conflicts = append(plan.Conflicts, RenameConflict{Record: record, Problem: fmt.Sprintf("file not in order (%s)", status)})
+			continue
+		}
+		newName, previewErr, _ := renameOf(doc)(true)
+		if previewErr != nil {
+			plan.Conflicts = append(plan.Conflicts, RenameConflict{Record: record, Problem: previewErr.Error()})
+			continue
+		}
+		if newName == "" {
+			continue
+		}
+		target := filepath.Join(filepath.Dir(absolute), newName)
+		if other, taken := targets[target]; taken {
+			plan.Conflicts = append(plan.Conflicts, RenameConflict{Record: record, Problem: fmt.Sprintf("same new name as %s", other.Id)})
+			continue
+		}
+		targets[target] = record
+		plan.Renames = append(plan.Renames, PlannedRename{Record: record, NewName: newName})
+		planned = append(planned, doc)
+	}
+
+	for _, rename := range plan.Renames {
+		d.Print(out.Normal, "  > rename   [%s] %s to %s\n", rename.Record.Id, displayableAnchoredPath(rename.Record.AnchoredPath), rename.NewName)
+	}
+	for _, conflict := range plan.Conflicts {
+		d.Print(out.Required, "  ! conflict [%s] %s: %s\n", conflict.Record.Id, displayableAnchoredPath(conflict.Record.AnchoredPath), conflict.Problem)
+	}
+	if len(plan.Conflicts) > 0 {
+		return plan, fmt.Errorf("%d %s, nothing renamed", len(plan.Conflicts), out.Plural(plan.Conflicts, "conflict", "conflicts"))
+	}
+	if dryRun {
+		d.Print(out.Normal, "%d %s to be renamed.\n", len(plan.Renames), out.Plural(plan.Renames, "file", "files"))
+		return plan, nil
+	}
+
+	for i, doc := range planned {
+		_, renameErr, rollback := renameOf(doc)(false)
+		d.rollbackLog = append(d.rollbackLog, rollback) //rollback is no-op on error
+		if renameErr != nil {
+			return plan, fmt.Errorf("rename of %s failed: %w", plan.Renames[i].Record.Id, renameErr)
+		}
+	}
+	d.Print(out.Normal, "%d %s renamed.\n", len(plan.Renames), out.Plural(plan.Renames, "file", "files"))
+	return plan, nil
+}
@@ -0,0 +1,55 @@
+package doccurator
+
+import (
+	"github.com/n2code/doccurator/internal/document"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenameRecords(t *testing.T) {
+	//GIVEN
+	root, lib := newTestLibrary(t, map[document.Id]string{1: "top.txt", 2: filepath.Join("sub", "nested.pdf"), 3: filepath.Join("sub", "blocked")})
+	standardized := func(id document.Id, name string) string {
+		record, _ := lib.GetRecord(id)
+		return document.DefaultNameTemplate.Render(name, id, record.Modified)
+	}
+	os.WriteFile(filepath.Join(root, "sub", standardized(3, "blocked")), []byte("in the way"), 0o600)
+
+	//WHEN
+	_, conflictErr := lib.RenameRecords(RecordSelection{}, false, false)
+	plan, err := lib.RenameRecords(RecordSelection{Subtree: root, IdPart: document.Id(1).String()}, false, false)
+
+	//THEN
+	if conflictErr == nil {
+		t.Error("conflicting rename not refused")
+	}
+	if _, err := os.Stat(filepath.Join(root, "sub", "nested.pdf")); err != nil {
+		t.Error("file renamed although plan has conflicts")
+	}
+	if err != nil || len(plan.Renames) != 1 {
+		t.Fatalf("unexpected plan %+v (%v)", plan, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, standardized(1, "top.txt"))); err != nil {
+		t.Error("file not renamed to standardized name")
+	}
+
+	t.Run("DryRun", func(Test *testing.T) {
+		plan, err := lib.RenameRecords(RecordSelection{Subtree: filepath.Join(root, "sub"), IdPart: document.Id(2).String()}, false, true)
+		if err != nil || len(plan.Renames) != 1 || plan.Renames[0].NewName != standardized(2, "nested.pdf") {
+			Test.Errorf("unexpected plan %+v (%v)", plan, err)
+		}
+		if _, err := os.Stat(filepath.Join(root, "sub", "nested.pdf")); err != nil {
+			Test.Error("file renamed during dry run")
+		}
+	})
+
+	t.Run("Reverse", func(Test *testing.T) {
+		if _, err := lib.RenameRecords(RecordSelection{IdPart: document.Id(1).String()}, true, false); err != nil {
+			Test.Fatal(err)
+		}
+		if record, _ := lib.GetRecord(1); record.AnchoredPath != "top.txt" {
+			Test.Errorf("original name not restored: %s", record.AnchoredPath)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package doccurator
+
+import (
+	checksum "crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/n2code/doccurator/internal"
+	"github.com/n2code/doccurator/internal/library"
+	out "github.com/n2code/doccurator/internal/output"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func (d *doccurator) RestoreFrom(directory string, includeModified bool, dryRun bool) (restoredCount int, err error) {
+	absoluteSource := mustAbsFilepath(directory)
+	if stat, statErr := os.Stat(absoluteSource); statErr != nil {
+		return 0, fmt.Errorf("backup location inaccessible: %w", statErr)
+	} else if !stat.IsDir() {
+		return 0, fmt.Errorf("backup location %s is not a directory", absoluteSource)
+	}
+
+	var wanted []Record
+	sizes := make(map[int64]bool)
+	d.appLib.VisitAllRecords(func(doc library.Document) {
+		if doc.IsObsolete() {
+			return
+		}
+		switch d.appLib.CheckFilePath(d.appLib.Absolutize(doc.AnchoredPath()), d.optimizedFsAccess).Status() {
+		case library.Missing:
+		case library.Modified:
+			if !includeModified {
+				return
+			}
+		default:
+			return
+		}
+		record := makeRecord(doc)
+		wanted = append(wanted, record)
+		sizes[record.Size] = true
+	})
+	if len(wanted) == 0 {
+		d.Print(out.Normal, "No records to restore.\n")
+		return 0, nil
+	}
+
+	candidates := make(map[[checksum.Size]byte]string)
+	_ = filepath.WalkDir(absoluteSource, func(path string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			d.Print(out.Error, "%s\n", walkErr)
+			return nil //directories are skipped automatically
+		}
+		if path != absoluteSource && !d.scanAll && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		if info, statErr := entry.Info(); statErr != nil || !sizes[info.Size()] {
+			return nil
+		}
+		sum, hashErr := internal.Sha256OfFile(path)
+		if hashErr != nil {
+			d.Print(out.Error, "%s\n", hashErr)
+			return nil
+		}
+		if _, known := candidates[sum]; !known {
+			candidates[sum] = path
+		}
+		return nil
+	})
+
+	notFound := 0
+	for _, record := range wanted {
+		source, found := candidates[record.Sha256]
+		if !found {
+			notFound++
+			d.Print(out.Normal, "  ? not found [%s] %s\n", record.Id, displayableAnchoredPath(record.AnchoredPath))
+			continue
+		}
+		d.Print(out.Normal, "  + restore   [%s] %s (from %s)\n", record.Id, displayableAnchoredPath(record.AnchoredPath), source)
+		if dryRun {
+			restoredCount++
+			continue
+		}
+		if err = d.restoreFile(record, source); err != nil {
+			return restoredCount, fmt.Errorf("restore of record %s failed: %w", record.Id, err)
+		}
+		restoredCount++
+	}
+	outcome := "restored"
+	if dryRun {
+		outcome = "to be restored"
+	}
+	d.Print(out.Normal, "%d %s %s, %d not found in backup location.\n",
+		restoredCount, out.Plural(restoredCount, "record", "records"), outcome, notFound)
+	return restoredCount, nil
+}
+
+// restoreFile copies the given file to the location of the record, keeping its permission bits and applying the recorded modification time.
+// A modified file at that location is staged for deletion. All steps are reverted by RollbackAllFilesystemChanges.
+func (d *doccurator) restoreFile(record Record, source string) error {
+	target := d.appLib.Absolutize(record.AnchoredPath)
+	if err := d.createMissingDirectories(filepath.Dir(target)); err != nil {
+		return err
+	}
+	if _, statErr := os.Stat(target); statErr == nil {
+		if err := d.stageFileDeletion(target); err != nil {
+			return fmt.Errorf("modified file not replaceable: %w", err)
+		}
+	} else if !errors.Is(statErr, os.ErrNotExist) {
+		return statErr
+	}
+	if err := copyVerifiedFile(source, target, record); errors.Is(err, errDiffersFromRecord) {
+		return fmt.Errorf("%s changed meanwhile", source)
+	} else if err != nil {
+		return err
+	}
+	d.rollbackLog = append(d.rollbackLog, func() error {
+		return os.Remove(target)
+	})
+	return nil
+}
+
+// createMissingDirectories creates the given directory including its parents, each created directory is removed on rollback
+func (d *doccurator) createMissingDirectories(absoluteDir string) error {
+	if _, err := os.Stat(absoluteDir); err == nil {
+		return nil
+	}
+	if err := d.createMissingDirectories(filepath.Dir(absoluteDir)); err != nil {
+		return err
+	}
+	if err := os.Mkdir(absoluteDir, 0o755); err != nil {
+		return err
+	}
+	d.rollbackLog = append(d.rollbackLog, func() error {
+		return os.Remove(absoluteDir)
+	})
+	return nil
+}
@@ -0,0 +1,84 @@
+package doccurator
+
+import (
+	"github.com/n2code/doccurator/internal/document"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRestoreFrom(t *testing.T) {
+	//GIVEN
+	root, lib := newTestLibrary(t, nil)
+	missing, modified := writeTestFile(t, root, filepath.Join("sub", "missing"), "missing"), writeTestFile(t, root, "modified", "modified")
+	recordedTime := time.Date(2020, 2, 20, 20, 20, 20, 0, time.Local)
+	for id, path := range []string{missing, modified} {
+		os.Chtimes(path, recordedTime, recordedTime)
+		if err := lib.AddWithId(document.Id(id+1), path, false, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lib.PersistChanges()
+	os.RemoveAll(filepath.Join(root, "sub"))
+	os.WriteFile(modified, []byte("changed"), 0o600)
+
+	backup := t.TempDir()
+	os.MkdirAll(filepath.Join(backup, "other", "layout"), 0o700)
+	os.WriteFile(filepath.Join(backup, "other", "layout", "renamed"), []byte("missing"), 0o600)
+	os.WriteFile(filepath.Join(backup, "old"), []byte("modified"), 0o600)
+
+	t.Run("DryRun", func(Test *testing.T) {
+		//WHEN
+		count, err := lib.RestoreFrom(backup, true, true)
+
+		//THEN
+		if err != nil || count != 2 {
+			Test.Errorf("expected 2 restorable records but got %d (%v)", count, err)
+		}
+		if _, err := os.Stat(missing); err == nil {
+			Test.Error("file restored during dry run")
+		}
+	})
+
+	t.Run("Rollback", func(Test *testing.T) {
+		//WHEN
+		count, err := lib.RestoreFrom(backup, true, false)
+		lib.RollbackAllFilesystemChanges()
+
+		//THEN
+		if err != nil || count != 2 {
+			Test.Errorf("expected 2 restored records but got %d (%v)", count, err)
+		}
+		if _, err := os.Stat(filepath.Join(root, "sub")); err == nil {
+			Test.Error("restored directory not removed")
+		}
+		if content, _ := os.ReadFile(modified); string(content) != "changed" {
+			Test.Error("replaced file not recovered")
+		}
+	})
+
+	t.Run("MissingOnly", func(Test *testing.T) {
+		//WHEN
+		count, err := lib.RestoreFrom(backup, false, false)
+
+		//THEN
+		if err != nil || count != 1 {
+			Test.Errorf("expected 1 restored record but got %d (%v)", count, err)
+		}
+		if content, _ := os.ReadFile(missing); string(content) != "missing" {
+			Test.Error("missing file not restored")
+		}
+		if stat, err := os.Stat(missing); err != nil || !stat.ModTime().Equal(recordedTime) {
+			Test.Error("recorded modification time not applied")
+		} else if stat.Mode().Perm() != 0o600 {
+			Test.Errorf("permissions of backup not kept: %v", stat.Mode())
+		}
+		if content, _ := os.ReadFile(modified); string(content) != "changed" {
+			Test.Error("modified file replaced without request")
+		}
+		if status := lib.GetStatus([]string{missing}); len(status) != 1 || status[0].IsChange {
+			Test.Errorf("restored file not in sync with record: %+v", status)
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package doccurator
+
+import (
+	"errors"
+	"fmt"
+	"github.com/n2code/doccurator/internal/library"
+	out "github.com/n2code/doccurator/internal/output"
+	"sort"
+	"time"
+)
+
+func (d *doccurator) SetRetention(directory string, years uint, basis library.RetentionBasis, endOfYear bool) error {
+	absolute := mustAbsFilepath(directory)
+	rule := library.RetentionRule{Years: years, Basis: basis, EndOfYear: endOfYear}
+	if err := d.appLib.SetRetentionRule(absolute, rule); err != nil {
+		return err
+	}
+	d.Print(out.Normal, "Retention rule set for %s\n", absolute)
+	return nil
+}
+
+func (d *doccurator) RemoveRetention(directory string) error {
+	absolute := mustAbsFilepath(directory)
+	if err := d.appLib.RemoveRetentionRule(absolute); err != nil {
+		return err
+	}
+	d.Print(out.Normal, "Retention rule removed for %s\n", absolute)
+	return nil
+}
+
+func (d *doccurator) PrintRetentionRules() {
+	rules := d.appLib.GetRetentionRules()
+	if len(rules) == 0 {
+		d.Print(out.Normal, "No retention rules defined.\n")
+		return
+	}
+	for _, rule := range rules {
+		d.Print(out.Required, "%s -> %s\n", displayableRetentionDirectory(rule), describeRetention(rule))
+	}
+}
+
+func (d *doccurator) Expire(asOf time.Time, retire bool) (report ExpiryReport, err error) {
+	if len(d.appLib.GetRetentionRules()) == 0 {
+		return report, errors.New("no retention rules defined")
+	}
+	var expired []library.Document
+	d.appLib.VisitAllRecords(func(doc library.Document) {
+		if doc.IsObsolete() {
+			return
+		}
+		rule, exists := d.appLib.GetRetentionRuleOf(doc)
+		if !exists {
+			report.Unruled++
+			return
+		}
+		record := makeRecord(doc)
+		if expiry := expiryOf(record, rule); !expiry.After(asOf) {
+			report.Expired = append(report.Expired, ExpiredRecord{Record: record, Rule: rule, Expiry: expiry})
+			expired = append(expired, doc)
+		} else {
+			report.Retained++
+		}
+	})
+	sort.SliceStable(report.Expired, func(i, j int) bool {
+		return report.Expired[i].Record.AnchoredPath < report.Expired[j].Record.AnchoredPath
+	})
+	if retire {
+		for _, doc := range expired {
+			d.appLib.MarkDocumentAsObsolete(doc)
+		}
+		report.Retired = len(expired)
+	}
+	return report, nil
+}
+
+func (d *doccurator) PrintExpiryReport(report ExpiryReport) {
+	if len(report.Expired) > 0 {
+		d.Print(out.Normal, " Past retention (%d %s)\n", len(report.Expired), out.Plural(report.Expired, "record", "records"))
+		for _, expired := range report.Expired {
+			d.Print(out.Required, "  [%s] %s\n", expired.Record.Id, displayableAnchoredPath(expired.Record.AnchoredPath))
+			d.Print(out.Normal, "      expired: %s (rule of %s: %s)\n", expired.Expiry.Local().Format("2006-01-02"), displayableRetentionDirectory(expired.Rule), describeRetention(expired.Rule))
+		}
+		d.Print(out.Normal, "\n")
+	}
+	d.Print(out.Normal, " %d %s past retention, %d still retained, %d without retention rule.\n",
+		len(report.Expired), out.Plural(report.Expired, "record", "records"), report.Retained, report.Unruled)
+	if report.Retired > 0 {
+		d.Print(out.Normal, " %d %s retired.\n", report.Retired, out.Plural(report.Retired, "record", "records"))
+	}
+}
+
+// expiryOf calculates the end of the retention period of the record according to the rule
+func expiryOf(record Record, rule library.RetentionRule) time.Time {
+	start := record.Recorded
+	if rule.Basis == library.RetainSinceModified {
+		start = record.Modified
+	}
+	start = start.Local()
+	if rule.EndOfYear {
+		start = time.Date(start.Year()+1, time.January, 1, 0, 0, 0, 0, time.Local)
+	}
+	return start.AddDate(int(rule.Years), 0, 0)
+}
+
+func describeRetention(rule library.RetentionRule) string {
+	description := fmt.Sprintf("%d %s since %s", rule.Years, out.Plural(int(rule.Years), "year", "years"), rule.Basis)
+	if rule.EndOfYear {
+		description += ", counted from end of year"
+	}
+	return description
+}
+
+// displayableRetentionDirectory shows the root directory itself without the trailing "."
+func displayableRetentionDirectory(rule library.RetentionRule) string {
+	scheme, relative := rootSchemeOf(rule.Directory)
+	if relative == "." {
+		return scheme
+	}
+	return scheme + relative
+}
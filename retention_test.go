@@ -0,0 +1,66 @@
+package doccurator
+
+import (
+	"github.com/n2code/doccurator/internal/document"
+	"github.com/n2code/doccurator/internal/library"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExpire(t *testing.T) {
+	//GIVEN
+	root, lib := newTestLibrary(t, nil)
+	modified := time.Date(2015, 3, 1, 12, 0, 0, 0, time.Local)
+	for id, path := range []string{"letter", filepath.Join("invoices", "2015", "invoice")} {
+		os.Chtimes(writeTestFile(t, root, path, path), modified, modified)
+		if err := lib.AddWithId(document.Id(id+1), filepath.Join(root, path), false, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lib.SetRetention(root, 6, library.RetainSinceModified, true)
+	lib.SetRetention(filepath.Join(root, "invoices"), 10, library.RetainSinceModified, true)
+
+	t.Run("MostSpecificRuleApplies", func(Test *testing.T) {
+		//WHEN
+		report, err := lib.Expire(time.Date(2022, 1, 1, 0, 0, 0, 0, time.Local), false)
+
+		//THEN
+		if err != nil {
+			Test.Fatal(err)
+		}
+		if len(report.Expired) != 1 || report.Expired[0].Record.Id != 1 || report.Retained != 1 {
+			Test.Errorf("unexpected report: %+v", report)
+		}
+		if expiry := report.Expired[0].Expiry; !expiry.Equal(time.Date(2022, 1, 1, 0, 0, 0, 0, time.Local)) {
+			Test.Errorf("retention period not counted from end of year, expired %s", expiry)
+		}
+	})
+
+	t.Run("RetireExpired", func(Test *testing.T) {
+		//WHEN
+		report, err := lib.Expire(time.Date(2025, 12, 31, 0, 0, 0, 0, time.Local), false)
+		if err != nil || len(report.Expired) != 1 {
+			Test.Fatalf("invoice expired too early (%v): %+v", err, report)
+		}
+		report, err = lib.Expire(time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local), true)
+
+		//THEN
+		if err != nil || report.Retired != 2 {
+			Test.Fatalf("expected 2 retired records (%v): %+v", err, report)
+		}
+		if record, _ := lib.GetRecord(2); !record.Retired {
+			Test.Error("expired record not retired")
+		}
+	})
+
+	t.Run("RemovedRule", func(Test *testing.T) {
+		if err := lib.RemoveRetention(filepath.Join(root, "invoices")); err != nil {
+			Test.Fatal(err)
+		}
+		if err := lib.RemoveRetention(filepath.Join(root, "invoices")); err == nil {
+			Test.Error("removal of unknown rule succeeded")
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package doccurator
+
+import (
+	"errors"
+	"fmt"
+	"github.com/n2code/doccurator/internal/document"
+	"github.com/n2code/doccurator/internal/library"
+	out "github.com/n2code/doccurator/internal/output"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func (d *doccurator) SetNamedRoot(name string, directory string) error {
+	absolute := mustAbsFilepath(directory)
+	if stat, err := os.Stat(absolute); err != nil {
+		return fmt.Errorf("root directory inaccessible: %w", err)
+	} else if !stat.IsDir() {
+		return fmt.Errorf("root %s is not a directory", absolute)
+	}
+	if err := d.appLib.SetNamedRoot(name, absolute); err != nil {
+		return err
+	}
+
+	locator := filepath.Join(absolute, library.LocatorFileName)
+	_, statErr := os.Stat(locator)
+	locatorExisted := statErr == nil
+	if err := d.createLocatorFile(absolute, false, false); err != nil {
+		return err
+	}
+	if !locatorExisted {
+		d.rollbackLog = append(d.rollbackLog, func() error {
+			return os.Remove(locator)
+		})
+	}
+	d.Print(out.Normal, "Library root %q set to %s\n", name, absolute)
+	return nil
+}
+
+func (d *doccurator) RemoveNamedRoot(name string) error {
+	absolute := d.appLib.GetNamedRoots()[name] //removal fails below if unknown
+	if err := d.appLib.RemoveNamedRoot(name); err != nil {
+		return err
+	}
+
+	locator := filepath.Join(absolute, library.LocatorFileName)
+	if err := os.Remove(locator); err == nil {
+		d.rollbackLog = append(d.rollbackLog, func() error {
+			return d.createLocatorFile(absolute, false, false)
+		})
+	} else if !errors.Is(err, os.ErrNotExist) {
+		d.Print(out.Error, "library locator %s could not be removed: %s\n", locator, err)
+	}
+	d.Print(out.Normal, "Library root %q removed\n", name)
+	return nil
+}
+
+func (d *doccurator) PrintRoots() {
+	d.Print(out.Required, "%s -> %s\n", libRootScheme, d.appLib.GetRoot())
+	roots := d.appLib.GetNamedRoots()
+	names := make([]string, 0, len(roots))
+	for name := range roots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		d.Print(out.Required, "%s -> %s\n", namedRootScheme(name), roots[name])
+	}
+}
+
+// getRootOf determines the name and directory of the library root which contains the given absolute path.
+// If the path is outside of all named roots the primary root (which has no name) is returned.
+func (d *doccurator) getRootOf(absolutePath string) (name string, directory string) {
+	for name, directory := range d.appLib.GetNamedRoots() {
+		if absolutePath == directory || isChildOf(absolutePath, directory) {
+			return name, directory
+		}
+	}
+	return "", d.appLib.GetRoot()
+}
+
+func namedRootScheme(name string) string {
+	return strings.TrimSuffix(libRootScheme, document.RootQualifierSeparator) + name + document.RootQualifierSeparator
+}
+
+// rootSchemeOf splits an anchored path into the display scheme of its library root and the path relative to that root
+func rootSchemeOf(anchored string) (scheme string, relative string) {
+	root, relative := document.SplitAnchoredPath(anchored)
+	if root == "" {
+		return libRootScheme, relative
+	}
+	return namedRootScheme(root), relative
+}
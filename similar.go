@@ -0,0 +1,108 @@
+package doccurator
+
+import (
+	"github.com/n2code/doccurator/internal/document"
+	"github.com/n2code/doccurator/internal/library"
+	out "github.com/n2code/doccurator/internal/output"
+	"sort"
+)
+
+func (d *doccurator) FindSimilar(maxDistance int) (groups []SimilarGroup, caughtUpCount int) {
+	type image struct {
+		doc  library.Document
+		hash document.PerceptualHash
+	}
+	var images []image
+	d.appLib.VisitAllRecords(func(doc library.Document) {
+		if doc.IsObsolete() {
+			return
+		}
+		hash, isImage := doc.PerceptualHash()
+		if !isImage && document.IsImageFilename(doc.AnchoredPath()) {
+			stored, err := d.appLib.CatchUpPerceptualHash(doc) //no-op for content examined before
+			if err != nil {
+				d.Print(out.Error, "hashing failed (%s): %s\n", displayableAnchoredPath(doc.AnchoredPath()), err)
+				return
+			}
+			if stored {
+				caughtUpCount++
+				hash, isImage = doc.PerceptualHash()
+			}
+		}
+		if isImage {
+			images = append(images, image{doc: doc, hash: hash})
+		}
+	})
+	if caughtUpCount > 0 {
+		d.Print(out.Verbose, "Perceptual hashes recorded for %d %s.\n", caughtUpCount, out.Plural(caughtUpCount, "file", "files"))
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].doc.AnchoredPath() < images[j].doc.AnchoredPath() })
+
+	//near-identity is not transitive, groups are formed by chaining similar pairs (union-find)
+	parents := make([]int, len(images))
+	for i := range parents {
+		parents[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parents[i] != i {
+			parents[i] = find(parents[i])
+		}
+		return parents[i]
+	}
+	for i := range images {
+		for j := i + 1; j < len(images); j++ {
+			if images[i].hash.Distance(images[j].hash) <= maxDistance {
+				//the lower index, i.e. the earlier path, stays representative
+				if rootI, rootJ := find(i), find(j); rootI < rootJ {
+					parents[rootJ] = rootI
+				} else if rootJ < rootI {
+					parents[rootI] = rootJ
+				}
+			}
+		}
+	}
+
+	groupIndex := make(map[int]int)
+	var members [][]int
+	for i := range images {
+		root := find(i)
+		index, known := groupIndex[root]
+		if !known {
+			index = len(members)
+			groupIndex[root] = index
+			members = append(members, nil)
+		}
+		members[index] = append(members[index], i)
+	}
+	for _, group := range members {
+		if len(group) < 2 {
+			continue
+		}
+		var similar SimilarGroup
+		reference := images[group[0]].hash
+		for _, i := range group {
+			similar.Records = append(similar.Records, makeRecord(images[i].doc))
+			similar.Distances = append(similar.Distances, reference.Distance(images[i].hash))
+		}
+		groups = append(groups, similar)
+	}
+	return
+}
+
+func (d *doccurator) PrintSimilar(groups []SimilarGroup) {
+	for _, group := range groups {
+		d.Print(out.Normal, " Similar images (%d %s)\n", len(group.Records), out.Plural(group.Records, "document", "documents"))
+		for i, record := range group.Records {
+			d.Print(out.Required, "  [%s] %s", record.Id, displayableAnchoredPath(record.AnchoredPath))
+			if i > 0 {
+				d.Print(out.Normal, " (distance %d)", group.Distances[i])
+			}
+			d.Print(out.Required, "\n")
+		}
+		d.Print(out.Normal, "\n")
+	}
+	if len(groups) == 0 {
+		d.Print(out.Normal, "No similar images found.\n")
+	}
+}
@@ -0,0 +1,92 @@
+package doccurator
+
+import (
+	"bytes"
+	"github.com/n2code/doccurator/internal/document"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindSimilar(t *testing.T) {
+	//GIVEN
+	root, lib := newTestLibrary(t, map[document.Id]string{5: "notes.txt"})
+	stripes := func(width int, height int, vertical bool) image.Image {
+		img := image.NewGray(image.Rect(0, 0, width, height))
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				position, extent := x, width
+				if vertical {
+					position, extent = y, height
+				}
+				img.SetGray(x, y, color.Gray{Y: uint8(255 * (position * 5 / extent % 2))})
+			}
+		}
+		return img
+	}
+	addImage := func(id document.Id, name string, img image.Image) {
+		var encoded bytes.Buffer
+		if filepath.Ext(name) == ".png" {
+			png.Encode(&encoded, img)
+		} else {
+			jpeg.Encode(&encoded, img, &jpeg.Options{Quality: 60})
+		}
+		addTestFile(t, lib, id, name, encoded.String())
+	}
+	addImage(1, "scan.png", stripes(300, 200, false))
+	addImage(2, "scan_small.jpg", stripes(150, 100, false))
+	addImage(3, "retired.jpg", stripes(120, 80, false))
+	addImage(4, "other.png", stripes(300, 200, true))
+	addTestFile(t, lib, 6, "broken.png", "no image")
+	lib.RetireByPath(filepath.Join(root, "retired.jpg"))
+	lib.PersistChanges()
+
+	//WHEN
+	groups, caughtUpCount := lib.FindSimilar(5)
+
+	//THEN
+	if caughtUpCount != 0 {
+		t.Errorf("files examined on add were hashed again: %d", caughtUpCount)
+	}
+	if len(groups) != 1 || len(groups[0].Records) != 2 {
+		t.Fatalf("expected one group of two documents, got %v", groups)
+	}
+	if first, second := groups[0].Records[0], groups[0].Records[1]; first.Id != 1 || second.Id != 2 {
+		t.Errorf("unexpected group members %s and %s", first.Id, second.Id)
+	}
+
+	t.Run("ThresholdGroupsEverything", func(Test *testing.T) {
+		if groups, _ := lib.FindSimilar(document.PerceptualHashBits); len(groups) != 1 || len(groups[0].Records) != 3 {
+			Test.Errorf("expected all active images in one group, got %v", groups)
+		}
+	})
+
+	t.Run("RecordsWithoutHashCaughtUp", func(Test *testing.T) {
+		//GIVEN
+		stripFromDatabase(Test, lib.libFile, `,\s*"PHash": "[^"]*"`) //as recorded before hashing was introduced
+		reopened, err := Open(root, testConfig(Test))
+		if err != nil {
+			Test.Fatal(err)
+		}
+		legacy := reopened.(*doccurator)
+		before, _ := legacy.GetRecord(1)
+
+		//WHEN
+		groups, caughtUpCount := legacy.FindSimilar(5)
+		_, repeatedCount := legacy.FindSimilar(5)
+
+		//THEN
+		if caughtUpCount != 4 || repeatedCount != 0 {
+			Test.Errorf("expected 4 active images (one broken) to be caught up once, got %d and %d", caughtUpCount, repeatedCount)
+		}
+		if len(groups) != 1 || len(groups[0].Records) != 2 {
+			Test.Errorf("expected one group of two documents, got %v", groups)
+		}
+		if after, _ := legacy.GetRecord(1); !after.Changed.Equal(before.Changed) {
+			Test.Error("change date of record touched by catching up")
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package doccurator
+
+import (
+	"errors"
+	"fmt"
+	"github.com/n2code/doccurator/internal/library"
+	out "github.com/n2code/doccurator/internal/output"
+	"os"
+	"path/filepath"
+)
+
+const ignoreFilePermissions = 0o644
+
+func (d *doccurator) Split(directory string, database string) (movedCount int, err error) {
+	absoluteDir := mustAbsFilepath(directory)
+	if stat, statErr := os.Stat(absoluteDir); statErr != nil {
+		return 0, fmt.Errorf("directory to split inaccessible: %w", statErr)
+	} else if !stat.IsDir() {
+		return 0, fmt.Errorf("%s is not a directory", absoluteDir)
+	}
+	if _, rootDir := d.getRootOf(absoluteDir); !isChildOf(absoluteDir, rootDir) {
+		return 0, fmt.Errorf("directory to split must be located below a library root: %s", absoluteDir)
+	}
+	absoluteDb := mustAbsFilepath(database)
+	if _, statErr := os.Stat(filepath.Join(absoluteDir, library.LocatorFileName)); statErr == nil {
+		return 0, fmt.Errorf("directory %s contains a library locator already", absoluteDir)
+	}
+
+	//rebase all records below the directory (retired ones included) onto the new library
+	split := makeDoccurator(HandleConfig{})
+	split.printer, split.registryFile = d.printer, d.registryFile
+	split.appLib = library.NewLibrary()
+	split.appLib.MapRoot(absoluteDir, false, "")
+	split.libFile = absoluteDb
+	var moved []library.Document
+	d.appLib.VisitAllRecords(func(doc library.Document) {
+		absolute := d.appLib.Absolutize(doc.AnchoredPath())
+		if !isChildOf(absolute, absoluteDir) || err != nil {
+			return
+		}
+		if _, importErr := split.appLib.ImportDocumentAt(doc, absolute); importErr != nil {
+			err = fmt.Errorf("record %s cannot be moved: %w", doc.Id(), importErr)
+			return
+		}
+		moved = append(moved, doc)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(moved) == 0 {
+		return 0, fmt.Errorf("no records located in %s", absoluteDir)
+	}
+
+	if err = split.appLib.SaveToLocalFile(absoluteDb, false); err != nil {
+		return 0, err
+	}
+	d.rollbackLog = append(d.rollbackLog, func() error {
+		return os.Remove(absoluteDb)
+	})
+	if err = split.createLocatorFile(absoluteDir, false, false); err != nil {
+		return 0, err
+	}
+	d.rollbackLog = append(d.rollbackLog, func() error {
+		return os.Remove(filepath.Join(absoluteDir, library.LocatorFileName))
+	})
+	if err = d.ignoreSplitDirectory(absoluteDir); err != nil {
+		return 0, err
+	}
+
+	for _, doc := range moved {
+		d.appLib.ForgetDocument(doc)
+	}
+	d.registrationQueue = append(d.registrationQueue, split) //the new database is removed again on rollback
+	d.Print(out.Normal, "Moved %d %s into new library rooted at %s (database: %s)\n", len(moved), out.Plural(moved, "record", "records"), absoluteDir, absoluteDb)
+	return len(moved), nil
+}
+
+// ignoreSplitDirectory adds the directory to the ignore file of its parent directory so that its files are no longer considered untracked
+func (d *doccurator) ignoreSplitDirectory(absoluteDir string) error {
+	ignoreFile := filepath.Join(filepath.Dir(absoluteDir), library.IgnoreFileName)
+	original, readErr := os.ReadFile(ignoreFile)
+	existed := readErr == nil
+	if readErr != nil && !errors.Is(readErr, os.ErrNotExist) {
+		return fmt.Errorf("ignore file %s unreadable: %w", ignoreFile, readErr)
+	}
+
+	content := string(original)
+	if content != "" && content[len(content)-1] != '\n' {
+		content += "\n"
+	}
+	content += filepath.ToSlash(filepath.Base(absoluteDir)) + "/\n" //entries are relative to the ignore file, i.e. anchored
+	if err := os.WriteFile(ignoreFile, []byte(content), ignoreFilePermissions); err != nil {
+		return fmt.Errorf("updating ignore file %s failed: %w", ignoreFile, err)
+	}
+	d.rollbackLog = append(d.rollbackLog, func() error {
+		if existed {
+			return os.WriteFile(ignoreFile, original, ignoreFilePermissions)
+		}
+		return os.Remove(ignoreFile)
+	})
+	d.Print(out.Verbose, "Ignored %s in %s\n", filepath.Base(absoluteDir), ignoreFile)
+	return nil
+}
@@ -0,0 +1,79 @@
+package doccurator
+
+import (
+	"github.com/n2code/doccurator/internal/document"
+	"github.com/n2code/doccurator/internal/library"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	//GIVEN
+	root, source := newTestLibrary(t, map[document.Id]string{1: "kept", 2: filepath.Join("project", "sub", "moved"), 3: filepath.Join("project", "retired")})
+	project := filepath.Join(root, "project")
+	source.RetireByPath(filepath.Join(project, "retired"))
+	source.PersistChanges()
+	original, _ := source.GetRecord(2)
+	splitDatabase := filepath.Join(t.TempDir(), "split.db")
+	sameName := writeTestFile(t, root, filepath.Join("archive", "project", "untracked"), "untracked")
+
+	//WHEN
+	moved, err := source.Split(project, splitDatabase)
+
+	//THEN
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != 2 {
+		t.Errorf("expected 2 moved records but got %d", moved)
+	}
+	if _, exists := source.GetRecord(2); exists {
+		t.Error("moved record still in source library")
+	}
+	if _, exists := source.GetRecord(1); !exists {
+		t.Error("record outside of split directory removed")
+	}
+	if ignored, _ := os.ReadFile(filepath.Join(root, library.IgnoreFileName)); string(ignored) != "project/\n" {
+		t.Errorf("split directory not ignored, ignore file reads %q", ignored)
+	}
+	if status := source.GetStatus([]string{sameName}); len(status) != 1 || status[0].Status != library.Untracked {
+		t.Errorf("directory of same name elsewhere ignored: %+v", status)
+	}
+
+	split, err := Open(project, testConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rebased, exists := split.GetRecord(2)
+	if !exists || rebased.AnchoredPath != filepath.Join("sub", "moved") {
+		t.Errorf("record not rebased: %+v", rebased)
+	}
+	if !rebased.Recorded.Equal(original.Recorded) || !rebased.Changed.Equal(original.Changed) || rebased.Sha256 != original.Sha256 {
+		t.Error("record properties changed")
+	}
+	if retired, _ := split.GetRecord(3); !retired.Retired {
+		t.Error("retirement not kept")
+	}
+
+	t.Run("RegisteredOnCommit", func(Test *testing.T) {
+		registered := func() bool {
+			libraries, _ := GetKnownLibraries(HandleConfig{RegistryFile: source.registryFile})
+			for _, known := range libraries {
+				if known.Database == splitDatabase {
+					return true
+				}
+			}
+			return false
+		}
+		if registered() {
+			Test.Error("split library registered before the source library is committed")
+		}
+		if err := source.PersistChanges(); err != nil {
+			Test.Fatal(err)
+		}
+		if !registered() {
+			Test.Error("split library not registered")
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package doccurator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/n2code/doccurator/internal/document"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+// testConfig keeps the test libraries out of the registry of known libraries of the user
+func testConfig(t *testing.T) HandleConfig {
+	return HandleConfig{Verbosity: QuietMode, RegistryFile: filepath.Join(t.TempDir(), "libraries.json")}
+}
+
+// newTestLibrary creates a library with its database inside a temporary root directory and records the given files
+// (anchored paths, created with the path as content), the state is persisted afterward
+func newTestLibrary(t *testing.T, files map[document.Id]string) (root string, lib *doccurator) {
+	root = t.TempDir()
+	handle, err := New(root, filepath.Join(root, "library.db"), RootMapping{}, testConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lib = handle.(*doccurator)
+	for id, anchoredPath := range files {
+		addTestFile(t, lib, id, anchoredPath, anchoredPath)
+	}
+	if err := lib.PersistChanges(); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+// writeTestFile creates a file including missing parent directories and returns its absolute path
+func writeTestFile(t *testing.T, root string, anchoredPath string, content string) string {
+	path := filepath.Join(root, anchoredPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// addTestFile writes a file into the library root and records it with the given ID, duplicate content is allowed
+func addTestFile(t *testing.T, lib *doccurator, id document.Id, anchoredPath string, content string) string {
+	path := writeTestFile(t, lib.appLib.GetRoot(), anchoredPath, content)
+	if err := lib.AddWithId(id, path, true, false); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// stripFromDatabase removes all matches of the pattern from the content of the database file, e.g. to recreate the
+// state of a database written by an earlier version
+func stripFromDatabase(t *testing.T, database string, pattern string) {
+	compressed, err := os.ReadFile(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content = regexp.MustCompile(pattern).ReplaceAll(content, nil)
+	var stripped bytes.Buffer
+	writer := gzip.NewWriter(&stripped)
+	writer.Write(content)
+	writer.Close()
+	if err := os.WriteFile(database, stripped.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}